@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// command is an admin subcommand run against the cluster's Redis.
+type command struct {
+	name  string
+	usage string
+	run   func(args []string) error
+}
+
+// commands lists the available subcommands.
+var commands = []command{
+	{
+		name:  "node",
+		usage: "node drain <id> [-exit] | node undrain <id>",
+		run:   runNode,
+	},
+	{
+		name:  "nodes",
+		usage: "nodes",
+		run:   runNodes,
+	},
+	{
+		name:  "pause",
+		usage: "pause <job> [-until 2026-11-01T00:00Z|4h] [-reason ...]",
+		run:   runPause,
+	},
+	{
+		name:  "resume",
+		usage: "resume <job>",
+		run:   runResume,
+	},
+	{
+		name:  "schedule",
+		usage: "schedule [-job name] [-from T] [-count N] [-tz zone] [-overlap]",
+		run:   runSchedule,
+	},
+	{
+		name:  "locks",
+		usage: "locks list | locks show <job> | locks release <job> -force [-yes]",
+		run:   runLocks,
+	},
+	{
+		name:  "validate",
+		usage: "validate [-cluster]",
+		run:   runValidate,
+	},
+	{
+		name:  "events",
+		usage: "events [-job name] [-n 20] [-follow] [-json]",
+		run:   runEvents,
+	},
+	{
+		name:  "config",
+		usage: "config push <jobs.yaml> [-force] | config show [version] | config history [-n 20] | config rollback <version>",
+		run:   runConfig,
+	},
+}
+
+// errUsage is returned by a subcommand when its arguments are invalid.
+var errUsage = errors.New("invalid arguments")
+
+// runCommand runs the named subcommand and returns the process exit code.
+func runCommand(name string, args []string) int {
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, flag.ErrHelp):
+			return 0
+		case errors.Is(err, errUsage):
+			fmt.Fprintf(os.Stderr, "usage: cronlock %s\n", cmd.usage)
+			return 2
+		default:
+			fmt.Fprintf(os.Stderr, "cronlock %s: %v\n", name, err)
+			return 1
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\ncommands:\n", name)
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  cronlock %s\n", cmd.usage)
+	}
+	return 2
+}
+
+// newFlagSet creates a subcommand flag set with the shared -config flag.
+func newFlagSet(name string) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	configPath := fs.String("config", "cronlock.yaml", "path to configuration file")
+	return fs, configPath
+}
+
+// parseFlags parses args, allowing flags after positional arguments
+// (e.g. "drain node-1 -exit"), and returns the positional arguments.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// cluster holds the connections a subcommand needs.
+type cluster struct {
+	cfg    *config.Config
+	client *redis.Client
+	store  *state.RedisStore
+	locks  lock.Inspector
+}
+
+// connectCluster loads the configuration, without the checks against this
+// host, and connects to its Redis. With config.source set to redis, the
+// jobs are those currently pushed.
+func connectCluster(configPath string) (*cluster, error) {
+	cfg, err := config.Load(configPath, config.WithoutHostChecks())
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := connectRedis(cfg.Redis)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Redis at %s: %w", cfg.Redis.Address, err)
+	}
+
+	if cfg.Config.Source == config.SourceRedis {
+		ctx, cancel := commandContext()
+		defer cancel()
+		if cfg, err = withCurrentJobs(ctx, client, cfg); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
+
+	return &cluster{
+		cfg:    cfg,
+		client: client,
+		store:  state.NewRedisStore(client, cfg.Redis.KeyPrefix),
+		locks:  lock.NewRedisLocker(client, operator(), cfg.Redis.KeyPrefix),
+	}, nil
+}
+
+// Close closes the Redis connection.
+func (c *cluster) Close() error {
+	return c.client.Close()
+}
+
+// commandContext returns the context used for a subcommand's Redis calls.
+func commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), 10*time.Second)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"cronlock/internal/api"
+	"cronlock/internal/config"
+	"cronlock/internal/configstore"
+	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// runConfig implements "cronlock config push|show|history|rollback".
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+	switch args[0] {
+	case "push":
+		return runConfigPush(args[1:])
+	case "show":
+		return runConfigShow(args[1:])
+	case "history":
+		return runConfigHistory(args[1:])
+	case "rollback":
+		return runConfigRollback(args[1:])
+	}
+	return errUsage
+}
+
+// runConfigPush implements "cronlock config push <file> [-force]".
+func runConfigPush(args []string) error {
+	fs, configPath := newFlagSet("config push")
+	force := fs.Bool("force", false, "push a new version even if the jobs are unchanged")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errUsage
+	}
+	path := positional[0]
+
+	format, err := config.Format(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	jobs, err := config.ParseJobs(data, format)
+	if err != nil {
+		return err
+	}
+	if err := config.ValidateJobs(jobs); err != nil {
+		return err
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	configs := configstore.NewStore(c.client, c.cfg.Redis.KeyPrefix)
+	hash, err := config.JobsHash(jobs)
+	if err != nil {
+		return err
+	}
+	if current, ok, err := configs.Current(ctx); err != nil {
+		return err
+	} else if ok && current.Hash == hash && !*force {
+		fmt.Printf("Jobs unchanged from current version %d, not pushed (use -force to push anyway)\n", current.Version)
+		return nil
+	}
+
+	doc, err := configs.Push(ctx, configstore.Document{
+		Format:   format,
+		Data:     string(data),
+		Hash:     hash,
+		Jobs:     len(jobs),
+		PushedBy: operator(),
+	})
+	if err != nil {
+		return err
+	}
+	recordConfigAudit(ctx, c, "config.push", doc, fmt.Sprintf("%d jobs from %s, hash %s", doc.Jobs, path, doc.Hash))
+
+	fmt.Printf("Pushed config version %d (%d jobs, hash %s)\n", doc.Version, doc.Jobs, doc.Hash)
+	return nil
+}
+
+// runConfigShow implements "cronlock config show [version]".
+func runConfigShow(args []string) error {
+	fs, configPath := newFlagSet("config show")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) > 1 {
+		return errUsage
+	}
+	version := 0
+	if len(positional) == 1 {
+		if version, err = parseVersion(positional[0]); err != nil {
+			return err
+		}
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	configs := configstore.NewStore(c.client, c.cfg.Redis.KeyPrefix)
+	var doc configstore.Document
+	if version == 0 {
+		var ok bool
+		if doc, ok, err = configs.Current(ctx); err != nil {
+			return err
+		} else if !ok {
+			return errors.New("no config has been pushed")
+		}
+	} else if doc, err = configs.Get(ctx, version); err != nil {
+		return err
+	}
+
+	fmt.Printf("# version %d, pushed by %s at %s\n", doc.Version, doc.PushedBy, doc.PushedAt.Local().Format(time.RFC3339))
+	fmt.Print(doc.Data)
+	return nil
+}
+
+// runConfigHistory implements "cronlock config history [-n 20]".
+func runConfigHistory(args []string) error {
+	fs, configPath := newFlagSet("config history")
+	n := fs.Int("n", 20, "number of versions to show")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *n < 1 {
+		return errUsage
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	history, err := configstore.NewStore(c.client, c.cfg.Redis.KeyPrefix).History(ctx)
+	if err != nil {
+		return err
+	}
+	if len(history) == 0 {
+		fmt.Println("No config has been pushed")
+		return nil
+	}
+	history = history[:min(*n, len(history))]
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tPUSHED\tBY\tJOBS\tHASH\tNOTE")
+	for i, doc := range history {
+		var note string
+		switch {
+		case i == 0 && doc.RollbackOf > 0:
+			note = fmt.Sprintf("current, rollback to %d", doc.RollbackOf)
+		case i == 0:
+			note = "current"
+		case doc.RollbackOf > 0:
+			note = fmt.Sprintf("rollback to %d", doc.RollbackOf)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\n",
+			doc.Version, doc.PushedAt.Local().Format("2006-01-02 15:04:05"), doc.PushedBy, doc.Jobs, doc.Hash, orDash(note))
+	}
+	return w.Flush()
+}
+
+// runConfigRollback implements "cronlock config rollback <version>".
+func runConfigRollback(args []string) error {
+	fs, configPath := newFlagSet("config rollback")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errUsage
+	}
+	version, err := parseVersion(positional[0])
+	if err != nil {
+		return err
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	doc, err := configstore.NewStore(c.client, c.cfg.Redis.KeyPrefix).Rollback(ctx, version, operator())
+	if err != nil {
+		return err
+	}
+	recordConfigAudit(ctx, c, "config.rollback", doc, fmt.Sprintf("restored version %d, %d jobs, hash %s", version, doc.Jobs, doc.Hash))
+
+	fmt.Printf("Rolled back to version %d as version %d (%d jobs, hash %s)\n", version, doc.Version, doc.Jobs, doc.Hash)
+	return nil
+}
+
+// parseVersion parses a config version argument.
+func parseVersion(s string) (int, error) {
+	version, err := strconv.Atoi(s)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid config version %q", s)
+	}
+	return version, nil
+}
+
+// recordConfigAudit records a change to the pushed config in the audit log.
+func recordConfigAudit(ctx context.Context, c *cluster, action string, doc configstore.Document, detail string) {
+	entry := state.AuditEntry{
+		Time:     time.Now().UTC(),
+		Operator: operator(),
+		Action:   action,
+		Target:   fmt.Sprintf("config v%d", doc.Version),
+		Detail:   detail,
+	}
+	if err := c.store.RecordAudit(ctx, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record audit entry: %v\n", err)
+	}
+}
+
+// withCurrentJobs returns cfg with the jobs of the current version pushed
+// to Redis, for admin commands run with config.source set to redis. The
+// jobs are parsed but not checked against this host, which may not be a
+// node.
+func withCurrentJobs(ctx context.Context, client *redis.Client, cfg *config.Config) (*config.Config, error) {
+	doc, ok, err := configstore.NewStore(client, cfg.Redis.KeyPrefix).Current(ctx)
+	if err != nil || !ok {
+		return cfg, err
+	}
+	jobs, err := config.ParseJobs([]byte(doc.Data), doc.Format)
+	if err != nil {
+		return nil, fmt.Errorf("config version %d: %w", doc.Version, err)
+	}
+	withJobs := *cfg
+	withJobs.Jobs = jobs
+	return &withJobs, nil
+}
+
+// loadPushedJobs returns cfg with the jobs of a pushed config version,
+// validated for this node.
+func loadPushedJobs(cfg *config.Config, doc configstore.Document) (*config.Config, error) {
+	jobs, err := config.ParseJobs([]byte(doc.Data), doc.Format)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.WithJobs(jobs)
+}
+
+// watchConfig applies each new config version pushed after version to the
+// scheduler and admin API until the returned function is called. A version
+// that is invalid on this node is logged and the node keeps its jobs.
+func watchConfig(configs *configstore.Store, version int, cfg *config.Config, sched *scheduler.Scheduler, apiServer *api.Server, logger *slog.Logger) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		configs.Watch(ctx, version, logger, func(doc configstore.Document) {
+			logger := logger.With("config_version", doc.Version, "pushed_by", doc.PushedBy)
+			newCfg, err := loadPushedJobs(cfg, doc)
+			if err == nil {
+				err = sched.ApplyJobs(newCfg.Jobs, doc.Version, doc.PushedAt)
+			}
+			if err != nil {
+				logger.Error("rejected pushed config, keeping current jobs", "error", err)
+				return
+			}
+			if apiServer != nil {
+				apiServer.SetJobs(newCfg.Jobs)
+			}
+		})
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
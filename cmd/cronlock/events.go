@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"cronlock/internal/events"
+)
+
+// followBlock is how long each read of a followed stream waits for events,
+// bounding how long an interrupt takes to be noticed.
+const followBlock = time.Second
+
+// runEvents implements "cronlock events".
+func runEvents(args []string) error {
+	fs, configPath := newFlagSet("events")
+	jobName := fs.String("job", "", "only show this job's events")
+	follow := fs.Bool("follow", false, "keep printing new events as they are published")
+	asJSON := fs.Bool("json", false, "print each event as a line of JSON")
+	count := fs.Int("n", 20, "number of past events to show")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *count < 0 {
+		return errUsage
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	stream := events.NewStream(c.client, c.cfg.Redis.KeyPrefix, 0)
+	match := func(e events.Event) bool {
+		return *jobName == "" || e.Job == *jobName
+	}
+	show := printEvent
+	if *asJSON {
+		show = printEventJSON
+	}
+
+	ctx, cancel := commandContext()
+	last, err := stream.LastID(ctx)
+	if err == nil && *count > 0 {
+		var past []events.Event
+		past, err = stream.Last(ctx, last, *count, match)
+		for _, e := range past {
+			show(e)
+		}
+	}
+	cancel()
+	if err != nil || !*follow {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	for ctx.Err() == nil {
+		batch, err := stream.Read(ctx, last, followBlock)
+		if err != nil {
+			if ctx.Err() != nil {
+				break
+			}
+			return err
+		}
+		for _, e := range batch {
+			last = e.ID
+			if match(e) {
+				show(e)
+			}
+		}
+	}
+	return nil
+}
+
+// printEvent prints an event as a line of text: its time, type, job and
+// node, then the fields its type sets.
+func printEvent(e events.Event) {
+	job := e.Job
+	if job == "" {
+		job = "-"
+	}
+
+	var details []string
+	add := func(key, value string) {
+		if value != "" {
+			details = append(details, key+"="+value)
+		}
+	}
+	add("run", e.RunID)
+	if e.Lock != e.Job {
+		add("lock", e.Lock)
+	}
+	add("reason", e.Reason)
+	add("hook", e.Hook)
+	add("outcome", e.Outcome)
+	if e.ExitCode != nil {
+		add("exit_code", fmt.Sprint(*e.ExitCode))
+	}
+	if e.Duration > 0 {
+		add("duration", fmt.Sprintf("%.2fs", e.Duration))
+	}
+	if e.Error != "" {
+		add("error", fmt.Sprintf("%q", e.Error))
+	}
+
+	line := fmt.Sprintf("%s  %-13s  %s  %s  %s",
+		e.Time.Local().Format(time.DateTime), e.Type, job, e.NodeID, strings.Join(details, " "))
+	fmt.Println(strings.TrimRight(line, " "))
+}
+
+// printEventJSON prints an event as a line of JSON, in the stream's schema.
+func printEventJSON(e events.Event) {
+	data, err := json.Marshal(e)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to encode event %s: %v\n", e.ID, err)
+		return
+	}
+	fmt.Println(string(data))
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+// runLocks implements "cronlock locks list|show|release".
+func runLocks(args []string) error {
+	fs, configPath := newFlagSet("locks")
+	force := fs.Bool("force", false, "required to release a lock, whichever node holds it")
+	yes := fs.Bool("yes", false, "release without asking for confirmation")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) == 0 {
+		return errUsage
+	}
+
+	action := positional[0]
+	switch {
+	case action == "list" && len(positional) == 1:
+	case (action == "show" || action == "release") && len(positional) == 2:
+	default:
+		return errUsage
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	switch action {
+	case "list":
+		return listLocks(c)
+	case "show":
+		return showLock(c, positional[1])
+	default:
+		return releaseLock(c, positional[1], *force, *yes)
+	}
+}
+
+// listLocks prints every held lock.
+func listLocks(c *cluster) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	infos, err := c.locks.List(ctx)
+	if err != nil {
+		return err
+	}
+	if len(infos) == 0 {
+		fmt.Println("No locks held")
+		return nil
+	}
+
+	live, err := liveNodes(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCK\tHOLDER\tTTL\tACQUIRED")
+	for _, info := range infos {
+		holder := info.Holder
+		if !live[holder] {
+			holder += " (not heartbeating, suspected orphan)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, holder, info.TTL.Round(time.Second), acquiredAgo(info, now))
+	}
+	return w.Flush()
+}
+
+// showLock prints the details of a job's lock, or of its shard locks for a
+// sharded job.
+func showLock(c *cluster, name string) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	infos, err := c.locks.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	shardPrefix := name + ":shard:"
+	var held []lock.LockInfo
+	for _, info := range infos {
+		if info.Name == name || strings.HasPrefix(info.Name, shardPrefix) {
+			held = append(held, info)
+		}
+	}
+	if len(held) == 0 {
+		fmt.Printf("Lock %s is not held\n", name)
+		return nil
+	}
+
+	now := time.Now()
+	for i, info := range held {
+		if i > 0 {
+			fmt.Println()
+		}
+		printLock(info, now)
+	}
+	return nil
+}
+
+// releaseLock deletes a lock after confirmation and records it in the audit log.
+func releaseLock(c *cluster, name string, force, yes bool) error {
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	info, err := c.locks.Inspect(ctx, name)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		return fmt.Errorf("lock %s is not held", name)
+	}
+	if !force {
+		return fmt.Errorf("lock %s is held by %s; pass -force to release it anyway", name, info.Holder)
+	}
+
+	printLock(*info, time.Now())
+	if !yes {
+		// Don't hold the Redis deadline open while waiting for the operator
+		cancel()
+		if !confirm(fmt.Sprintf("\nRelease this lock? If %s is still running the job, another node may start it too. [y/N] ", info.Holder)) {
+			fmt.Println("Aborted")
+			return nil
+		}
+		ctx, cancel = commandContext()
+		defer cancel()
+	}
+
+	released, err := c.locks.ForceRelease(ctx, name, info.Value)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return fmt.Errorf("lock %s changed since it was inspected; not released", name)
+	}
+
+	entry := state.AuditEntry{
+		Time:     time.Now().UTC(),
+		Operator: operator(),
+		Action:   "lock.force_release",
+		Target:   name,
+		Detail:   fmt.Sprintf("held by %s (value %s, ttl %s)", info.Holder, info.Value, info.TTL.Round(time.Second)),
+	}
+	if err := c.store.RecordAudit(ctx, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to record audit entry: %v\n", err)
+	}
+
+	fmt.Printf("Released lock %s\n", name)
+	return nil
+}
+
+// printLock prints one lock's details.
+func printLock(info lock.LockInfo, now time.Time) {
+	fmt.Printf("Lock:     %s\n", info.Name)
+	fmt.Printf("Holder:   %s\n", info.Holder)
+	fmt.Printf("Value:    %s\n", info.Value)
+	fmt.Printf("TTL:      %s\n", info.TTL.Round(time.Second))
+	fmt.Printf("Acquired: %s\n", acquiredAgo(info, now))
+}
+
+// acquiredAgo formats when a lock was acquired, or "unknown" for values that
+// don't record it.
+func acquiredAgo(info lock.LockInfo, now time.Time) string {
+	if info.AcquiredAt.IsZero() {
+		return "unknown"
+	}
+	ago := now.Sub(info.AcquiredAt).Round(time.Second)
+	return fmt.Sprintf("%s (%s ago)", info.AcquiredAt.Local().Format(time.DateTime), ago)
+}
+
+// confirm asks a yes/no question on stdin, defaulting to no.
+func confirm(prompt string) bool {
+	fmt.Print(prompt)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
@@ -7,16 +7,25 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
-	"syscall"
+	"strings"
 	"time"
 
+	"cronlock/internal/api"
 	"cronlock/internal/config"
+	"cronlock/internal/configstore"
+	"cronlock/internal/events"
+	"cronlock/internal/executor"
 	"cronlock/internal/lock"
+	"cronlock/internal/logging"
+	"cronlock/internal/notify"
 	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+	"cronlock/internal/tracing"
 
 	"github.com/coreos/go-systemd/v22/daemon"
 	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
 var (
@@ -25,9 +34,19 @@ var (
 )
 
 func main() {
+	// Commands with rlimits start through cronlock itself, see executor.Init
+	executor.Init()
+
+	// Dispatch admin subcommands, e.g. "cronlock node drain <id>"
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1], os.Args[2:]))
+	}
+
 	configPath := flag.String("config", "cronlock.yaml", "path to configuration file")
 	showVersion := flag.Bool("version", false, "show version and exit")
 	validateOnly := flag.Bool("validate", false, "validate configuration and exit")
+	validateCluster := flag.Bool("cluster", false, "with -validate, also check jobs against the live nodes in Redis: placement and config drift")
+	logLevel := flag.String("log-level", "", "override log.level from the configuration (debug, info, warn, error)")
 	flag.Parse()
 
 	if *showVersion {
@@ -35,7 +54,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Set up structured logging
+	// Log to stderr until the configured logger is set up
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
 	}))
@@ -50,42 +69,136 @@ func main() {
 
 	// Validate-only mode: print success and exit
 	if *validateOnly {
-		fmt.Printf("Configuration valid: %s\n", *configPath)
-		fmt.Printf("  Redis: %s\n", cfg.Redis.Address)
-		fmt.Printf("  Jobs:  %d\n", len(cfg.Jobs))
+		if err := reportValid(cfg, *configPath, *validateCluster); err != nil {
+			fmt.Fprintf(os.Stderr, "Cluster validation failed: %v\n", err)
+			os.Exit(1)
+		}
 		os.Exit(0)
 	}
 
+	// Set up structured logging as configured
+	if *logLevel != "" {
+		cfg.Log.Level = *logLevel
+	}
+	logger, logFile, err := logging.New(cfg.Log)
+	if err != nil {
+		slog.Error("failed to set up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
 	// Generate node ID if not specified
 	nodeID := cfg.Node.ID
 	if nodeID == "" {
 		hostname, _ := os.Hostname()
 		nodeID = fmt.Sprintf("%s-%s", hostname, uuid.New().String()[:8])
 		logger.Info("generated node ID", "node_id", nodeID)
+		cfg.Node.ID = nodeID
 	}
 
-	// Initialize Redis client
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     cfg.Redis.Address,
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
-
-	// Verify Redis connection
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	if err := redisClient.Ping(ctx).Err(); err != nil {
+	// Initialize and verify Redis connection
+	redisClient, err := connectRedis(cfg.Redis)
+	if err != nil {
 		logger.Error("failed to connect to Redis", "error", err, "address", cfg.Redis.Address)
-		cancel()
 		os.Exit(1)
 	}
-	cancel()
 	logger.Info("connected to Redis", "address", cfg.Redis.Address)
 
 	// Create locker
 	locker := lock.NewRedisLocker(redisClient, nodeID, cfg.Redis.KeyPrefix)
 
+	// Create cluster state store
+	store := state.NewRedisStore(redisClient, cfg.Redis.KeyPrefix)
+
+	// Create notification dispatcher
+	var notifier *notify.Dispatcher
+	if len(cfg.Notifiers) > 0 {
+		notifier, err = notify.NewDispatcher(cfg.Notifiers, logger)
+		if err != nil {
+			logger.Error("failed to set up notifiers", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Publish lifecycle events to the cluster's event stream
+	var publisher *events.Publisher
+	if cfg.Events.Enabled {
+		publisher = events.NewPublisher(events.NewStream(redisClient, cfg.Redis.KeyPrefix, cfg.Events.MaxLen), nodeID, logger)
+	}
+
+	// Set up tracing of job runs
+	var tracerProvider *sdktrace.TracerProvider
+	if cfg.OTel.Enabled {
+		tracerProvider, err = tracing.NewProvider(context.Background(), cfg.OTel, nodeID, version)
+		if err != nil {
+			logger.Error("failed to set up tracing", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("tracing job runs", "endpoint", cfg.OTel.Endpoint, "protocol", cfg.OTel.Protocol)
+	}
+
+	// The jobs are as new as the config file, unless loaded from the config
+	// pushed to Redis below
+	var configVersion int
+	var configModTime time.Time
+	if info, err := os.Stat(*configPath); err == nil {
+		configModTime = info.ModTime()
+	}
+
+	// Load jobs from the config pushed to Redis
+	var configs *configstore.Store
+	if cfg.Config.Source == config.SourceRedis {
+		configModTime = time.Time{}
+		configs = configstore.NewStore(redisClient, cfg.Redis.KeyPrefix)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		doc, ok, err := configs.Current(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("failed to read pushed config", "error", err)
+			os.Exit(1)
+		}
+		if !ok {
+			logger.Warn("no config has been pushed, starting without jobs")
+		} else {
+			if cfg, err = loadPushedJobs(cfg, doc); err != nil {
+				logger.Error("invalid pushed config", "config_version", doc.Version, "error", err)
+				os.Exit(1)
+			}
+			configVersion, configModTime = doc.Version, doc.PushedAt
+			logger.Info("loaded pushed config", "config_version", doc.Version, "job_count", len(cfg.Jobs))
+		}
+	}
+
+	configHash, err := config.JobsHash(cfg.Jobs)
+	if err != nil {
+		logger.Error("failed to hash job configuration", "error", err)
+		os.Exit(1)
+	}
+
 	// Create scheduler
-	sched := scheduler.New(locker, cfg.Node, logger)
+	opts := []scheduler.Option{
+		scheduler.WithStore(store),
+		scheduler.WithOutput(cfg.Output),
+		scheduler.WithMonitor(locker.Lease("monitor"), cfg.Jobs),
+		scheduler.WithRegistration(versionString(), configHash),
+		scheduler.WithConfigVersion(configVersion, configModTime),
+		scheduler.WithStatusHandler(func(status string) {
+			notifySystemdStatus(logger, status)
+		}),
+	}
+	if notifier != nil {
+		opts = append(opts, scheduler.WithNotifier(notifier))
+	}
+	if publisher != nil {
+		opts = append(opts, scheduler.WithEvents(publisher))
+	}
+	if tracerProvider != nil {
+		opts = append(opts, scheduler.WithTracerProvider(tracerProvider))
+	}
+	if cfg.Node.Coordination == config.CoordinationLeader {
+		opts = append(opts, scheduler.WithLeaderElection(locker.Lease("leader"), cfg.Node.LeaderTTL))
+	}
+	sched := scheduler.New(locker, cfg.Node, logger, opts...)
 
 	// Add jobs
 	for _, jobCfg := range cfg.Jobs {
@@ -98,18 +211,65 @@ func main() {
 	// Start scheduler
 	sched.Start()
 
+	// Start admin API if configured
+	var apiServer *api.Server
+	if cfg.API.Listen != "" {
+		if cfg.API.Token == "" {
+			logger.Warn("admin API has no token configured, all endpoints are unauthenticated")
+		}
+		apiServer = api.New(cfg.API, sched, cfg.Jobs, logger,
+			api.WithStore(store),
+			api.WithLocks(locker),
+			api.WithHealthCheck(func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			}),
+		)
+		if err := apiServer.Start(); err != nil {
+			logger.Error("failed to start admin API", "error", err)
+			sched.Stop()
+			os.Exit(1)
+		}
+	}
+
+	// Apply config versions as they are pushed
+	var stopWatchingConfig func()
+	if configs != nil {
+		stopWatchingConfig = watchConfig(configs, configVersion, cfg, sched, apiServer, logger)
+	}
+
 	// Notify systemd that we're ready
 	notifySystemd(logger)
 
 	// Start systemd watchdog if configured
 	stopWatchdog := startWatchdog(logger)
 
-	// Wait for shutdown signal
+	// Wait for shutdown signal or a completed drain
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	sig := <-sigChan
-	logger.Info("received shutdown signal", "signal", sig)
+	signal.Notify(sigChan, daemonSignals...)
+
+wait:
+	for {
+		select {
+		case sig := <-sigChan:
+			if sig == drainSignal {
+				if sched.IsDraining() {
+					sched.Undrain()
+				} else {
+					sched.Drain(cfg.Node.ExitWhenDrained)
+				}
+				continue
+			}
+			if sig == reopenLogSignal {
+				reopenLogFile(logger, logFile)
+				continue
+			}
+			logger.Info("received shutdown signal", "signal", sig)
+			break wait
+		case <-sched.Drained():
+			logger.Info("drain complete, shutting down")
+			break wait
+		}
+	}
 
 	// Stop watchdog
 	if stopWatchdog != nil {
@@ -119,15 +279,68 @@ func main() {
 	// Notify systemd we're stopping
 	_, _ = daemon.SdNotify(false, daemon.SdNotifyStopping)
 
+	// Stop applying pushed config
+	if stopWatchingConfig != nil {
+		stopWatchingConfig()
+	}
+
+	// Stop admin API
+	if apiServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := apiServer.Shutdown(ctx); err != nil {
+			logger.Error("failed to stop admin API", "error", err)
+		}
+		cancel()
+	}
+
 	// Stop scheduler gracefully
 	sched.Stop()
 
+	// Deliver pending notifications, including those of the jobs just stopped
+	if notifier != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		notifier.Close(ctx)
+		cancel()
+	}
+
+	// Publish pending events, including those of the jobs just stopped
+	if publisher != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		publisher.Close(ctx)
+		cancel()
+	}
+
+	// Export the spans of the jobs just stopped
+	if tracerProvider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := tracerProvider.Shutdown(ctx); err != nil {
+			logger.Error("failed to export traces", "error", err)
+		}
+		cancel()
+	}
+
 	// Close locker
 	if err := locker.Close(); err != nil {
 		logger.Error("failed to close locker", "error", err)
 	}
 
 	logger.Info("shutdown complete")
+	if logFile != nil {
+		_ = logFile.Close()
+	}
+}
+
+// reopenLogFile reopens the log file after it has been rotated.
+func reopenLogFile(logger *slog.Logger, logFile *logging.File) {
+	if logFile == nil {
+		logger.Debug("received SIGUSR2, but not logging to a file")
+		return
+	}
+	if err := logFile.Reopen(); err != nil {
+		logger.Error("failed to reopen log file", "error", err)
+		return
+	}
+	logger.Info("reopened log file")
 }
 
 // notifySystemd sends the ready notification to systemd if running under systemd.
@@ -140,6 +353,14 @@ func notifySystemd(logger *slog.Logger) {
 	}
 }
 
+// notifySystemdStatus reports the node status (e.g. leader/follower) to
+// systemd, shown by `systemctl status`.
+func notifySystemdStatus(logger *slog.Logger, status string) {
+	if _, err := daemon.SdNotify(false, "STATUS="+status); err != nil {
+		logger.Debug("failed to send status to systemd", "error", err)
+	}
+}
+
 // startWatchdog starts the systemd watchdog if configured.
 // Returns a function to stop the watchdog, or nil if not running.
 func startWatchdog(logger *slog.Logger) func() {
@@ -170,6 +391,23 @@ func startWatchdog(logger *slog.Logger) func() {
 	}
 }
 
+// connectRedis creates a Redis client and verifies the connection.
+func connectRedis(cfg config.RedisConfig) (*redis.Client, error) {
+	client := redis.NewClient(&redis.Options{
+		Addr:     cfg.Address,
+		Password: cfg.Password,
+		DB:       cfg.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return client, nil
+}
+
 // versionString returns a formatted version string.
 // If version is set (tagged release): "cronlock v1.0.0 (abc1234)"
 // If no tag: "cronlock abc1234"
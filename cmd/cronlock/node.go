@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+)
+
+// runNode implements "cronlock node drain|undrain <id>".
+func runNode(args []string) error {
+	fs, configPath := newFlagSet("node")
+	exit := fs.Bool("exit", false, "shut the node down once its running jobs finish")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		return errUsage
+	}
+	action, nodeID := positional[0], positional[1]
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	switch action {
+	case "drain":
+		if err := c.store.SetDrain(ctx, nodeID, *exit); err != nil {
+			return err
+		}
+		fmt.Printf("Drain requested for node %s", nodeID)
+		if *exit {
+			fmt.Print(" (exits once running jobs finish)")
+		}
+		fmt.Println()
+	case "undrain":
+		if err := c.store.ClearDrain(ctx, nodeID); err != nil {
+			return err
+		}
+		fmt.Printf("Undrain requested for node %s\n", nodeID)
+	default:
+		return errUsage
+	}
+
+	fmt.Println("The node applies the change on its next heartbeat (within 10s).")
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+// runNodes implements "cronlock nodes".
+func runNodes(args []string) error {
+	fs, configPath := newFlagSet("nodes")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errUsage
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	nodes, err := c.store.RecentNodes(ctx)
+	if err != nil {
+		return err
+	}
+	locks, err := c.locks.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if len(nodes) == 0 {
+		fmt.Println("No nodes have registered in the last 24h")
+	} else if err := printNodes(nodes, now); err != nil {
+		return err
+	}
+
+	orphans := orphanedLocks(locks, nodes)
+	if len(orphans) == 0 {
+		return nil
+	}
+	fmt.Println()
+	fmt.Println("Suspected orphaned locks, held by nodes that are no longer heartbeating:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "LOCK\tHOLDER\tTTL\tACQUIRED")
+	for _, info := range orphans {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", info.Name, info.Holder, info.TTL.Round(time.Second), acquiredAgo(info, now))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Println("They expire with their TTL; \"cronlock locks release <job> -force\" releases one now.")
+	return nil
+}
+
+// printNodes prints the registry, live nodes first, each sorted by ID.
+func printNodes(nodes []state.NodeRecord, now time.Time) error {
+	slices.SortFunc(nodes, func(a, b state.NodeRecord) int { return strings.Compare(a.ID, b.ID) })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tSTATUS\tHOST\tVERSION\tSTARTED\tLAST SEEN\tCONFIG\tJOBS")
+	for _, live := range []bool{true, false} {
+		for _, n := range nodes {
+			if n.Live != live {
+				continue
+			}
+			jobs := "-"
+			if len(n.Jobs) > 0 {
+				jobs = strings.Join(n.Jobs, ",")
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+				n.ID, nodeStatus(n), orDash(n.Hostname), orDash(strings.TrimPrefix(n.Version, "cronlock ")),
+				ago(n.StartedAt, now), ago(n.LastHeartbeat, now), nodeConfig(n), jobs)
+		}
+	}
+	return w.Flush()
+}
+
+// nodeConfig returns the node's job configuration hash and, if its jobs
+// were pushed to Redis, their version.
+func nodeConfig(n state.NodeRecord) string {
+	if n.ConfigVersion == 0 {
+		return orDash(n.ConfigHash)
+	}
+	return fmt.Sprintf("%s (v%d)", n.ConfigHash, n.ConfigVersion)
+}
+
+// nodeStatus returns "live", "draining" or, once the node has stopped
+// heartbeating, "dead".
+func nodeStatus(n state.NodeRecord) string {
+	switch {
+	case !n.Live:
+		return "dead"
+	case n.Draining:
+		return "draining"
+	}
+	return "live"
+}
+
+// orphanedLocks returns the locks whose holder is not a live node.
+func orphanedLocks(locks []lock.LockInfo, nodes []state.NodeRecord) []lock.LockInfo {
+	live := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		if n.Live {
+			live[n.ID] = true
+		}
+	}
+	var orphans []lock.LockInfo
+	for _, info := range locks {
+		if !live[info.Holder] {
+			orphans = append(orphans, info)
+		}
+	}
+	return orphans
+}
+
+// liveNodes returns the IDs of the nodes with a live registration.
+func liveNodes(ctx context.Context, c *cluster) (map[string]bool, error) {
+	nodes, err := c.store.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		live[n.ID] = true
+	}
+	return live, nil
+}
+
+// ago formats a time as how long ago it was, or "-" if it is unset.
+func ago(t time.Time, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return now.Sub(t).Round(time.Second).String() + " ago"
+}
+
+// orDash returns s, or "-" if it is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
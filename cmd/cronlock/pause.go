@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/state"
+)
+
+// untilLayouts are the accepted formats for pause -until, besides durations.
+var untilLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+// runPause implements "cronlock pause <job> [-until T] [-reason ...]".
+func runPause(args []string) error {
+	fs, configPath := newFlagSet("pause")
+	until := fs.String("until", "", "resume automatically at this time (RFC 3339, e.g. 2026-11-01T00:00Z) or after this duration (e.g. 4h)")
+	reason := fs.String("reason", "", "why the job is paused, shown in logs and history")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errUsage
+	}
+	jobName := positional[0]
+
+	pause := state.Pause{
+		Reason:   *reason,
+		PausedAt: time.Now().UTC(),
+		PausedBy: operator(),
+	}
+	if *until != "" {
+		pause.Until, err = parseUntil(*until, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if err := requireJob(c.cfg, jobName); err != nil {
+		return err
+	}
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if err := c.store.SetPause(ctx, jobName, pause); err != nil {
+		return err
+	}
+
+	if pause.Until.IsZero() {
+		fmt.Printf("Paused job %s until resumed\n", jobName)
+	} else {
+		fmt.Printf("Paused job %s until %s\n", jobName, pause.Until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// runResume implements "cronlock resume <job>".
+func runResume(args []string) error {
+	fs, configPath := newFlagSet("resume")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errUsage
+	}
+	jobName := positional[0]
+
+	c, err := connectCluster(*configPath)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	ctx, cancel := commandContext()
+	defer cancel()
+
+	if err := c.store.ClearPause(ctx, jobName); err != nil {
+		return err
+	}
+	fmt.Printf("Resumed job %s\n", jobName)
+	return nil
+}
+
+// parseUntil parses a pause end given as a timestamp or a duration from now.
+func parseUntil(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d <= 0 {
+			return time.Time{}, fmt.Errorf("-until duration must be positive, got %s", s)
+		}
+		return now.Add(d), nil
+	}
+
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			if !t.After(now) {
+				return time.Time{}, fmt.Errorf("-until %s is in the past", s)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid -until %q: use RFC 3339 (2026-11-01T00:00Z) or a duration (4h)", s)
+}
+
+// requireJob returns an error if the configuration has no job with this name.
+func requireJob(cfg *config.Config, name string) error {
+	for _, job := range cfg.Jobs {
+		if job.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("job %q not found in configuration", name)
+}
+
+// operator identifies who ran an admin command, as user@host.
+func operator() string {
+	name := "unknown"
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+	host, _ := os.Hostname()
+	return name + "@" + host
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+// runSchedule implements "cronlock schedule", which previews upcoming fire
+// times without connecting to Redis.
+func runSchedule(args []string) error {
+	fs, configPath := newFlagSet("schedule")
+	jobName := fs.String("job", "", "only show this job")
+	from := fs.String("from", "", "preview from this time instead of now (RFC 3339, e.g. 2026-11-01T00:00Z)")
+	count := fs.Int("count", 5, "number of fire times to show per job")
+	tz := fs.String("tz", "", "time zone to compute and show fire times in (default: local, like the scheduler)")
+	overlap := fs.Bool("overlap", false, "report jobs whose timeout exceeds the interval between fires")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 || *count < 1 {
+		return errUsage
+	}
+
+	loc := time.Local
+	if *tz != "" {
+		if loc, err = time.LoadLocation(*tz); err != nil {
+			return fmt.Errorf("invalid -tz: %w", err)
+		}
+	}
+
+	start := time.Now().In(loc)
+	if *from != "" {
+		if start, err = parseFrom(*from, loc); err != nil {
+			return err
+		}
+	}
+
+	cfg, err := config.Load(*configPath, config.WithoutHostChecks())
+	if err != nil {
+		return err
+	}
+
+	jobs := cfg.Jobs
+	if *jobName != "" {
+		if err := requireJob(cfg, *jobName); err != nil {
+			return err
+		}
+		for _, job := range cfg.Jobs {
+			if job.Name == *jobName {
+				jobs = []config.JobConfig{job}
+			}
+		}
+	}
+
+	if *overlap {
+		return printOverlaps(jobs, start, loc)
+	}
+	printFireTimes(jobs, start, *count)
+	return nil
+}
+
+// printFireTimes lists the next fire times of each job, marking UTC offset
+// changes such as DST transitions.
+func printFireTimes(jobs []config.JobConfig, start time.Time, count int) {
+	for i, job := range jobs {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		fmt.Printf("%s  %q", job.Name, job.Schedule)
+		if job.Timeout > 0 {
+			fmt.Printf("  timeout %s", job.Timeout)
+		}
+		if !job.IsEnabled() {
+			fmt.Print("  (disabled)")
+		}
+		fmt.Println()
+
+		// The config was validated on load, so the schedule parses
+		schedule, _ := config.ParseSchedule(job.Schedule)
+		times := config.FireTimes(schedule, start, count)
+		if len(times) == 0 {
+			fmt.Println("  warning: schedule never fires")
+			continue
+		}
+
+		prev := start
+		for _, t := range times {
+			fmt.Printf("  %s", t.Format("Mon 2006-01-02 15:04:05 MST"))
+			if _, prevOffset := prev.Zone(); t.Location() == prev.Location() {
+				if _, offset := t.Zone(); offset != prevOffset {
+					fmt.Printf("  (UTC offset changes from %s to %s)", prev.Format("-07:00"), t.Format("-07:00"))
+				}
+			}
+			fmt.Println()
+			prev = t
+		}
+	}
+}
+
+// printOverlaps reports jobs whose timeout is longer than the shortest
+// interval between fires. It returns an error if any enabled job overlaps,
+// so it can gate config changes in CI.
+func printOverlaps(jobs []config.JobConfig, start time.Time, loc *time.Location) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "JOB\tTIMEOUT\tSHORTEST INTERVAL\tSTATUS")
+
+	overlapping := 0
+	for _, job := range jobs {
+		interval, overlaps, err := job.Overlaps(start, loc)
+		if err != nil {
+			return err
+		}
+
+		timeout, shortest, status := "none", "-", "ok"
+		if job.Timeout > 0 {
+			timeout = job.Timeout.String()
+		}
+		if interval > 0 {
+			shortest = interval.String()
+		}
+		switch {
+		case interval == 0:
+			status = "never fires"
+		case overlaps && !job.IsEnabled():
+			status = "overlap (disabled)"
+		case overlaps:
+			status = "OVERLAP: runs are skipped while the previous run holds the lock"
+			overlapping++
+		case job.Timeout == 0:
+			status = "no timeout, can't check"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", job.Name, timeout, shortest, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if overlapping > 0 {
+		return fmt.Errorf("%d job(s) have a timeout longer than the interval between fires", overlapping)
+	}
+	return nil
+}
+
+// parseFrom parses the -from time, using loc for timestamps without an offset.
+func parseFrom(s string, loc *time.Location) (time.Time, error) {
+	for _, layout := range untilLayouts {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t.In(loc), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid -from %q: use RFC 3339, e.g. 2026-11-01T00:00Z", s)
+}
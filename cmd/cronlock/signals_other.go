@@ -0,0 +1,16 @@
+//go:build !unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals the daemon handles: only shutdown. Drain mode is toggled with
+// "cronlock node drain" and the log file isn't reopened on these platforms.
+var (
+	drainSignal     os.Signal
+	reopenLogSignal os.Signal
+	daemonSignals   = []os.Signal{os.Interrupt, syscall.SIGTERM}
+)
@@ -0,0 +1,16 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// Signals the daemon handles: SIGINT and SIGTERM shut it down, SIGUSR1
+// toggles drain mode and SIGUSR2 reopens the log file after rotation.
+var (
+	drainSignal     os.Signal = syscall.SIGUSR1
+	reopenLogSignal os.Signal = syscall.SIGUSR2
+	daemonSignals             = []os.Signal{syscall.SIGINT, syscall.SIGTERM, drainSignal, reopenLogSignal}
+)
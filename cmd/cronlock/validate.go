@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/state"
+)
+
+// runValidate implements "cronlock validate", the same as the -validate flag.
+func runValidate(args []string) error {
+	fs, configPath := newFlagSet("validate")
+	cluster := fs.Bool("cluster", false, "also check jobs against the live nodes in Redis: placement and config drift")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 0 {
+		return errUsage
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return err
+	}
+	if err := reportValid(cfg, *configPath, *cluster); err != nil {
+		return fmt.Errorf("cluster validation failed: %w", err)
+	}
+	return nil
+}
+
+// reportValid prints a summary of a valid configuration and, with cluster,
+// checks it against the live cluster.
+func reportValid(cfg *config.Config, configPath string, cluster bool) error {
+	fmt.Printf("Configuration valid: %s\n", configPath)
+	fmt.Printf("  Redis: %s\n", cfg.Redis.Address)
+	if cfg.Config.Source == config.SourceRedis {
+		fmt.Println("  Jobs:  pushed to Redis (config.source: redis)")
+	} else {
+		fmt.Printf("  Jobs:  %d\n", len(cfg.Jobs))
+	}
+	if !cluster {
+		return nil
+	}
+	return validateAgainstCluster(cfg)
+}
+
+// validateAgainstCluster checks that every enabled job can be placed on at
+// least one node, using this node's labels and the live node registry, and
+// reports jobs the live nodes configure differently.
+func validateAgainstCluster(cfg *config.Config) error {
+	client, err := connectRedis(cfg.Redis)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if cfg.Config.Source == config.SourceRedis {
+		if cfg, err = withCurrentJobs(ctx, client, cfg); err != nil {
+			return err
+		}
+		fmt.Printf("  Pushed jobs: %d\n", len(cfg.Jobs))
+	}
+
+	nodes, err := state.NewRedisStore(client, cfg.Redis.KeyPrefix).Nodes(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("  Nodes: %d live in cluster\n", len(nodes))
+
+	return errors.Join(checkPlacement(cfg, nodes), checkDrift(cfg, nodes))
+}
+
+// checkPlacement reports enabled jobs that no node can run.
+func checkPlacement(cfg *config.Config, nodes []state.NodeInfo) error {
+	nodeLabels := []map[string]string{cfg.Node.Labels}
+	for _, node := range nodes {
+		nodeLabels = append(nodeLabels, node.Labels)
+	}
+
+	unplaceable := config.UnplaceableJobs(cfg.Jobs, nodeLabels)
+	if len(unplaceable) == 0 {
+		return nil
+	}
+
+	constraints := make(map[string][]string, len(cfg.Jobs))
+	for _, job := range cfg.Jobs {
+		constraints[job.Name] = job.Constraints
+	}
+	fmt.Println("  Jobs no node can run:")
+	for _, name := range unplaceable {
+		fmt.Printf("    %s (constraints: %s)\n", name, strings.Join(constraints[name], ", "))
+	}
+	return fmt.Errorf("%d job(s) cannot be placed on any node", len(unplaceable))
+}
+
+// checkDrift prints, for each job, whether the live nodes scheduling it
+// have the same configuration of it as this file, and reports jobs that
+// drift.
+func checkDrift(cfg *config.Config, nodes []state.NodeInfo) error {
+	local := make(map[string]string, len(cfg.Jobs))
+	for _, job := range cfg.Jobs {
+		hash, err := job.Hash()
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		local[job.Name] = hash
+	}
+
+	// Hash -> node IDs, per job, across the live nodes
+	cluster := make(map[string]map[string][]string)
+	for _, node := range nodes {
+		for name, v := range node.JobConfigs {
+			if cluster[name] == nil {
+				cluster[name] = make(map[string][]string)
+			}
+			cluster[name][v.Hash] = append(cluster[name][v.Hash], node.ID)
+		}
+	}
+
+	names := slices.Collect(maps.Keys(local))
+	for name := range cluster {
+		if _, ok := local[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	fmt.Println("  Config drift:")
+	w := tabwriter.NewWriter(os.Stdout, 4, 0, 2, ' ', 0)
+	drifted := 0
+	for _, name := range names {
+		hash, inFile := local[name]
+		byHash := cluster[name]
+		var status string
+		switch {
+		case !inFile:
+			status = "not in this file; scheduled by " + describeHashes(byHash)
+			drifted++
+		case len(byHash) == 0:
+			status = fmt.Sprintf("%s  not scheduled by any live node", hash)
+		case len(byHash) == 1 && len(byHash[hash]) > 0:
+			status = fmt.Sprintf("%s  in sync on %d node(s)", hash, len(byHash[hash]))
+		default:
+			status = fmt.Sprintf("%s  DRIFT: nodes have %s", hash, describeHashes(byHash))
+			drifted++
+		}
+		fmt.Fprintf(w, "    %s\t%s\n", name, status)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if drifted > 0 {
+		return fmt.Errorf("%d job(s) are configured differently on live nodes", drifted)
+	}
+	return nil
+}
+
+// describeHashes formats the nodes having each hash of a job, e.g.
+// "1a2b3c4d5e6f (node-1, node-2), 9f8e7d6c5b4a (node-3)".
+func describeHashes(byHash map[string][]string) string {
+	var parts []string
+	for _, hash := range slices.Sorted(maps.Keys(byHash)) {
+		ids := slices.Sorted(slices.Values(byHash[hash]))
+		parts = append(parts, fmt.Sprintf("%s (%s)", hash, strings.Join(ids, ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
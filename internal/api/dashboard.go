@@ -0,0 +1,179 @@
+package api
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+)
+
+// dashboardHistory is how many recent runs the dashboard shows per job.
+const dashboardHistory = 20
+
+//go:embed dashboard
+var dashboardFiles embed.FS
+
+// clusterState is the cluster-wide view served by GET /cluster and shown
+// by the dashboard.
+type clusterState struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	NodeID      string        `json:"node_id"` // node serving the dashboard
+	Nodes       []clusterNode `json:"nodes"`
+	Jobs        []clusterJob  `json:"jobs"`
+}
+
+// clusterNode is a node from the heartbeat registry.
+type clusterNode struct {
+	ID            string            `json:"id"`
+	Labels        map[string]string `json:"labels,omitempty"`
+	RunningJobs   int               `json:"running_jobs"`
+	LoadAvg       float64           `json:"load_avg"`
+	CPUs          int               `json:"cpus"`
+	Draining      bool              `json:"draining"`
+	LastHeartbeat *time.Time        `json:"last_heartbeat,omitempty"`
+}
+
+// clusterJob combines a job's configuration with its cluster-wide state.
+type clusterJob struct {
+	Name     string        `json:"name"`
+	Schedule string        `json:"schedule"`
+	Enabled  bool          `json:"enabled"`
+	NextRun  *time.Time    `json:"next_run,omitempty"`
+	Locks    []lockStatus  `json:"locks"` // the job lock, or held shard locks
+	Paused   *clusterPause `json:"paused,omitempty"`
+	History  []runStatus   `json:"history"`
+}
+
+// clusterPause describes an active pause.
+type clusterPause struct {
+	Reason   string     `json:"reason,omitempty"`
+	Until    *time.Time `json:"until,omitempty"`
+	PausedBy string     `json:"paused_by,omitempty"`
+}
+
+// dashboardHandler serves the embedded dashboard page.
+func dashboardHandler() http.Handler {
+	files, err := fs.Sub(dashboardFiles, "dashboard")
+	if err != nil {
+		panic(err) // the embedded directory is fixed at build time
+	}
+	return http.StripPrefix("/dashboard/", http.FileServerFS(files))
+}
+
+func (s *Server) handleCluster(w http.ResponseWriter, r *http.Request) {
+	if s.store == nil {
+		writeError(w, http.StatusNotImplemented, "cluster state requires a state store")
+		return
+	}
+
+	st, err := s.readCluster(r.Context(), time.Now())
+	if err != nil {
+		s.logger.Error("failed to read cluster state", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to read cluster state")
+		return
+	}
+	writeJSON(w, http.StatusOK, st)
+}
+
+// readCluster reads lock holders, run history, pauses and node heartbeats
+// from the cluster, so every node's dashboard shows the same picture.
+func (s *Server) readCluster(ctx context.Context, now time.Time) (*clusterState, error) {
+	st := &clusterState{
+		GeneratedAt: now,
+		NodeID:      s.sched.NodeID(),
+		Nodes:       []clusterNode{},
+		Jobs:        []clusterJob{},
+	}
+
+	nodes, err := s.store.Nodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, n := range nodes {
+		st.Nodes = append(st.Nodes, clusterNode{
+			ID:            n.ID,
+			Labels:        n.Labels,
+			RunningJobs:   n.RunningJobs,
+			LoadAvg:       n.LoadAvg,
+			CPUs:          n.CPUs,
+			Draining:      n.Draining,
+			LastHeartbeat: timeOrNil(n.LastHeartbeat),
+		})
+	}
+	slices.SortFunc(st.Nodes, func(a, b clusterNode) int { return strings.Compare(a.ID, b.ID) })
+
+	var locks []lock.LockInfo
+	if s.locks != nil {
+		if locks, err = s.locks.List(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, cfg := range s.jobList() {
+		job, err := s.readClusterJob(ctx, cfg, locks, now)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", cfg.Name, err)
+		}
+		st.Jobs = append(st.Jobs, job)
+	}
+	return st, nil
+}
+
+// readClusterJob reads one job's lock holders, pause and recent runs.
+func (s *Server) readClusterJob(ctx context.Context, cfg config.JobConfig, locks []lock.LockInfo, now time.Time) (clusterJob, error) {
+	job := clusterJob{
+		Name:     cfg.Name,
+		Schedule: cfg.Schedule,
+		Enabled:  cfg.IsEnabled(),
+		Locks:    []lockStatus{},
+		History:  []runStatus{},
+	}
+
+	if sched, err := config.ParseSchedule(cfg.Schedule); err == nil {
+		job.NextRun = timeOrNil(sched.Next(now))
+	}
+
+	for _, info := range locks {
+		if isJobLock(info.Name, cfg.Name) {
+			job.Locks = append(job.Locks, newLockStatus(info))
+		}
+	}
+
+	pause, err := s.store.GetPause(ctx, cfg.Name)
+	if err != nil {
+		return job, err
+	}
+	if pause != nil {
+		job.Paused = &clusterPause{
+			Reason:   pause.Reason,
+			Until:    timeOrNil(pause.Until),
+			PausedBy: pause.PausedBy,
+		}
+	}
+
+	records, err := s.store.History(ctx, cfg.Name, dashboardHistory)
+	if err != nil {
+		return job, err
+	}
+	for _, rec := range records {
+		job.History = append(job.History, newRunStatus(rec))
+	}
+	return job, nil
+}
+
+// isJobLock reports whether a lock name is the job's lock or one of its
+// shard locks.
+func isJobLock(lockName, jobName string) bool {
+	if lockName == jobName {
+		return true
+	}
+	rest, ok := strings.CutPrefix(lockName, jobName+":shard:")
+	return ok && rest != "" && !strings.Contains(rest, ":")
+}
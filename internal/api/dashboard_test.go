@@ -0,0 +1,116 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cronlock/internal/state"
+)
+
+func TestDashboard_ServedWithoutToken(t *testing.T) {
+	env := newTestEnv(t, "s3cret")
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard/", nil)
+	rec := httptest.NewRecorder()
+	env.server.Handler().ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /dashboard/ status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	if strings.Contains(rec.Body.String(), "<script src=") {
+		t.Error("dashboard must not load external scripts")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	env.server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusFound || rec.Header().Get("Location") != "/dashboard/" {
+		t.Errorf("GET / = %d to %q, want redirect to /dashboard/", rec.Code, rec.Header().Get("Location"))
+	}
+
+	// The data behind the dashboard still needs the token
+	if code := env.do(t, http.MethodGet, "/cluster", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("GET /cluster without token status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
+
+func TestCluster(t *testing.T) {
+	env := newTestEnv(t, "")
+	ctx := context.Background()
+
+	heartbeat := time.Now().Add(-5 * time.Second)
+	_ = env.store.RegisterNode(ctx, state.NodeInfo{ID: "node-2", RunningJobs: 1, LastHeartbeat: heartbeat}, time.Minute)
+	_ = env.store.RegisterNode(ctx, state.NodeInfo{ID: "node-1", Draining: true}, time.Minute)
+
+	env.locker.SetLockHeld("backup", true)
+	env.locker.SetLockHeld("backup-old", true)
+	_ = env.store.SetPause(ctx, "report", state.Pause{Reason: "migration", PausedBy: "ops"})
+	for i := 0; i < dashboardHistory+5; i++ {
+		_ = env.store.RecordRun(ctx, state.RunRecord{
+			Job:         "backup",
+			Outcome:     state.OutcomeSuccess,
+			ScheduledAt: time.Unix(1700000000, 0).Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	var st clusterState
+	if code := env.do(t, http.MethodGet, "/cluster", "", &st); code != http.StatusOK {
+		t.Fatalf("GET /cluster status = %d, want %d", code, http.StatusOK)
+	}
+
+	if st.NodeID != "node-1" {
+		t.Errorf("NodeID = %q, want %q", st.NodeID, "node-1")
+	}
+	if len(st.Nodes) != 2 || st.Nodes[0].ID != "node-1" || !st.Nodes[0].Draining {
+		t.Fatalf("Nodes = %+v, want node-1 (draining) and node-2", st.Nodes)
+	}
+	if st.Nodes[1].LastHeartbeat == nil || !st.Nodes[1].LastHeartbeat.Equal(heartbeat) {
+		t.Errorf("node-2 LastHeartbeat = %v, want %v", st.Nodes[1].LastHeartbeat, heartbeat)
+	}
+
+	if len(st.Jobs) != 2 {
+		t.Fatalf("Jobs = %d, want 2", len(st.Jobs))
+	}
+	backup, report := st.Jobs[0], st.Jobs[1]
+
+	// Every node computes the next run from the schedule, even for jobs it doesn't run
+	if backup.NextRun == nil || report.NextRun == nil {
+		t.Errorf("NextRun = %v, %v, want both set", backup.NextRun, report.NextRun)
+	}
+	if len(backup.Locks) != 1 || backup.Locks[0].Holder != "node-2" {
+		t.Errorf("backup.Locks = %+v, want only its own lock held by node-2", backup.Locks)
+	}
+	if len(backup.History) != dashboardHistory {
+		t.Errorf("backup.History = %d runs, want %d", len(backup.History), dashboardHistory)
+	}
+	if report.Paused == nil || report.Paused.Reason != "migration" || report.Paused.PausedBy != "ops" {
+		t.Errorf("report.Paused = %+v, want migration pause by ops", report.Paused)
+	}
+}
+
+func TestIsJobLock(t *testing.T) {
+	tests := []struct {
+		lock string
+		job  string
+		want bool
+	}{
+		{"backup", "backup", true},
+		{"reindex:shard:3", "reindex", true},
+		{"backup-old", "backup", false},
+		{"reindex:shard:", "reindex", false},
+		{"a:shard:1:shard:2", "a", false},
+		{"a:shard:1:shard:2", "a:shard:1", true},
+	}
+	for _, tt := range tests {
+		if got := isJobLock(tt.lock, tt.job); got != tt.want {
+			t.Errorf("isJobLock(%q, %q) = %v, want %v", tt.lock, tt.job, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,332 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+)
+
+// Limits for GET /jobs/{name}/history.
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// jobStatus is a job's entry in GET /jobs.
+type jobStatus struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	Command        string     `json:"command"`
+	TimeoutSeconds float64    `json:"timeout_seconds,omitempty"`
+	Shards         int        `json:"shards,omitempty"`
+	Constraints    []string   `json:"constraints,omitempty"`
+	Enabled        bool       `json:"enabled"`
+	Scheduled      bool       `json:"scheduled"` // registered on this node
+	Running        bool       `json:"running"`
+	NextRun        *time.Time `json:"next_run,omitempty"`
+	PrevRun        *time.Time `json:"prev_run,omitempty"`
+}
+
+// runStatus is one entry in GET /jobs/{name}/history.
+type runStatus struct {
+	RunID           string     `json:"run_id,omitempty"`
+	NodeID          string     `json:"node_id"`
+	Outcome         string     `json:"outcome"`
+	ScheduledAt     time.Time  `json:"scheduled_at"`
+	StartedAt       *time.Time `json:"started_at,omitempty"`
+	DurationSeconds float64    `json:"duration_seconds"`
+	ExitCode        int        `json:"exit_code"`
+	Error           string     `json:"error,omitempty"`
+	Reason          string     `json:"reason,omitempty"`
+	Slow            bool       `json:"slow,omitempty"`
+}
+
+// lockStatus is one entry in GET /locks.
+type lockStatus struct {
+	Name       string     `json:"name"`
+	Holder     string     `json:"holder"`
+	TTLSeconds float64    `json:"ttl_seconds"`
+	AcquiredAt *time.Time `json:"acquired_at,omitempty"`
+}
+
+// nodeStatus is the response of GET /node and the drain endpoints.
+type nodeStatus struct {
+	ID          string   `json:"id"`
+	Status      string   `json:"status"`
+	Leader      bool     `json:"leader"`
+	LeaderID    string   `json:"leader_id,omitempty"`
+	Draining    bool     `json:"draining"`
+	RunningJobs []string `json:"running_jobs"`
+}
+
+// healthStatus is the response of /healthz and /readyz.
+type healthStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (s *Server) handleListJobs(w http.ResponseWriter, r *http.Request) {
+	cfgs := s.jobList()
+	jobs := make([]jobStatus, 0, len(cfgs))
+	for _, cfg := range cfgs {
+		jobs = append(jobs, s.jobStatus(cfg))
+	}
+	writeJSON(w, http.StatusOK, jobs)
+}
+
+func (s *Server) handleGetJob(w http.ResponseWriter, r *http.Request) {
+	cfg, ok := s.jobConfig(r.PathValue("name"))
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.jobStatus(cfg))
+}
+
+func (s *Server) handleJobHistory(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+	if _, ok := s.jobConfig(name); !ok {
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	}
+	if s.store == nil {
+		writeError(w, http.StatusNotImplemented, "run history requires a state store")
+		return
+	}
+
+	limit := defaultHistoryLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxHistoryLimit)
+	}
+
+	records, err := s.store.History(r.Context(), name, limit)
+	if err != nil {
+		s.logger.Error("failed to read job history", "job", name, "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to read job history")
+		return
+	}
+
+	runs := make([]runStatus, 0, len(records))
+	for _, rec := range records {
+		runs = append(runs, newRunStatus(rec))
+	}
+	writeJSON(w, http.StatusOK, runs)
+}
+
+func (s *Server) handleTriggerJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	err := s.sched.Trigger(name)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		if _, ok := s.jobConfig(name); ok {
+			writeError(w, http.StatusConflict, "job is not scheduled on this node")
+			return
+		}
+		writeError(w, http.StatusNotFound, "job not found")
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	s.logger.Info("job triggered via API", "job", name, "client", clientName(r))
+	writeJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "triggered"})
+}
+
+func (s *Server) handleCancelJob(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("name")
+
+	job, ok := s.sched.GetJob(name)
+	if !ok {
+		writeError(w, http.StatusNotFound, "job not found on this node")
+		return
+	}
+	if !job.IsRunning() {
+		writeError(w, http.StatusConflict, "job is not running")
+		return
+	}
+
+	job.Cancel()
+	s.logger.Info("job canceled via API", "job", name, "client", clientName(r))
+	writeJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "canceling"})
+}
+
+func (s *Server) handleListLocks(w http.ResponseWriter, r *http.Request) {
+	if s.locks == nil {
+		writeError(w, http.StatusNotImplemented, "lock inspection is not supported")
+		return
+	}
+
+	infos, err := s.locks.List(r.Context())
+	if err != nil {
+		s.logger.Error("failed to list locks", "error", err)
+		writeError(w, http.StatusInternalServerError, "failed to list locks")
+		return
+	}
+
+	locks := make([]lockStatus, 0, len(infos))
+	for _, info := range infos {
+		locks = append(locks, newLockStatus(info))
+	}
+	writeJSON(w, http.StatusOK, locks)
+}
+
+func (s *Server) handleNode(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.nodeStatus(r.Context()))
+}
+
+func (s *Server) handleDrain(w http.ResponseWriter, r *http.Request) {
+	exit, _ := strconv.ParseBool(r.URL.Query().Get("exit"))
+
+	s.logger.Info("node drain requested via API", "exit_when_drained", exit, "client", clientName(r))
+	s.sched.Drain(exit)
+	writeJSON(w, http.StatusOK, s.nodeStatus(r.Context()))
+}
+
+func (s *Server) handleUndrain(w http.ResponseWriter, r *http.Request) {
+	s.logger.Info("node undrain requested via API", "client", clientName(r))
+	s.sched.Undrain()
+	writeJSON(w, http.StatusOK, s.nodeStatus(r.Context()))
+}
+
+// handleHealthz reports whether the node can reach Redis.
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkRedis(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, healthStatus{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// handleReadyz reports whether the node can reach Redis and is accepting
+// jobs, i.e. not draining.
+func (s *Server) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if err := s.checkRedis(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, healthStatus{Status: "unavailable", Error: err.Error()})
+		return
+	}
+	if s.sched.IsDraining() {
+		writeJSON(w, http.StatusServiceUnavailable, healthStatus{Status: scheduler.StatusDraining})
+		return
+	}
+	writeJSON(w, http.StatusOK, healthStatus{Status: "ok"})
+}
+
+// checkRedis pings Redis, if a health check is configured.
+func (s *Server) checkRedis(ctx context.Context) error {
+	if s.ping == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	return s.ping(ctx)
+}
+
+// jobConfig looks up a job by name in the configured job list.
+func (s *Server) jobConfig(name string) (config.JobConfig, bool) {
+	jobs := s.jobList()
+	i := slices.IndexFunc(jobs, func(j config.JobConfig) bool { return j.Name == name })
+	if i == -1 {
+		return config.JobConfig{}, false
+	}
+	return jobs[i], true
+}
+
+// jobStatus combines a job's configuration with its state on this node.
+func (s *Server) jobStatus(cfg config.JobConfig) jobStatus {
+	status := jobStatus{
+		Name:           cfg.Name,
+		Schedule:       cfg.Schedule,
+		Command:        cfg.Command.String(),
+		TimeoutSeconds: cfg.Timeout.Seconds(),
+		Shards:         cfg.Shards,
+		Constraints:    cfg.Constraints,
+		Enabled:        cfg.IsEnabled(),
+	}
+
+	job, ok := s.sched.GetJob(cfg.Name)
+	if !ok {
+		return status
+	}
+	status.Scheduled = true
+	status.Running = job.IsRunning()
+
+	if next, prev, ok := s.sched.NextRun(cfg.Name); ok {
+		status.NextRun = timeOrNil(next)
+		status.PrevRun = timeOrNil(prev)
+	}
+	return status
+}
+
+// nodeStatus reports this node's scheduling state.
+func (s *Server) nodeStatus(ctx context.Context) nodeStatus {
+	status := nodeStatus{
+		ID:          s.sched.NodeID(),
+		Status:      s.sched.Status(),
+		Leader:      s.sched.IsLeader(),
+		Draining:    s.sched.IsDraining(),
+		RunningJobs: s.sched.RunningJobs(),
+	}
+	if status.RunningJobs == nil {
+		status.RunningJobs = []string{}
+	}
+	slices.Sort(status.RunningJobs)
+
+	if leaderID, err := s.sched.LeaderID(ctx); err != nil {
+		s.logger.Warn("failed to look up leader", "error", err)
+	} else {
+		status.LeaderID = leaderID
+	}
+	return status
+}
+
+// newLockStatus converts a held lock for the API.
+func newLockStatus(info lock.LockInfo) lockStatus {
+	return lockStatus{
+		Name:       info.Name,
+		Holder:     info.Holder,
+		TTLSeconds: info.TTL.Seconds(),
+		AcquiredAt: timeOrNil(info.AcquiredAt),
+	}
+}
+
+// newRunStatus converts a history record for the API.
+func newRunStatus(rec state.RunRecord) runStatus {
+	return runStatus{
+		RunID:           rec.RunID,
+		NodeID:          rec.NodeID,
+		Outcome:         rec.Outcome,
+		ScheduledAt:     rec.ScheduledAt,
+		StartedAt:       timeOrNil(rec.StartedAt),
+		DurationSeconds: rec.Duration.Seconds(),
+		ExitCode:        rec.ExitCode,
+		Error:           rec.Error,
+		Reason:          rec.Reason,
+		Slow:            rec.Slow,
+	}
+}
+
+// timeOrNil returns nil for the zero time so it is omitted from JSON.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// metricsContentType is the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// labelEscaper escapes label values for the text exposition format.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Metric types.
+const (
+	metricGauge   = "gauge"
+	metricCounter = "counter"
+)
+
+// family is a metric family written by handleMetrics.
+type family struct {
+	name    string
+	kind    string
+	help    string
+	samples []sample
+}
+
+// sample is one value of a gauge, labelled with a job name.
+type sample struct {
+	job   string
+	value float64
+}
+
+// write writes the family in the text exposition format.
+func (f *family) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+	for _, s := range f.samples {
+		fmt.Fprintf(w, "%s{job=\"%s\"} %g\n", f.name, labelEscaper.Replace(s.job), s.value)
+	}
+}
+
+// handleMetrics serves metrics in the Prometheus text format. The deadline
+// metrics are read from Redis, so every node reports the same values; the
+// run counters are the node's own.
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	deadlines, err := s.sched.Deadlines(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	window := &family{
+		name: "cronlock_job_expect_success_within_seconds",
+		kind: metricGauge,
+		help: "The job's expect_success_within window.",
+	}
+	lastSuccess := &family{
+		name: "cronlock_job_last_success_timestamp_seconds",
+		kind: metricGauge,
+		help: "Unix time the job last finished a successful run.",
+	}
+	overdue := &family{
+		name: "cronlock_job_success_overdue",
+		kind: metricGauge,
+		help: "Whether the job has not succeeded within expect_success_within (1) or has (0).",
+	}
+	for _, d := range deadlines {
+		window.samples = append(window.samples, sample{d.Job, d.Window.Seconds()})
+		if !d.LastSuccess.IsZero() {
+			lastSuccess.samples = append(lastSuccess.samples, sample{d.Job, float64(d.LastSuccess.UnixMilli()) / 1000})
+		}
+		value := 0.0
+		if d.Overdue {
+			value = 1
+		}
+		overdue.samples = append(overdue.samples, sample{d.Job, value})
+	}
+
+	slow := &family{
+		name: "cronlock_job_slow_runs_total",
+		kind: metricCounter,
+		help: "Runs on this node still going after the job's warn_after.",
+	}
+	slowRuns := s.sched.SlowRuns()
+	for _, job := range s.jobList() {
+		if job.WarnAfter > 0 {
+			slow.samples = append(slow.samples, sample{job.Name, float64(slowRuns[job.Name])})
+		}
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	for _, f := range []*family{window, lastSuccess, overdue, slow} {
+		f.write(w)
+	}
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+)
+
+func TestMetrics(t *testing.T) {
+	store := state.NewMockStore()
+	jobs := []config.JobConfig{
+		{Name: "backup", Schedule: "@every 1h", Command: config.ShellCommand("true"), ExpectSuccessWithin: time.Hour},
+		{Name: `re"port`, Schedule: "@every 1h", Command: config.ShellCommand("true"), ExpectSuccessWithin: 2 * time.Hour},
+		{Name: "cleanup", Schedule: "@every 1h", Command: config.ShellCommand("true"), WarnAfter: time.Minute},
+	}
+	last := time.Unix(1700000000, 0)
+	run := state.RunRecord{Job: "backup", Outcome: state.OutcomeSuccess, ScheduledAt: last, StartedAt: last}
+	if err := store.RecordRun(context.Background(), run); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+
+	sched := scheduler.New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger(),
+		scheduler.WithStore(store),
+		scheduler.WithMonitor(lock.NewMockLease(), jobs),
+	)
+	server := New(config.APIConfig{Token: "s3cret"}, sched, jobs, newTestLogger(), WithStore(store))
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without token status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	req.Header.Set("Authorization", "Bearer s3cret")
+	rec = httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /metrics status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+		t.Errorf("Content-Type = %q, want text/plain", ct)
+	}
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"# TYPE cronlock_job_success_overdue gauge\n",
+		`cronlock_job_expect_success_within_seconds{job="backup"} 3600` + "\n",
+		`cronlock_job_expect_success_within_seconds{job="re\"port"} 7200` + "\n",
+		`cronlock_job_last_success_timestamp_seconds{job="backup"} 1.7e+09` + "\n",
+		`cronlock_job_success_overdue{job="backup"} 1` + "\n",
+		`cronlock_job_success_overdue{job="re\"port"} 0` + "\n",
+		"# TYPE cronlock_job_slow_runs_total counter\n",
+		`cronlock_job_slow_runs_total{job="cleanup"} 0` + "\n",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q:\n%s", want, body)
+		}
+	}
+	for _, unwanted := range []string{
+		`cronlock_job_success_overdue{job="cleanup"}`,
+		`cronlock_job_last_success_timestamp_seconds{job="re\"port"}`,
+		`cronlock_job_slow_runs_total{job="backup"}`,
+	} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("metrics contain %q:\n%s", unwanted, body)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+// Package api serves the admin HTTP API used to query and control a running node.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+)
+
+// healthCheckTimeout bounds the Redis ping made by /healthz and /readyz.
+const healthCheckTimeout = 2 * time.Second
+
+// Server serves the admin HTTP API for one node.
+type Server struct {
+	sched  *scheduler.Scheduler
+	token  string
+	logger *slog.Logger
+
+	store state.Store
+	locks lock.Inspector
+	ping  func(ctx context.Context) error
+
+	mu   sync.Mutex
+	jobs []config.JobConfig
+
+	handler http.Handler
+	srv     *http.Server
+}
+
+// Option configures optional Server dependencies.
+type Option func(*Server)
+
+// WithStore sets the state store used to serve job history.
+func WithStore(store state.Store) Option {
+	return func(s *Server) {
+		s.store = store
+	}
+}
+
+// WithLocks sets the lock inspector used to serve /locks.
+func WithLocks(locks lock.Inspector) Option {
+	return func(s *Server) {
+		s.locks = locks
+	}
+}
+
+// WithHealthCheck sets the function /healthz and /readyz call to check
+// Redis connectivity.
+func WithHealthCheck(ping func(ctx context.Context) error) Option {
+	return func(s *Server) {
+		s.ping = ping
+	}
+}
+
+// SetJobs replaces the job list, after the node applied a new version of
+// the job configuration.
+func (s *Server) SetJobs(jobs []config.JobConfig) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = jobs
+}
+
+// jobList returns the full job list from the config.
+func (s *Server) jobList() []config.JobConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.jobs
+}
+
+// New creates an API server. jobs is the full job list from the config,
+// including jobs this node doesn't schedule.
+func New(cfg config.APIConfig, sched *scheduler.Scheduler, jobs []config.JobConfig, logger *slog.Logger, opts ...Option) *Server {
+	s := &Server{
+		sched:  sched,
+		jobs:   jobs,
+		token:  cfg.Token,
+		logger: logger.With("component", "api"),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.handler = s.routes()
+	s.srv = &http.Server{
+		Addr:              cfg.Listen,
+		Handler:           s.handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return s
+}
+
+// Handler returns the API's HTTP handler.
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start listens on the configured address and serves requests in the background.
+func (s *Server) Start() error {
+	ln, err := net.Listen("tcp", s.srv.Addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", s.srv.Addr, err)
+	}
+
+	s.logger.Info("serving admin API", "address", ln.Addr().String(), "auth", s.token != "")
+	go func() {
+		if err := s.srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("admin API server failed", "error", err)
+		}
+	}()
+	return nil
+}
+
+// Shutdown stops the server, waiting for in-flight requests until ctx is done.
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// routes builds the API's request multiplexer. Health checks are served
+// without authentication so load balancers and orchestrators can probe them,
+// as is the dashboard page, which holds no data and asks for the token itself.
+func (s *Server) routes() http.Handler {
+	api := http.NewServeMux()
+	api.HandleFunc("GET /jobs", s.handleListJobs)
+	api.HandleFunc("GET /jobs/{name}", s.handleGetJob)
+	api.HandleFunc("GET /jobs/{name}/history", s.handleJobHistory)
+	api.HandleFunc("POST /jobs/{name}/trigger", s.handleTriggerJob)
+	api.HandleFunc("POST /jobs/{name}/cancel", s.handleCancelJob)
+	api.HandleFunc("GET /locks", s.handleListLocks)
+	api.HandleFunc("GET /cluster", s.handleCluster)
+	api.HandleFunc("GET /node", s.handleNode)
+	api.HandleFunc("POST /node/drain", s.handleDrain)
+	api.HandleFunc("POST /node/undrain", s.handleUndrain)
+	api.HandleFunc("GET /metrics", s.handleMetrics)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", s.handleHealthz)
+	mux.HandleFunc("GET /readyz", s.handleReadyz)
+	mux.Handle("GET /{$}", http.RedirectHandler("/dashboard/", http.StatusFound))
+	mux.Handle("GET /dashboard/", dashboardHandler())
+	mux.Handle("/", s.requireToken(api))
+	return mux
+}
+
+// requireToken rejects requests without the configured bearer token.
+// Without a token every request is allowed.
+func (s *Server) requireToken(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+	want := []byte("Bearer " + s.token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(got, want) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="cronlock"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeJSON writes v as the JSON response body with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// writeError writes a JSON error response.
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// clientName identifies the caller in audit log lines.
+func clientName(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return strings.TrimSpace(r.RemoteAddr)
+}
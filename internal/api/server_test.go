@@ -0,0 +1,311 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/scheduler"
+	"cronlock/internal/state"
+)
+
+func newTestLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
+		Level: slog.LevelError, // Suppress logs during tests
+	}))
+}
+
+// testEnv is an API server backed by a scheduler with mock dependencies.
+type testEnv struct {
+	server  *Server
+	sched   *scheduler.Scheduler
+	locker  *lock.MockLocker
+	store   *state.MockStore
+	pingErr error
+}
+
+func newTestEnv(t *testing.T, token string) *testEnv {
+	t.Helper()
+
+	locker := lock.NewMockLocker()
+	locker.HolderID = "node-2"
+	store := state.NewMockStore()
+
+	jobs := []config.JobConfig{
+		{Name: "backup", Schedule: "@every 1h", Command: config.ShellCommand("sleep 5"), Timeout: time.Minute},
+		{Name: "report", Schedule: "@every 1h", Command: config.ShellCommand("true"), Constraints: []string{"role=batch"}},
+	}
+
+	sched := scheduler.New(locker, config.NodeConfig{ID: "node-1"}, newTestLogger(), scheduler.WithStore(store))
+	for _, job := range jobs {
+		if err := sched.AddJob(job); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+	}
+
+	env := &testEnv{sched: sched, locker: locker, store: store}
+	env.server = New(config.APIConfig{Token: token}, sched, jobs, newTestLogger(),
+		WithStore(store),
+		WithLocks(locker),
+		WithHealthCheck(func(ctx context.Context) error { return env.pingErr }),
+	)
+	return env
+}
+
+// do sends a request to the server and decodes the JSON response into out, if set.
+func (e *testEnv) do(t *testing.T, method, path, token string, out any) int {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.server.Handler().ServeHTTP(rec, req)
+
+	if out != nil {
+		if err := json.NewDecoder(rec.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAuth(t *testing.T) {
+	env := newTestEnv(t, "s3cret")
+
+	tests := []struct {
+		name   string
+		path   string
+		token  string
+		status int
+	}{
+		{"no token", "/jobs", "", http.StatusUnauthorized},
+		{"wrong token", "/jobs", "wrong", http.StatusUnauthorized},
+		{"valid token", "/jobs", "s3cret", http.StatusOK},
+		{"healthz without token", "/healthz", "", http.StatusOK},
+		{"readyz without token", "/readyz", "", http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := env.do(t, http.MethodGet, tt.path, tt.token, nil); got != tt.status {
+				t.Errorf("GET %s status = %d, want %d", tt.path, got, tt.status)
+			}
+		})
+	}
+}
+
+func TestAuth_NoTokenConfigured(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	if got := env.do(t, http.MethodGet, "/jobs", "", nil); got != http.StatusOK {
+		t.Errorf("GET /jobs status = %d, want %d", got, http.StatusOK)
+	}
+}
+
+func TestListJobs(t *testing.T) {
+	env := newTestEnv(t, "")
+	env.sched.Start()
+	defer env.sched.Stop()
+
+	var jobs []jobStatus
+	if code := env.do(t, http.MethodGet, "/jobs", "", &jobs); code != http.StatusOK {
+		t.Fatalf("GET /jobs status = %d, want %d", code, http.StatusOK)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("GET /jobs returned %d jobs, want 2", len(jobs))
+	}
+
+	backup := jobs[0]
+	if backup.Name != "backup" || !backup.Enabled || !backup.Scheduled || backup.Running {
+		t.Errorf("backup = %+v, want enabled, scheduled and idle", backup)
+	}
+	if backup.TimeoutSeconds != 60 {
+		t.Errorf("backup.TimeoutSeconds = %v, want 60", backup.TimeoutSeconds)
+	}
+	if backup.NextRun == nil || time.Until(*backup.NextRun) > time.Hour {
+		t.Errorf("backup.NextRun = %v, want within the next hour", backup.NextRun)
+	}
+
+	// report's constraints don't match this node, so it isn't scheduled here
+	report := jobs[1]
+	if report.Scheduled || report.NextRun != nil {
+		t.Errorf("report = %+v, want not scheduled", report)
+	}
+}
+
+func TestGetJob_NotFound(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	if code := env.do(t, http.MethodGet, "/jobs/missing", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET /jobs/missing status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	env := newTestEnv(t, "")
+	ctx := context.Background()
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < 3; i++ {
+		_ = env.store.RecordRun(ctx, state.RunRecord{
+			Job:         "backup",
+			NodeID:      "node-1",
+			Outcome:     state.OutcomeSuccess,
+			ScheduledAt: base.Add(time.Duration(i) * time.Hour),
+			Duration:    1500 * time.Millisecond,
+		})
+	}
+
+	var runs []runStatus
+	if code := env.do(t, http.MethodGet, "/jobs/backup/history?limit=2", "", &runs); code != http.StatusOK {
+		t.Fatalf("GET history status = %d, want %d", code, http.StatusOK)
+	}
+	if len(runs) != 2 {
+		t.Fatalf("GET history returned %d runs, want 2", len(runs))
+	}
+	if !runs[0].ScheduledAt.Equal(base.Add(2 * time.Hour)) {
+		t.Errorf("runs[0].ScheduledAt = %v, want newest run first", runs[0].ScheduledAt)
+	}
+	if runs[0].DurationSeconds != 1.5 || runs[0].Outcome != state.OutcomeSuccess {
+		t.Errorf("runs[0] = %+v, want 1.5s success", runs[0])
+	}
+
+	if code := env.do(t, http.MethodGet, "/jobs/backup/history?limit=zero", "", nil); code != http.StatusBadRequest {
+		t.Errorf("GET history with invalid limit status = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code := env.do(t, http.MethodGet, "/jobs/missing/history", "", nil); code != http.StatusNotFound {
+		t.Errorf("GET history for missing job status = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestTriggerAndCancelJob(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	if code := env.do(t, http.MethodPost, "/jobs/backup/cancel", "", nil); code != http.StatusConflict {
+		t.Errorf("cancel idle job status = %d, want %d", code, http.StatusConflict)
+	}
+
+	if code := env.do(t, http.MethodPost, "/jobs/backup/trigger", "", nil); code != http.StatusAccepted {
+		t.Fatalf("trigger status = %d, want %d", code, http.StatusAccepted)
+	}
+
+	job, _ := env.sched.GetJob("backup")
+	waitFor(t, 2*time.Second, job.IsRunning)
+
+	if code := env.do(t, http.MethodPost, "/jobs/backup/trigger", "", nil); code != http.StatusConflict {
+		t.Errorf("trigger running job status = %d, want %d", code, http.StatusConflict)
+	}
+
+	if code := env.do(t, http.MethodPost, "/jobs/backup/cancel", "", nil); code != http.StatusAccepted {
+		t.Fatalf("cancel status = %d, want %d", code, http.StatusAccepted)
+	}
+	// The first cancel can land before the command has started, so repeat it
+	waitFor(t, 2*time.Second, func() bool {
+		env.do(t, http.MethodPost, "/jobs/backup/cancel", "", nil)
+		return !job.IsRunning()
+	})
+
+	history, _ := env.store.History(context.Background(), "backup", 10)
+	if len(history) != 1 || history[0].Outcome != state.OutcomeFailure {
+		t.Errorf("history = %+v, want one failed (canceled) run", history)
+	}
+}
+
+func TestTriggerJob_NotScheduled(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/jobs/report/trigger", http.StatusConflict},
+		{"/jobs/missing/trigger", http.StatusNotFound},
+		{"/jobs/missing/cancel", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code := env.do(t, http.MethodPost, tt.path, "", nil); code != tt.status {
+			t.Errorf("POST %s status = %d, want %d", tt.path, code, tt.status)
+		}
+	}
+}
+
+func TestListLocks(t *testing.T) {
+	env := newTestEnv(t, "")
+	env.locker.SetLockHeld("backup", true)
+
+	var locks []lockStatus
+	if code := env.do(t, http.MethodGet, "/locks", "", &locks); code != http.StatusOK {
+		t.Fatalf("GET /locks status = %d, want %d", code, http.StatusOK)
+	}
+	if len(locks) != 1 || locks[0].Name != "backup" || locks[0].Holder != "node-2" {
+		t.Errorf("GET /locks = %+v, want backup held by node-2", locks)
+	}
+}
+
+func TestDrainEndpoints(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	var node nodeStatus
+	if code := env.do(t, http.MethodPost, "/node/drain", "", &node); code != http.StatusOK {
+		t.Fatalf("POST /node/drain status = %d, want %d", code, http.StatusOK)
+	}
+	if !node.Draining || node.Status != scheduler.StatusDraining || node.ID != "node-1" {
+		t.Errorf("node after drain = %+v, want node-1 draining", node)
+	}
+
+	if code := env.do(t, http.MethodGet, "/readyz", "", nil); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /readyz while draining status = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	if code := env.do(t, http.MethodPost, "/node/undrain", "", &node); code != http.StatusOK {
+		t.Fatalf("POST /node/undrain status = %d, want %d", code, http.StatusOK)
+	}
+	if node.Draining {
+		t.Errorf("node after undrain = %+v, want not draining", node)
+	}
+}
+
+func TestHealthChecks_RedisDown(t *testing.T) {
+	env := newTestEnv(t, "")
+	env.pingErr = errors.New("connection refused")
+
+	for _, path := range []string{"/healthz", "/readyz"} {
+		var health healthStatus
+		if code := env.do(t, http.MethodGet, path, "", &health); code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s status = %d, want %d", path, code, http.StatusServiceUnavailable)
+		}
+		if health.Error != "connection refused" {
+			t.Errorf("GET %s error = %q, want %q", path, health.Error, "connection refused")
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	env := newTestEnv(t, "")
+
+	if code := env.do(t, http.MethodGet, "/jobs/backup/trigger", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET trigger status = %d, want %d", code, http.StatusMethodNotAllowed)
+	}
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// Command is a job command. In the config it is either a string, which is
+// run with a shell, or a list, which is executed directly.
+type Command struct {
+	// Line is a command line passed to the shell with -c.
+	Line string
+	// Argv is a program and its arguments, executed without a shell.
+	Argv []string
+}
+
+// ShellCommand returns a Command that runs line with the shell.
+func ShellCommand(line string) Command {
+	return Command{Line: line}
+}
+
+// IsZero reports whether no command is set.
+func (c Command) IsZero() bool {
+	return c.Line == "" && len(c.Argv) == 0
+}
+
+// String returns the command as shown in logs and the API: the command line,
+// or the argv with arguments quoted where needed.
+func (c Command) String() string {
+	if len(c.Argv) == 0 {
+		return c.Line
+	}
+	args := make([]string, len(c.Argv))
+	for i, arg := range c.Argv {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'\\$`") {
+			arg = strconv.Quote(arg)
+		}
+		args[i] = arg
+	}
+	return strings.Join(args, " ")
+}
+
+// commandType is the reflect type of Command, used by the decode hook.
+var commandType = reflect.TypeOf(Command{})
+
+// commandDecodeHook decodes a config string or list into a Command.
+func commandDecodeHook(from, to reflect.Type, data any) (any, error) {
+	if to != commandType {
+		return data, nil
+	}
+
+	switch v := data.(type) {
+	case string:
+		return Command{Line: v}, nil
+	case []any:
+		argv := make([]string, len(v))
+		for i, arg := range v {
+			switch a := arg.(type) {
+			case string:
+				argv[i] = a
+			case int, int64, float64, bool:
+				argv[i] = fmt.Sprint(a)
+			default:
+				return nil, fmt.Errorf("command argument %d must be a string, got %T", i, arg)
+			}
+		}
+		return Command{Argv: argv}, nil
+	case []string:
+		return Command{Argv: v}, nil
+	default:
+		return nil, fmt.Errorf("command must be a string or a list, got %s", from)
+	}
+}
+
+// decoderConfig returns koanf's default decoder configuration with the
+// Command hook added and text unmarshaling for types such as ByteSize.
+func decoderConfig() *mapstructure.DecoderConfig {
+	return &mapstructure.DecoderConfig{
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.TextUnmarshallerHookFunc(),
+			commandDecodeHook,
+		),
+		WeaklyTypedInput: true,
+	}
+}
+
+// lookPath checks that the program exists and is executable. A program
+// containing a path separator is checked as a path, relative to workDir if
+// it isn't absolute; a bare name is looked up in PATH, as exec does.
+func lookPath(program, workDir string) error {
+	if program == "" {
+		return fmt.Errorf("program is empty")
+	}
+	if strings.Contains(program, "/") && !filepath.IsAbs(program) && workDir != "" {
+		program = filepath.Join(workDir, program)
+	}
+	if _, err := exec.LookPath(program); err != nil {
+		return err
+	}
+	return nil
+}
+
+// validateShell checks a shell setting: the shell program followed by
+// optional flags, e.g. "/bin/bash -eo pipefail".
+func validateShell(shell string) error {
+	fields := strings.Fields(shell)
+	if len(fields) == 0 {
+		return fmt.Errorf("shell is empty")
+	}
+	return lookPath(fields[0], "")
+}
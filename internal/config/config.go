@@ -1,18 +1,71 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
 
 // Config represents the complete application configuration.
 type Config struct {
-	Node  NodeConfig  `koanf:"node"`
-	Redis RedisConfig `koanf:"redis"`
-	Jobs  []JobConfig `koanf:"jobs"`
+	Node   NodeConfig   `koanf:"node"`
+	Redis  RedisConfig  `koanf:"redis"`
+	API    APIConfig    `koanf:"api"`
+	Output OutputConfig `koanf:"output"`
+	Log    LogConfig    `koanf:"log"`
+	OTel   OTelConfig   `koanf:"otel"`
+	Events EventsConfig `koanf:"events"`
+	Config SourceConfig `koanf:"config"`
+	// Notifiers are the named channels jobs send notifications to.
+	Notifiers []NotifierConfig `koanf:"notifiers"`
+	Jobs      []JobConfig      `koanf:"jobs"`
 }
 
+// Coordination modes for NodeConfig.Coordination.
+const (
+	// CoordinationLock makes every node compete for every job's lock.
+	CoordinationLock = "lock"
+	// CoordinationLeader runs jobs only on the node holding the leader lease.
+	CoordinationLeader = "leader"
+)
+
+// PlacementLeastLoaded makes busier nodes delay lock acquisition so that
+// lightly loaded nodes tend to win.
+const PlacementLeastLoaded = "least_loaded"
+
+// Drift policies for NodeConfig.DriftPolicy and JobConfig.DriftPolicy,
+// deciding what a node does when a live peer has a job configured
+// differently.
+const (
+	// DriftWarn logs the drift and runs the job as usual.
+	DriftWarn = "warn"
+	// DriftRefuseToRun skips the job's runs on this node while it drifts.
+	DriftRefuseToRun = "refuse_to_run"
+	// DriftNewestWins leaves the job's runs to the nodes whose
+	// configuration of it was loaded most recently.
+	DriftNewestWins = "newest_wins"
+)
+
 // NodeConfig contains node-specific settings.
 type NodeConfig struct {
-	ID          string        `koanf:"id"`
-	GracePeriod time.Duration `koanf:"grace_period"`
+	ID           string            `koanf:"id"`
+	GracePeriod  time.Duration     `koanf:"grace_period"`
+	Labels       map[string]string `koanf:"labels"`
+	Coordination string            `koanf:"coordination"`
+	LeaderTTL    time.Duration     `koanf:"leader_ttl"`
+	// MaxConcurrentJobs caps how many jobs this node runs at once (0 = no cap).
+	MaxConcurrentJobs int `koanf:"max_concurrent_jobs"`
+	// ExitWhenDrained makes a node drained by SIGUSR1 shut down once its
+	// running jobs finish.
+	ExitWhenDrained bool `koanf:"exit_when_drained"`
+	// Shell runs string commands and hooks, e.g. "/bin/bash -eo pipefail".
+	// Empty uses $SHELL, falling back to /bin/sh.
+	Shell string `koanf:"shell"`
+	// CgroupParent is the cgroup v2 directory under which a cgroup is
+	// created for each run of a job with cgroup limits.
+	CgroupParent string `koanf:"cgroup_parent"`
+	// DriftPolicy is the drift policy of jobs that don't set their own.
+	DriftPolicy string `koanf:"drift_policy"`
 }
 
 // RedisConfig contains Redis connection settings.
@@ -23,18 +76,168 @@ type RedisConfig struct {
 	KeyPrefix string `koanf:"key_prefix"`
 }
 
+// APIConfig contains admin HTTP API settings.
+type APIConfig struct {
+	// Listen is the address to serve the API on, e.g. "127.0.0.1:8080".
+	// Empty disables the API.
+	Listen string `koanf:"listen"`
+	// Token is the bearer token required by all endpoints except health checks.
+	Token string `koanf:"token"`
+}
+
+// Job sources for SourceConfig.Source.
+const (
+	// SourceFile reads jobs from the configuration file.
+	SourceFile = "file"
+	// SourceRedis reads jobs from the document last pushed to Redis with
+	// "cronlock config push", applying new versions as they are pushed.
+	SourceRedis = "redis"
+)
+
+// SourceConfig selects where the node's jobs come from. Everything else is
+// always read from the local file.
+type SourceConfig struct {
+	Source string `koanf:"source"`
+}
+
+// EventsConfig controls the cluster event stream in Redis.
+type EventsConfig struct {
+	// Enabled publishes the node's lifecycle events to the stream.
+	Enabled bool `koanf:"enabled"`
+	// MaxLen caps the stream at about this many events, oldest dropped first.
+	MaxLen int64 `koanf:"max_len"`
+}
+
+// Log formats for LogConfig.Format.
+const (
+	LogFormatText   = "text"
+	LogFormatLogfmt = "logfmt" // the same as text, which is logfmt
+	LogFormatJSON   = "json"
+)
+
+// LogConfig controls cronlock's own log output.
+type LogConfig struct {
+	// Format is "text" (key=value pairs, also accepted as "logfmt") or "json".
+	Format string `koanf:"format"`
+	// Level is the minimum level logged: debug, info, warn or error.
+	Level string `koanf:"level"`
+	// Output is "stderr", "stdout" or a file path. A file is reopened on
+	// SIGUSR2 so it can be rotated.
+	Output string `koanf:"output"`
+	// IncludeSource adds the source file and line to each record.
+	IncludeSource bool `koanf:"include_source"`
+}
+
+// OutputConfig controls what happens to job output.
+type OutputConfig struct {
+	// MaxCapture is how many bytes of each of stdout and stderr are kept
+	// in memory per run, split between the start and end of the output.
+	MaxCapture int `koanf:"max_capture"`
+	// Dir is where each run's output is written, as <dir>/<job>/<run-id>.log.
+	// Empty disables log files.
+	Dir string `koanf:"dir"`
+	// Keep is how many log files to keep per job (0 = no limit).
+	Keep int `koanf:"keep"`
+	// MaxAge removes log files older than this (0 = no limit).
+	MaxAge time.Duration `koanf:"max_age"`
+	// LogLines logs each line of output as it is written.
+	LogLines bool `koanf:"log_lines"`
+}
+
 // JobConfig defines a scheduled job.
 type JobConfig struct {
-	Name       string            `koanf:"name"`
-	Schedule   string            `koanf:"schedule"`
-	Command    string            `koanf:"command"`
-	Timeout    time.Duration     `koanf:"timeout"`
-	LockTTL    time.Duration     `koanf:"lock_ttl"`
-	WorkDir    string            `koanf:"work_dir"`
-	Env        map[string]string `koanf:"env"`
-	OnFailure  string            `koanf:"on_failure"`
-	OnSuccess  string            `koanf:"on_success"`
-	Enabled    *bool             `koanf:"enabled"`
+	Name      string            `koanf:"name"`
+	Schedule  string            `koanf:"schedule"`
+	Command   Command           `koanf:"command"`
+	Timeout   time.Duration     `koanf:"timeout"`
+	LockTTL   time.Duration     `koanf:"lock_ttl"`
+	WorkDir   string            `koanf:"work_dir"`
+	Env       map[string]string `koanf:"env"`
+	OnFailure string            `koanf:"on_failure"`
+	OnSuccess string            `koanf:"on_success"`
+	Enabled   *bool             `koanf:"enabled"`
+	// WarnAfter flags a run still going after this long as slow, without
+	// stopping it (0 = never).
+	WarnAfter time.Duration `koanf:"warn_after"`
+	// OnStart runs before the command, OnTimeout when the command is killed
+	// by its timeout, and OnComplete after every run, whatever its outcome.
+	OnStart    string `koanf:"on_start"`
+	OnTimeout  string `koanf:"on_timeout"`
+	OnComplete string `koanf:"on_complete"`
+	// OnSlow runs while the command is still going, once the run is slow.
+	OnSlow string `koanf:"on_slow"`
+	// OnOverdue runs on the monitoring node when the job has not succeeded
+	// within ExpectSuccessWithin.
+	OnOverdue string `koanf:"on_overdue"`
+	// HookTimeout kills a hook running longer than this (default 5m).
+	HookTimeout time.Duration `koanf:"hook_timeout"`
+	// HooksAfterRelease runs the hooks after a finished run only once its
+	// lock is released, so slow hooks don't hold the lock.
+	HooksAfterRelease bool `koanf:"hooks_after_release"`
+	// Shell overrides node.shell for this job's string command and hooks.
+	Shell string `koanf:"shell"`
+	// LogLevel overrides log.level for this job's log lines.
+	LogLevel string `koanf:"log_level"`
+
+	// User and Group run the command and hooks as another user and group,
+	// by name or numeric ID. SupplementaryGroups replaces the user's groups.
+	User                string   `koanf:"user"`
+	Group               string   `koanf:"group"`
+	SupplementaryGroups []string `koanf:"supplementary_groups"`
+
+	// Limits caps the resources the command can use.
+	Limits LimitsConfig `koanf:"limits"`
+
+	// Notify sends the job's events to notifiers defined in notifiers.
+	Notify []NotifyConfig `koanf:"notify"`
+	// AlertAfterFailures sends failure notifications only once this many
+	// consecutive runs have failed cluster-wide, then a recovered event on
+	// the next success (0 = notify every failure).
+	AlertAfterFailures int `koanf:"alert_after_failures"`
+	// RenotifyInterval repeats the failure notification this often while
+	// the job keeps failing (0 = notify once).
+	RenotifyInterval time.Duration `koanf:"renotify_interval"`
+	// ExpectSuccessWithin makes the cluster monitor alert when the job has
+	// not succeeded for this long, whatever the reason (0 = not monitored).
+	ExpectSuccessWithin time.Duration `koanf:"expect_success_within"`
+
+	// Shards splits each run into this many independently locked shards.
+	// Zero or one means the job is not sharded.
+	Shards int `koanf:"shards"`
+	// MaxShardsPerNode caps how many shards one node takes per run (0 = no cap).
+	MaxShardsPerNode int `koanf:"max_shards_per_node"`
+
+	// Constraints are label expressions a node must satisfy to register the job.
+	Constraints []string `koanf:"constraints"`
+	// Prefer are label expressions for preferred nodes. Nodes that don't
+	// match wait PreferDelay before competing for the lock.
+	Prefer      []string      `koanf:"prefer"`
+	PreferDelay time.Duration `koanf:"prefer_delay"`
+	// Placement selects how nodes compete for the lock: "" (first to fire
+	// wins) or "least_loaded" (busier nodes wait before trying).
+	Placement string `koanf:"placement"`
+	// DriftPolicy overrides node.drift_policy for this job.
+	DriftPolicy string `koanf:"drift_policy"`
+
+	// rawHash is the hash of the job as written, before ${VAR} expansion,
+	// set when the job is loaded.
+	rawHash string
+}
+
+// DefaultHookTimeout is the hook timeout if hook_timeout is not set.
+const DefaultHookTimeout = 5 * time.Minute
+
+// MinExpectSuccessWithin is the shortest expect_success_within, as the
+// cluster monitor checks jobs once a minute.
+const MinExpectSuccessWithin = time.Minute
+
+// ParseLogLevel parses a log level: debug, info, warn or error, in any case.
+func ParseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q: use debug, info, warn or error", s)
+	}
+	return level, nil
 }
 
 // IsEnabled returns whether the job is enabled. Defaults to true if not specified.
@@ -45,17 +248,54 @@ func (j JobConfig) IsEnabled() bool {
 	return *j.Enabled
 }
 
+// validDriftPolicy reports whether policy is a known drift policy.
+func validDriftPolicy(policy string) bool {
+	switch policy {
+	case DriftWarn, DriftRefuseToRun, DriftNewestWins:
+		return true
+	}
+	return false
+}
+
+// IsSharded returns whether each run is split across multiple shards.
+func (j JobConfig) IsSharded() bool {
+	return j.Shards > 1
+}
+
 // Defaults returns a Config with sensible default values.
 func Defaults() Config {
 	return Config{
 		Node: NodeConfig{
-			ID:          "",
-			GracePeriod: 5 * time.Second,
+			ID:           "",
+			GracePeriod:  5 * time.Second,
+			Coordination: CoordinationLock,
+			DriftPolicy:  DriftWarn,
+			LeaderTTL:    15 * time.Second,
+			CgroupParent: DefaultCgroupParent,
 		},
 		Redis: RedisConfig{
 			Address:   "localhost:6379",
 			KeyPrefix: "cronlock:",
 		},
+		Output: OutputConfig{
+			MaxCapture: 64 * 1024,
+		},
+		Log: LogConfig{
+			Format: LogFormatText,
+			Level:  "info",
+			Output: "stderr",
+		},
+		Config: SourceConfig{
+			Source: SourceFile,
+		},
+		Events: EventsConfig{
+			MaxLen: 10000,
+		},
+		OTel: OTelConfig{
+			Protocol:    OTelProtocolGRPC,
+			ServiceName: "cronlock",
+			SampleRatio: 1,
+		},
 		Jobs: []JobConfig{},
 	}
 }
@@ -2,8 +2,11 @@ package config
 
 import (
 	"fmt"
+	"math"
 	"os"
+	"os/user"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -18,6 +21,12 @@ func TestDefaults(t *testing.T) {
 	if cfg.Node.GracePeriod != 5*time.Second {
 		t.Errorf("expected GracePeriod 5s, got %v", cfg.Node.GracePeriod)
 	}
+	if cfg.Node.Coordination != CoordinationLock {
+		t.Errorf("expected Coordination %q, got %q", CoordinationLock, cfg.Node.Coordination)
+	}
+	if cfg.Node.LeaderTTL != 15*time.Second {
+		t.Errorf("expected LeaderTTL 15s, got %v", cfg.Node.LeaderTTL)
+	}
 	if cfg.Redis.Address != "localhost:6379" {
 		t.Errorf("expected Redis.Address localhost:6379, got %q", cfg.Redis.Address)
 	}
@@ -30,6 +39,12 @@ func TestDefaults(t *testing.T) {
 	if cfg.Redis.Password != "" {
 		t.Errorf("expected empty Redis.Password, got %q", cfg.Redis.Password)
 	}
+	if cfg.Events.Enabled {
+		t.Error("expected Events.Enabled false")
+	}
+	if cfg.Events.MaxLen != 10000 {
+		t.Errorf("expected Events.MaxLen 10000, got %d", cfg.Events.MaxLen)
+	}
 	if len(cfg.Jobs) != 0 {
 		t.Errorf("expected empty Jobs slice, got %d jobs", len(cfg.Jobs))
 	}
@@ -134,8 +149,8 @@ jobs:
 	if job.Schedule != "* * * * *" {
 		t.Errorf("Job.Schedule = %q, want %q", job.Schedule, "* * * * *")
 	}
-	if job.Command != "echo hello" {
-		t.Errorf("Job.Command = %q, want %q", job.Command, "echo hello")
+	if job.Command.Line != "echo hello" {
+		t.Errorf("Job.Command.Line = %q, want %q", job.Command.Line, "echo hello")
 	}
 	if job.Timeout != 30*time.Second {
 		t.Errorf("Job.Timeout = %v, want %v", job.Timeout, 30*time.Second)
@@ -236,8 +251,8 @@ lock_ttl = "90s"
 	if job.Schedule != "*/5 * * * *" {
 		t.Errorf("Job.Schedule = %q, want %q", job.Schedule, "*/5 * * * *")
 	}
-	if job.Command != "echo toml" {
-		t.Errorf("Job.Command = %q, want %q", job.Command, "echo toml")
+	if job.Command.Line != "echo toml" {
+		t.Errorf("Job.Command.Line = %q, want %q", job.Command.Line, "echo toml")
 	}
 	if job.Timeout != 45*time.Second {
 		t.Errorf("Job.Timeout = %v, want %v", job.Timeout, 45*time.Second)
@@ -305,8 +320,8 @@ jobs:
 	if cfg.Redis.KeyPrefix != "cronlock:" {
 		t.Errorf("Redis.KeyPrefix = %q, want %q (default)", cfg.Redis.KeyPrefix, "cronlock:")
 	}
-	if cfg.Jobs[0].Command != "echo hello" {
-		t.Errorf("Job.Command = %q, want %q", cfg.Jobs[0].Command, "echo hello")
+	if cfg.Jobs[0].Command.Line != "echo hello" {
+		t.Errorf("Job.Command.Line = %q, want %q", cfg.Jobs[0].Command.Line, "echo hello")
 	}
 }
 
@@ -643,6 +658,33 @@ jobs:
 `,
 			errMsg: "node.grace_period must be non-negative",
 		},
+		{
+			name: "negative shards",
+			content: `
+redis:
+  address: localhost:6379
+jobs:
+  - name: test-job
+    schedule: "* * * * *"
+    command: echo test
+    shards: -2
+`,
+			errMsg: "jobs[0].shards must be non-negative",
+		},
+		{
+			name: "negative max_shards_per_node",
+			content: `
+redis:
+  address: localhost:6379
+jobs:
+  - name: test-job
+    schedule: "* * * * *"
+    command: echo test
+    shards: 4
+    max_shards_per_node: -1
+`,
+			errMsg: "jobs[0].max_shards_per_node must be non-negative",
+		},
 	}
 
 	for _, tt := range tests {
@@ -857,6 +899,205 @@ jobs:
 	}
 }
 
+func TestLoad_NodeLabelsAndPlacement(t *testing.T) {
+	content := `
+node:
+  labels:
+    zone: a
+    role: batch
+redis:
+  address: localhost:6379
+jobs:
+  - name: backup
+    schedule: "0 2 * * *"
+    command: backup.sh
+    constraints: ["role=batch", "!maintenance"]
+    prefer: ["zone=a"]
+    prefer_delay: 5s
+`
+	tmpFile := writeTempFile(t, "config-labels.yaml", content)
+	defer os.Remove(tmpFile)
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Node.Labels["zone"] != "a" || cfg.Node.Labels["role"] != "batch" {
+		t.Errorf("Node.Labels = %v, want zone=a role=batch", cfg.Node.Labels)
+	}
+	job := cfg.Jobs[0]
+	if len(job.Constraints) != 2 || len(job.Prefer) != 1 {
+		t.Errorf("Constraints = %v, Prefer = %v", job.Constraints, job.Prefer)
+	}
+	if job.PreferDelay != 5*time.Second {
+		t.Errorf("PreferDelay = %v, want 5s", job.PreferDelay)
+	}
+	if !job.CanRunOn(cfg.Node.Labels) {
+		t.Error("CanRunOn() = false, want true")
+	}
+}
+
+func TestLoad_Validation_InvalidLabelExpr(t *testing.T) {
+	content := `
+redis:
+  address: localhost:6379
+jobs:
+  - name: backup
+    schedule: "0 2 * * *"
+    command: backup.sh
+    constraints: ["=batch"]
+`
+	tmpFile := writeTempFile(t, "config-bad-constraint.yaml", content)
+	defer os.Remove(tmpFile)
+
+	_, err := Load(tmpFile)
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+	if !strings.Contains(err.Error(), "jobs[0].constraints") {
+		t.Errorf("error = %q, want to contain %q", err.Error(), "jobs[0].constraints")
+	}
+}
+
+func TestLoad_Validation_Coordination(t *testing.T) {
+	tests := []struct {
+		name   string
+		node   string
+		errMsg string
+	}{
+		{
+			name:   "unknown mode",
+			node:   "coordination: raft",
+			errMsg: "node.coordination must be",
+		},
+		{
+			name:   "leader_ttl too small",
+			node:   "coordination: leader\n  leader_ttl: 500ms",
+			errMsg: "node.leader_ttl must be at least 1s",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "node:\n  " + tt.node + "\nredis:\n  address: localhost:6379\n"
+			tmpFile := writeTempFile(t, "config-coordination.yaml", content)
+			defer os.Remove(tmpFile)
+
+			_, err := Load(tmpFile)
+			if err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestLoad_Validation_Placement(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		errMsg  string
+	}{
+		{
+			name: "unknown placement",
+			content: `
+redis:
+  address: localhost:6379
+jobs:
+  - name: test-job
+    schedule: "* * * * *"
+    command: echo test
+    placement: random
+`,
+			errMsg: "jobs[0].placement must be",
+		},
+		{
+			name: "negative max_concurrent_jobs",
+			content: `
+node:
+  max_concurrent_jobs: -1
+redis:
+  address: localhost:6379
+`,
+			errMsg: "node.max_concurrent_jobs must be non-negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpFile := writeTempFile(t, "config-placement.yaml", tt.content)
+			defer os.Remove(tmpFile)
+
+			_, err := Load(tmpFile)
+			if err == nil {
+				t.Fatal("expected validation error, got nil")
+			}
+			if !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("error = %q, want to contain %q", err.Error(), tt.errMsg)
+			}
+		})
+	}
+}
+
+func TestParseLabelExpr(t *testing.T) {
+	labels := map[string]string{"zone": "a", "role": "batch"}
+
+	tests := []struct {
+		expr    string
+		matches bool
+	}{
+		{"zone=a", true},
+		{"zone=b", false},
+		{"zone!=b", true},
+		{"zone!=a", false},
+		{"gpu!=yes", true},
+		{"role", true},
+		{"gpu", false},
+		{"!gpu", true},
+		{"!role", false},
+		{" zone = a ", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := ParseLabelExpr(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseLabelExpr() error = %v", err)
+			}
+			if got := expr.Matches(labels); got != tt.matches {
+				t.Errorf("Matches() = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"", "=a", "!", "zone a=b"} {
+		if _, err := ParseLabelExpr(invalid); err == nil {
+			t.Errorf("ParseLabelExpr(%q) error = nil, want error", invalid)
+		}
+	}
+}
+
+func TestUnplaceableJobs(t *testing.T) {
+	jobs := []JobConfig{
+		{Name: "anywhere"},
+		{Name: "batch-only", Constraints: []string{"role=batch"}},
+		{Name: "gpu-only", Constraints: []string{"gpu"}},
+		{Name: "disabled-gpu", Constraints: []string{"gpu"}, Enabled: boolPtr(false)},
+	}
+	nodes := []map[string]string{
+		{"role": "web"},
+		{"role": "batch"},
+	}
+
+	got := UnplaceableJobs(jobs, nodes)
+	if len(got) != 1 || got[0] != "gpu-only" {
+		t.Errorf("UnplaceableJobs() = %v, want [gpu-only]", got)
+	}
+}
+
 func writeTempFile(t *testing.T, name, content string) string {
 	t.Helper()
 	tmpDir := t.TempDir()
@@ -866,3 +1107,1277 @@ func writeTempFile(t *testing.T, name, content string) string {
 	}
 	return path
 }
+
+func TestLoad_API(t *testing.T) {
+	os.Setenv("TEST_API_TOKEN", "s3cret")
+	defer os.Unsetenv("TEST_API_TOKEN")
+
+	content := `
+redis:
+  address: localhost:6379
+api:
+  listen: 127.0.0.1:8080
+  token: ${TEST_API_TOKEN}
+`
+	tmpFile := writeTempFile(t, "config-api.yaml", content)
+	defer os.Remove(tmpFile)
+
+	cfg, err := Load(tmpFile)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.API.Listen != "127.0.0.1:8080" {
+		t.Errorf("API.Listen = %q, want %q", cfg.API.Listen, "127.0.0.1:8080")
+	}
+	if cfg.API.Token != "s3cret" {
+		t.Errorf("API.Token = %q, want expanded from env", cfg.API.Token)
+	}
+}
+
+func TestLoad_Validation_APIListen(t *testing.T) {
+	content := "redis:\n  address: localhost:6379\napi:\n  listen: localhost\n"
+	tmpFile := writeTempFile(t, "config-api.yaml", content)
+	defer os.Remove(tmpFile)
+
+	_, err := Load(tmpFile)
+	if err == nil {
+		t.Fatal("expected validation error, got nil")
+	}
+	if !strings.Contains(err.Error(), "api.listen") {
+		t.Errorf("error = %q, want to contain %q", err.Error(), "api.listen")
+	}
+}
+
+func TestFireTimes(t *testing.T) {
+	from := time.Date(2026, 1, 1, 0, 0, 30, 0, time.UTC)
+
+	tests := []struct {
+		schedule string
+		n        int
+		want     []time.Time
+	}{
+		{
+			schedule: "*/15 * * * *",
+			n:        3,
+			want: []time.Time{
+				time.Date(2026, 1, 1, 0, 15, 0, 0, time.UTC),
+				time.Date(2026, 1, 1, 0, 30, 0, 0, time.UTC),
+				time.Date(2026, 1, 1, 0, 45, 0, 0, time.UTC),
+			},
+		},
+		{
+			schedule: "0 0 29 2 *",
+			n:        2,
+			want: []time.Time{
+				time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+				time.Date(2032, 2, 29, 0, 0, 0, 0, time.UTC),
+			},
+		},
+		{
+			schedule: "0 0 31 2 *", // never fires
+			n:        3,
+			want:     nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			schedule, err := ParseSchedule(tt.schedule)
+			if err != nil {
+				t.Fatalf("ParseSchedule() error = %v", err)
+			}
+			got := FireTimes(schedule, from, tt.n)
+			if len(got) != len(tt.want) {
+				t.Fatalf("FireTimes() = %v, want %v", got, tt.want)
+			}
+			for i := range tt.want {
+				if !got[i].Equal(tt.want[i]) {
+					t.Errorf("FireTimes()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFireTimes_DST(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// Clocks go forward from 02:00 to 03:00 on 2026-03-29, so that day has no 02:30
+	schedule, _ := ParseSchedule("30 2 * * *")
+	from := time.Date(2026, 3, 27, 12, 0, 0, 0, berlin)
+
+	got := FireTimes(schedule, from, 3)
+	want := []string{"2026-03-28 02:30 CET", "2026-03-30 02:30 CEST", "2026-03-31 02:30 CEST"}
+	if len(got) != len(want) {
+		t.Fatalf("FireTimes() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if s := got[i].Format("2006-01-02 15:04 MST"); s != want[i] {
+			t.Errorf("FireTimes()[%d] = %s, want %s", i, s, want[i])
+		}
+	}
+}
+
+func TestJobConfig_Overlaps(t *testing.T) {
+	from := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC) // a Monday
+
+	tests := []struct {
+		name         string
+		schedule     string
+		timeout      time.Duration
+		wantInterval time.Duration
+		wantOverlaps bool
+	}{
+		{"fits", "*/10 * * * *", 5 * time.Minute, 10 * time.Minute, false},
+		{"exceeds interval", "*/10 * * * *", 15 * time.Minute, 10 * time.Minute, true},
+		{"no timeout", "*/10 * * * *", 0, 10 * time.Minute, false},
+		// Hourly during business hours: the overnight gap doesn't hide the hourly interval
+		{"business hours", "0 9-17 * * 1-5", 90 * time.Minute, time.Hour, true},
+		{"never fires", "0 0 31 2 *", time.Hour, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := JobConfig{Name: "job", Schedule: tt.schedule, Timeout: tt.timeout}
+			interval, overlaps, err := job.Overlaps(from, time.UTC)
+			if err != nil {
+				t.Fatalf("Overlaps() error = %v", err)
+			}
+			if interval != tt.wantInterval || overlaps != tt.wantOverlaps {
+				t.Errorf("Overlaps() = %v, %v, want %v, %v", interval, overlaps, tt.wantInterval, tt.wantOverlaps)
+			}
+		})
+	}
+}
+
+func TestLoad_CommandArgv(t *testing.T) {
+	os.Setenv("TEST_ARGV_TARGET", "/var/backups")
+	defer os.Unsetenv("TEST_ARGV_TARGET")
+
+	yamlContent := `
+redis:
+  address: localhost:6379
+node:
+  shell: /bin/sh -e
+jobs:
+  - name: argv
+    schedule: "@daily"
+    command: ["echo", "two words", "${TEST_ARGV_TARGET}", 3]
+  - name: line
+    schedule: "@daily"
+    command: "echo one, two"
+    shell: /bin/sh
+`
+	tomlContent := `
+[redis]
+address = "localhost:6379"
+
+[node]
+shell = "/bin/sh -e"
+
+[[jobs]]
+name = "argv"
+schedule = "@daily"
+command = ["echo", "two words", "${TEST_ARGV_TARGET}", 3]
+
+[[jobs]]
+name = "line"
+schedule = "@daily"
+command = "echo one, two"
+shell = "/bin/sh"
+`
+
+	for name, content := range map[string]string{"config.yaml": yamlContent, "config.toml": tomlContent} {
+		t.Run(name, func(t *testing.T) {
+			cfg, err := Load(writeTempFile(t, name, content))
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+
+			if cfg.Node.Shell != "/bin/sh -e" {
+				t.Errorf("Node.Shell = %q, want %q", cfg.Node.Shell, "/bin/sh -e")
+			}
+
+			argv := cfg.Jobs[0].Command
+			want := []string{"echo", "two words", "/var/backups", "3"}
+			if argv.Line != "" || fmt.Sprint(argv.Argv) != fmt.Sprint(want) {
+				t.Errorf("Jobs[0].Command = %#v, want Argv %q", argv, want)
+			}
+
+			line := cfg.Jobs[1].Command
+			if line.Line != "echo one, two" || line.Argv != nil {
+				t.Errorf("Jobs[1].Command = %#v, want Line %q", line, "echo one, two")
+			}
+			if cfg.Jobs[1].Shell != "/bin/sh" {
+				t.Errorf("Jobs[1].Shell = %q, want %q", cfg.Jobs[1].Shell, "/bin/sh")
+			}
+		})
+	}
+}
+
+func TestLoad_Validation_Command(t *testing.T) {
+	workDir := t.TempDir()
+	script := filepath.Join(workDir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	notExecutable := filepath.Join(workDir, "data.txt")
+	if err := os.WriteFile(notExecutable, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		job     string
+		node    string
+		wantErr string
+	}{
+		{
+			name: "binary on PATH",
+			job:  `command: ["sh", "-c", "true"]`,
+		},
+		{
+			name: "absolute path",
+			job:  fmt.Sprintf("command: [%q]", script),
+		},
+		{
+			name: "path relative to work_dir",
+			job:  fmt.Sprintf("command: [./run.sh]\n    work_dir: %s", workDir),
+		},
+		{
+			name: "string command is not checked",
+			job:  `command: "cronlock-test-no-such-binary"`,
+		},
+		{
+			name:    "binary not on PATH",
+			job:     `command: [cronlock-test-no-such-binary]`,
+			wantErr: `jobs[0].command: exec: "cronlock-test-no-such-binary": executable file not found in $PATH`,
+		},
+		{
+			name:    "missing absolute path",
+			job:     `command: [/nonexistent/cronlock-test]`,
+			wantErr: "jobs[0].command",
+		},
+		{
+			name:    "not executable",
+			job:     fmt.Sprintf("command: [%q]", notExecutable),
+			wantErr: "jobs[0].command",
+		},
+		{
+			name:    "empty list",
+			job:     `command: []`,
+			wantErr: "jobs[0].command is required",
+		},
+		{
+			name:    "nested list",
+			job:     `command: [echo, [a, b]]`,
+			wantErr: "command argument 1 must be a string",
+		},
+		{
+			name:    "job shell not found",
+			job:     "command: \"true\"\n    shell: /nonexistent/sh",
+			wantErr: `jobs[0].shell "/nonexistent/sh" is invalid`,
+		},
+		{
+			name:    "node shell not found",
+			job:     `command: "true"`,
+			node:    "cronlock-test-no-such-shell -e",
+			wantErr: `node.shell "cronlock-test-no-such-shell -e" is invalid`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n"
+			if tt.node != "" {
+				content += fmt.Sprintf("node:\n  shell: %s\n", tt.node)
+			}
+			content += fmt.Sprintf("jobs:\n  - name: test\n    schedule: \"@daily\"\n    %s\n", tt.job)
+
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Load() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCommand_String(t *testing.T) {
+	tests := []struct {
+		cmd  Command
+		want string
+	}{
+		{ShellCommand("echo $HOME | wc -c"), "echo $HOME | wc -c"},
+		{Command{Argv: []string{"/usr/bin/backup", "--full"}}, "/usr/bin/backup --full"},
+		{Command{Argv: []string{"echo", "two words", "", "$HOME"}}, `echo "two words" "" "$HOME"`},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cmd.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestLoad_Output(t *testing.T) {
+	os.Setenv("TEST_OUTPUT_DIR", "/var/log/cronlock")
+	defer os.Unsetenv("TEST_OUTPUT_DIR")
+
+	content := `
+redis:
+  address: localhost:6379
+output:
+  max_capture: 4096
+  dir: ${TEST_OUTPUT_DIR}/runs
+  keep: 50
+  max_age: 168h
+  log_lines: true
+`
+	cfg, err := Load(writeTempFile(t, "config-output.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := OutputConfig{
+		MaxCapture: 4096,
+		Dir:        "/var/log/cronlock/runs",
+		Keep:       50,
+		MaxAge:     168 * time.Hour,
+		LogLines:   true,
+	}
+	if cfg.Output != want {
+		t.Errorf("Output = %+v, want %+v", cfg.Output, want)
+	}
+
+	// Without an output block, output is captured with the default limit only
+	cfg, err = Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Output != Defaults().Output || cfg.Output.MaxCapture != 64*1024 {
+		t.Errorf("Output = %+v, want defaults with 64KiB max_capture", cfg.Output)
+	}
+}
+
+func TestLoad_Validation_Output(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		wantErr string
+	}{
+		{"max_capture too small", "max_capture: 100", "output.max_capture must be at least 1024 bytes"},
+		{"negative keep", "keep: -1", "output.keep must be non-negative"},
+		{"negative max_age", "max_age: -1h", "output.max_age must be non-negative"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := fmt.Sprintf("redis:\n  address: localhost:6379\noutput:\n  %s\n", tt.output)
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Log(t *testing.T) {
+	content := `
+redis:
+  address: localhost:6379
+log:
+  format: json
+  level: DEBUG
+  output: /var/log/cronlock.log
+  include_source: true
+jobs:
+  - name: noisy
+    schedule: "@daily"
+    command: "true"
+    log_level: debug
+`
+	cfg, err := Load(writeTempFile(t, "config-log.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	want := LogConfig{Format: "json", Level: "DEBUG", Output: "/var/log/cronlock.log", IncludeSource: true}
+	if cfg.Log != want {
+		t.Errorf("Log = %+v, want %+v", cfg.Log, want)
+	}
+	if cfg.Jobs[0].LogLevel != "debug" {
+		t.Errorf("Jobs[0].LogLevel = %q, want %q", cfg.Jobs[0].LogLevel, "debug")
+	}
+
+	// Defaults match the previous hard-coded logger
+	cfg, err = Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want = LogConfig{Format: "text", Level: "info", Output: "stderr"}
+	if cfg.Log != want {
+		t.Errorf("Log = %+v, want %+v", cfg.Log, want)
+	}
+}
+
+func TestLoad_Validation_Log(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bad format", "log:\n  format: xml\n", `log.format must be "text", "logfmt" or "json", got "xml"`},
+		{"bad level", "log:\n  level: verbose\n", `log.level: invalid log level "verbose"`},
+		{"empty output", "log:\n  output: \"\"\n", "log.output is required"},
+		{
+			"bad job level",
+			"jobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    log_level: trace\n",
+			`jobs[0].log_level: invalid log level "trace"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJobConfig_RunAs(t *testing.T) {
+	nobody, err := user.Lookup("nobody")
+	if err != nil {
+		t.Skip("user nobody not available")
+	}
+	nobodyUID, _ := strconv.ParseUint(nobody.Uid, 10, 32)
+	nobodyGID, _ := strconv.ParseUint(nobody.Gid, 10, 32)
+	root, err := user.LookupId("0")
+	if err != nil {
+		t.Skip("root user not available")
+	}
+
+	tests := []struct {
+		name    string
+		job     JobConfig
+		want    *RunAs
+		wantErr string
+	}{
+		{
+			name: "no user or group",
+			job:  JobConfig{},
+			want: nil,
+		},
+		{
+			name: "user by name",
+			job:  JobConfig{User: "nobody", SupplementaryGroups: []string{nobody.Gid}},
+			want: &RunAs{UID: uint32(nobodyUID), GID: uint32(nobodyGID), Groups: []uint32{uint32(nobodyGID)}, User: "nobody", Home: nobody.HomeDir},
+		},
+		{
+			name: "user by ID with group",
+			job:  JobConfig{User: nobody.Uid, Group: "0", SupplementaryGroups: []string{"0", "0"}},
+			want: &RunAs{UID: uint32(nobodyUID), GID: 0, Groups: []uint32{0}, User: "nobody", Home: nobody.HomeDir},
+		},
+		{
+			name: "root",
+			job:  JobConfig{User: "0", SupplementaryGroups: []string{"0"}},
+			want: &RunAs{UID: 0, GID: 0, Groups: []uint32{0}, User: root.Username, Home: root.HomeDir},
+		},
+		{
+			name:    "unknown user",
+			job:     JobConfig{User: "cronlock-test-no-such-user"},
+			wantErr: `user "cronlock-test-no-such-user" not found`,
+		},
+		{
+			name:    "unknown group",
+			job:     JobConfig{User: "nobody", Group: "cronlock-test-no-such-group"},
+			wantErr: `group "cronlock-test-no-such-group" not found`,
+		},
+		{
+			name:    "unknown supplementary group",
+			job:     JobConfig{User: "nobody", SupplementaryGroups: []string{"cronlock-test-no-such-group"}},
+			wantErr: `group "cronlock-test-no-such-group" not found`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.job.RunAs()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("RunAs() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunAs() error = %v", err)
+			}
+			if fmt.Sprintf("%+v", got) != fmt.Sprintf("%+v", tt.want) {
+				t.Errorf("RunAs() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad_Validation_RunAs(t *testing.T) {
+	content := `
+redis:
+  address: localhost:6379
+jobs:
+  - name: test
+    schedule: "@daily"
+    command: "true"
+    user: cronlock-test-no-such-user
+`
+	_, err := Load(writeTempFile(t, "config.yaml", content))
+	want := `jobs[0]: user "cronlock-test-no-such-user" not found`
+	if err == nil || err.Error() != want {
+		t.Errorf("Load() error = %v, want %q", err, want)
+	}
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    ByteSize
+		wantErr bool
+	}{
+		{"4096", 4096, false},
+		{"0", 0, false},
+		{"512K", 512 << 10, false},
+		{"512M", 512 << 20, false},
+		{"2g", 2 << 30, false},
+		{"2GiB", 2 << 30, false},
+		{"1TB", 1 << 40, false},
+		{"64B", 64, false},
+		{"", 0, true},
+		{"M", 0, true},
+		{"1.5G", 0, true},
+		{"-1M", 0, true},
+		{"10X", 0, true},
+		{"1KM", 0, true},
+		{"99999999T", 0, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseByteSize(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseByteSize(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseByteSize(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_Limits(t *testing.T) {
+	content := `
+redis:
+  address: localhost:6379
+node:
+  cgroup_parent: /sys/fs/cgroup/jobs.slice
+jobs:
+  - name: report
+    schedule: "@daily"
+    command: "true"
+    limits:
+      nofile: 1024
+      nproc: 64
+      core: 0
+      as: 4G
+      memory_max: 512M
+      cpu_weight: 50
+      cpu_max: 1.5
+      pids_max: 100
+  - name: plain
+    schedule: "@daily"
+    command: "true"
+`
+	cfg, err := Load(writeTempFile(t, "config-limits.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if cfg.Node.CgroupParent != "/sys/fs/cgroup/jobs.slice" {
+		t.Errorf("Node.CgroupParent = %q, want %q", cfg.Node.CgroupParent, "/sys/fs/cgroup/jobs.slice")
+	}
+
+	l := cfg.Jobs[0].Limits
+	if l.NoFile == nil || *l.NoFile != 1024 {
+		t.Errorf("NoFile = %v, want 1024", l.NoFile)
+	}
+	if l.NProc == nil || *l.NProc != 64 {
+		t.Errorf("NProc = %v, want 64", l.NProc)
+	}
+	if l.Core == nil || *l.Core != 0 {
+		t.Errorf("Core = %v, want 0", l.Core)
+	}
+	if l.AS == nil || *l.AS != 4<<30 {
+		t.Errorf("AS = %v, want 4GiB", l.AS)
+	}
+	if l.MemoryMax != 512<<20 || l.CPUWeight != 50 || l.CPUMax != 1.5 || l.PidsMax != 100 {
+		t.Errorf("cgroup limits = %+v, want memory_max 512M, cpu_weight 50, cpu_max 1.5, pids_max 100", l)
+	}
+	if !l.HasRlimits() || !l.HasCgroup() {
+		t.Errorf("HasRlimits() = %v, HasCgroup() = %v, want both true", l.HasRlimits(), l.HasCgroup())
+	}
+
+	plain := cfg.Jobs[1].Limits
+	if plain.HasRlimits() || plain.HasCgroup() {
+		t.Errorf("job without limits: HasRlimits() = %v, HasCgroup() = %v, want both false", plain.HasRlimits(), plain.HasCgroup())
+	}
+
+	// The cgroup parent has a default
+	cfg, err = Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Node.CgroupParent != DefaultCgroupParent {
+		t.Errorf("Node.CgroupParent = %q, want %q", cfg.Node.CgroupParent, DefaultCgroupParent)
+	}
+}
+
+func TestLoad_Validation_Limits(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"relative cgroup parent", "node:\n  cgroup_parent: cronlock\n", `node.cgroup_parent must be an absolute path, got "cronlock"`},
+		{"cpu_weight too low", "    limits:\n      cpu_weight: -1\n", "jobs[0].limits.cpu_weight must be between 1 and 10000, got -1"},
+		{"cpu_weight too high", "    limits:\n      cpu_weight: 10001\n", "jobs[0].limits.cpu_weight must be between 1 and 10000"},
+		{"cpu_max too small", "    limits:\n      cpu_max: 0.001\n", "jobs[0].limits.cpu_max must be at least 0.01 CPUs"},
+		{"cpu_max NaN", "    limits:\n      cpu_max: .nan\n", "jobs[0].limits.cpu_max must be a finite number of CPUs, got NaN"},
+		{"cpu_max infinite", "    limits:\n      cpu_max: .inf\n", "jobs[0].limits.cpu_max must be a finite number of CPUs, got +Inf"},
+		{"negative pids_max", "    limits:\n      pids_max: -1\n", "jobs[0].limits.pids_max must be non-negative"},
+		{"bad size", "    limits:\n      memory_max: 1.5G\n", `invalid size "1.5G"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n"
+			if strings.HasPrefix(tt.content, "node:") {
+				content += tt.content
+			} else {
+				content += "jobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n" + tt.content
+			}
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Hooks(t *testing.T) {
+	os.Setenv("TEST_HOOK_BIN", "/usr/local/bin")
+	defer os.Unsetenv("TEST_HOOK_BIN")
+
+	content := `
+redis:
+  address: localhost:6379
+jobs:
+  - name: report
+    schedule: "@daily"
+    command: "true"
+    on_start: "${TEST_HOOK_BIN}/notify start"
+    on_timeout: "${TEST_HOOK_BIN}/notify timeout"
+    on_complete: "${TEST_HOOK_BIN}/notify complete"
+    hook_timeout: 30s
+    hooks_after_release: true
+`
+	cfg, err := Load(writeTempFile(t, "config-hooks.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	job := cfg.Jobs[0]
+	if job.OnStart != "/usr/local/bin/notify start" {
+		t.Errorf("OnStart = %q, want %q", job.OnStart, "/usr/local/bin/notify start")
+	}
+	if job.OnTimeout != "/usr/local/bin/notify timeout" {
+		t.Errorf("OnTimeout = %q, want %q", job.OnTimeout, "/usr/local/bin/notify timeout")
+	}
+	if job.OnComplete != "/usr/local/bin/notify complete" {
+		t.Errorf("OnComplete = %q, want %q", job.OnComplete, "/usr/local/bin/notify complete")
+	}
+	if job.HookTimeout != 30*time.Second {
+		t.Errorf("HookTimeout = %v, want 30s", job.HookTimeout)
+	}
+	if !job.HooksAfterRelease {
+		t.Error("HooksAfterRelease = false, want true")
+	}
+}
+
+func TestLoad_Validation_HookTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		hookTimeout string
+		wantErr     string
+	}{
+		{"negative", "-1s", "jobs[0].hook_timeout must be non-negative"},
+		{"missing unit", "30", "jobs[0].hook_timeout 30ns is suspiciously small"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := fmt.Sprintf("redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    hook_timeout: %s\n", tt.hookTimeout)
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Notifiers(t *testing.T) {
+	os.Setenv("TEST_WEBHOOK_SECRET", "s3cret")
+	defer os.Unsetenv("TEST_WEBHOOK_SECRET")
+
+	content := `
+redis:
+  address: localhost:6379
+notifiers:
+  - name: ops-webhook
+    type: webhook
+    url: https://hooks.example.com/cronlock
+    headers:
+      Authorization: "Bearer ${TEST_WEBHOOK_SECRET}"
+    secret: ${TEST_WEBHOOK_SECRET}
+    retries: 0
+  - name: chat
+    type: slack
+    url: https://chat.example.com/hooks/abc
+    timeout: 5s
+  - name: mail
+    type: email
+    smtp:
+      host: smtp.example.com
+      from: cronlock@example.com
+      to: [ops@example.com]
+jobs:
+  - name: backup
+    schedule: "@daily"
+    command: "true"
+    notify:
+      - notifier: ops-webhook
+        events: [failure, lock_lost]
+      - notifier: mail
+`
+	cfg, err := Load(writeTempFile(t, "config-notifiers.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(cfg.Notifiers) != 3 {
+		t.Fatalf("len(Notifiers) = %d, want 3", len(cfg.Notifiers))
+	}
+	webhook := cfg.Notifiers[0]
+	if webhook.Secret != "s3cret" || webhook.Headers["Authorization"] != "Bearer s3cret" {
+		t.Errorf("Secret = %q, Headers = %v, want env expanded", webhook.Secret, webhook.Headers)
+	}
+	if webhook.RetryCount() != 0 {
+		t.Errorf("RetryCount() = %d, want 0", webhook.RetryCount())
+	}
+	if cfg.Notifiers[1].RetryCount() != DefaultNotifierRetries {
+		t.Errorf("RetryCount() = %d, want %d", cfg.Notifiers[1].RetryCount(), DefaultNotifierRetries)
+	}
+	if cfg.Notifiers[1].Timeout != 5*time.Second {
+		t.Errorf("Timeout = %v, want 5s", cfg.Notifiers[1].Timeout)
+	}
+	if cfg.Notifiers[2].SMTP.Host != "smtp.example.com" || len(cfg.Notifiers[2].SMTP.To) != 1 {
+		t.Errorf("SMTP = %+v, want the smtp settings", cfg.Notifiers[2].SMTP)
+	}
+
+	notify := cfg.Jobs[0].Notify
+	if len(notify) != 2 || notify[0].Notifier != "ops-webhook" || len(notify[0].Events) != 2 {
+		t.Errorf("Notify = %+v, want both subscriptions", notify)
+	}
+}
+
+func TestNotifyConfig_Matches(t *testing.T) {
+	tests := []struct {
+		events []string
+		event  string
+		want   bool
+	}{
+		{nil, EventFailure, true},
+		{nil, EventTimeout, true},
+		{nil, EventSuccess, false},
+		{nil, EventLockLost, false},
+		{nil, EventRecovered, true},
+		{nil, EventOverdue, true},
+		{[]string{EventSuccess}, EventSuccess, true},
+		{[]string{EventSuccess}, EventFailure, false},
+		{[]string{EventTimeout}, EventTimeout, true},
+		{[]string{EventTimeout}, EventFailure, false},
+		{[]string{EventLockLost}, EventLockLost, true},
+	}
+
+	for _, tt := range tests {
+		n := NotifyConfig{Notifier: "n", Events: tt.events}
+		if got := n.Matches(tt.event); got != tt.want {
+			t.Errorf("NotifyConfig{Events: %v}.Matches(%q) = %v, want %v", tt.events, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestLoad_Validation_Notifiers(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"missing name", "notifiers:\n  - type: webhook\n    url: https://example.com\n", "notifiers[0].name is required"},
+		{"duplicate name", "notifiers:\n  - name: a\n    type: webhook\n    url: https://example.com\n  - name: a\n    type: slack\n    url: https://example.com\n", `notifiers[1].name "a" is a duplicate`},
+		{"bad type", "notifiers:\n  - name: a\n    type: pager\n", `notifiers[0].type must be "webhook", "slack" or "email", got "pager"`},
+		{"missing url", "notifiers:\n  - name: a\n    type: webhook\n", "notifiers[0].url must be an http or https URL"},
+		{"bad url scheme", "notifiers:\n  - name: a\n    type: slack\n    url: ftp://example.com\n", "notifiers[0].url must be an http or https URL"},
+		{"missing smtp host", "notifiers:\n  - name: a\n    type: email\n", "notifiers[0].smtp.host is required"},
+		{"missing smtp to", "notifiers:\n  - name: a\n    type: email\n    smtp:\n      host: mail\n      from: a@example.com\n", "notifiers[0].smtp.to is required"},
+		{"negative retries", "notifiers:\n  - name: a\n    type: webhook\n    url: https://example.com\n    retries: -1\n", "notifiers[0].retries must be non-negative"},
+		{"unknown notifier", "jobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    notify:\n      - notifier: missing\n", `jobs[0].notify[0].notifier "missing" is not defined in notifiers`},
+		{"unknown event", "notifiers:\n  - name: a\n    type: webhook\n    url: https://example.com\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    notify:\n      - notifier: a\n        events: [crashed]\n", `jobs[0].notify[0].events: unknown event "crashed"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad_Validation_Alerting(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"negative threshold", "    alert_after_failures: -1\n", "jobs[0].alert_after_failures must be non-negative"},
+		{"negative renotify", "    alert_after_failures: 3\n    renotify_interval: -1m\n", "jobs[0].renotify_interval must be non-negative"},
+		{"renotify missing unit", "    alert_after_failures: 3\n    renotify_interval: 60\n", "jobs[0].renotify_interval 60ns is suspiciously small"},
+		{"renotify without threshold", "    renotify_interval: 1h\n", "jobs[0].renotify_interval requires alert_after_failures"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    alert_after_failures: 3\n    renotify_interval: 1h\n"
+	cfg, err := Load(writeTempFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Jobs[0].AlertAfterFailures != 3 || cfg.Jobs[0].RenotifyInterval != time.Hour {
+		t.Errorf("AlertAfterFailures = %d, RenotifyInterval = %v, want 3, 1h", cfg.Jobs[0].AlertAfterFailures, cfg.Jobs[0].RenotifyInterval)
+	}
+}
+
+func TestLoad_Validation_ExpectSuccessWithin(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"negative window", "    expect_success_within: -1h\n", "jobs[0].expect_success_within must be non-negative"},
+		{"window too short", "    expect_success_within: 30s\n", "jobs[0].expect_success_within must be at least 1m0s"},
+		{"hook without window", "    on_overdue: \"echo late\"\n", "jobs[0].on_overdue requires expect_success_within"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Setenv("CRONLOCK_TEST_PAGER", "page-oncall")
+	content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    expect_success_within: 26h\n    on_overdue: \"${CRONLOCK_TEST_PAGER} test\"\n"
+	cfg, err := Load(writeTempFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Jobs[0].ExpectSuccessWithin != 26*time.Hour || cfg.Jobs[0].OnOverdue != "page-oncall test" {
+		t.Errorf("ExpectSuccessWithin = %v, OnOverdue = %q, want 26h, %q", cfg.Jobs[0].ExpectSuccessWithin, cfg.Jobs[0].OnOverdue, "page-oncall test")
+	}
+}
+
+func TestLoad_Validation_WarnAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"negative", "    warn_after: -1m\n", "jobs[0].warn_after must be non-negative"},
+		{"missing unit", "    warn_after: 60\n", "jobs[0].warn_after 60ns is suspiciously small"},
+		{"not before timeout", "    timeout: 10m\n    warn_after: 10m\n", "jobs[0].warn_after 10m0s must be less than timeout 10m0s"},
+		{"hook without threshold", "    on_slow: \"echo slow\"\n", "jobs[0].on_slow requires warn_after"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    timeout: 1h\n    warn_after: 20m\n    on_slow: \"echo slow\"\n"
+	cfg, err := Load(writeTempFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Jobs[0].WarnAfter != 20*time.Minute || cfg.Jobs[0].OnSlow != "echo slow" {
+		t.Errorf("WarnAfter = %v, OnSlow = %q, want 20m, %q", cfg.Jobs[0].WarnAfter, cfg.Jobs[0].OnSlow, "echo slow")
+	}
+}
+
+func TestLoad_OTel(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bad protocol", "otel:\n  enabled: true\n  protocol: thrift\n", `otel.protocol must be "grpc" or "http/protobuf", got "thrift"`},
+		{"bad sample ratio", "otel:\n  enabled: true\n  sample_ratio: 1.5\n", "otel.sample_ratio must be between 0 and 1, got 1.5"},
+		{"no service name", "otel:\n  enabled: true\n  service_name: \"\"\n", "otel.service_name is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Setenv("CRONLOCK_TEST_OTLP_TOKEN", "s3cret")
+	content := "redis:\n  address: localhost:6379\notel:\n  enabled: true\n  endpoint: collector:4318\n  protocol: http/protobuf\n  headers:\n    Authorization: \"Bearer ${CRONLOCK_TEST_OTLP_TOKEN}\"\n"
+	cfg, err := Load(writeTempFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	otel := cfg.OTel
+	if !otel.Enabled || otel.Endpoint != "collector:4318" || otel.Protocol != OTelProtocolHTTP {
+		t.Errorf("OTel = %+v, want enabled with the configured endpoint and protocol", otel)
+	}
+	if otel.ServiceName != "cronlock" || otel.SampleRatio != 1 {
+		t.Errorf("ServiceName = %q, SampleRatio = %v, want the defaults", otel.ServiceName, otel.SampleRatio)
+	}
+	if got := otel.Headers["Authorization"]; got != "Bearer s3cret" {
+		t.Errorf("Headers[Authorization] = %q, want %q", got, "Bearer s3cret")
+	}
+}
+
+func TestLoad_Events(t *testing.T) {
+	cfg, err := Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Events.Enabled || cfg.Events.MaxLen != 10000 {
+		t.Errorf("Events = %+v, want disabled with max_len 10000 by default", cfg.Events)
+	}
+
+	cfg, err = Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\nevents:\n  enabled: true\n  max_len: 500\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !cfg.Events.Enabled || cfg.Events.MaxLen != 500 {
+		t.Errorf("Events = %+v, want enabled with max_len 500", cfg.Events)
+	}
+
+	_, err = Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\nevents:\n  max_len: 0\n"))
+	if want := "events.max_len must be at least 1, got 0"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("Load() error = %v, want to contain %q", err, want)
+	}
+}
+
+func TestJobsHash(t *testing.T) {
+	backup := JobConfig{Name: "backup", Schedule: "0 2 * * *", Command: ShellCommand("backup.sh"), Env: map[string]string{"A": "1", "B": "2"}}
+	report := JobConfig{Name: "report", Schedule: "0 8 * * *", Command: ShellCommand("report.sh")}
+
+	jobsHash := func(jobs ...JobConfig) string {
+		t.Helper()
+		hash, err := JobsHash(jobs)
+		if err != nil {
+			t.Fatalf("JobsHash() error = %v", err)
+		}
+		return hash
+	}
+
+	hash := jobsHash(backup, report)
+	if len(hash) != 12 {
+		t.Errorf("JobsHash() = %q, want 12 hex digits", hash)
+	}
+	if got := jobsHash(report, backup); got != hash {
+		t.Errorf("JobsHash() of reordered jobs = %q, want %q", got, hash)
+	}
+
+	changed := backup
+	changed.Command = ShellCommand("backup-v2.sh")
+	if got := jobsHash(changed, report); got == hash {
+		t.Errorf("JobsHash() of a changed job = %q, want it to differ", got)
+	}
+
+	nan := backup
+	nan.Limits.CPUMax = math.NaN()
+	if _, err := JobsHash([]JobConfig{nan, report}); err == nil || !strings.Contains(err.Error(), "job backup: failed to hash config") {
+		t.Errorf("JobsHash() with a NaN cpu_max error = %v, want an error", err)
+	}
+}
+
+func TestLoad_DriftPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bad node policy", "node:\n  drift_policy: ignore\n", `node.drift_policy must be "warn", "refuse_to_run" or "newest_wins", got "ignore"`},
+		{"bad job policy", "jobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    drift_policy: oldest_wins\n", `jobs[0].drift_policy must be "warn", "refuse_to_run" or "newest_wins" if set, got "oldest_wins"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	content := "redis:\n  address: localhost:6379\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n    drift_policy: refuse_to_run\n"
+	cfg, err := Load(writeTempFile(t, "config.yaml", content))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Node.DriftPolicy != DriftWarn {
+		t.Errorf("Node.DriftPolicy = %q, want %q by default", cfg.Node.DriftPolicy, DriftWarn)
+	}
+	if cfg.Jobs[0].DriftPolicy != DriftRefuseToRun {
+		t.Errorf("Jobs[0].DriftPolicy = %q, want %q", cfg.Jobs[0].DriftPolicy, DriftRefuseToRun)
+	}
+}
+
+// jobHash returns job.Hash(), failing the test on an error.
+func jobHash(t *testing.T, job JobConfig) string {
+	t.Helper()
+	hash, err := job.Hash()
+	if err != nil {
+		t.Fatalf("Hash() error = %v", err)
+	}
+	return hash
+}
+
+func TestJobConfig_Hash(t *testing.T) {
+	job := JobConfig{Name: "backup", Schedule: "0 2 * * *", Command: ShellCommand("backup-v1.sh")}
+	same := JobConfig{Name: "backup", Schedule: "0 2 * * *", Command: ShellCommand("backup-v1.sh")}
+	if jobHash(t, job) != jobHash(t, same) {
+		t.Errorf("Hash() = %q and %q for the same job, want equal", jobHash(t, job), jobHash(t, same))
+	}
+	changed := job
+	changed.Command = ShellCommand("backup-v2.sh")
+	if jobHash(t, job) == jobHash(t, changed) {
+		t.Errorf("Hash() = %q for a changed command, want it to differ", jobHash(t, changed))
+	}
+
+	job.Limits.CPUMax = math.NaN()
+	if _, err := job.Hash(); err == nil {
+		t.Error("Hash() with a NaN cpu_max error = nil, want an error")
+	}
+}
+
+func TestLoad_HashBeforeExpansion(t *testing.T) {
+	content := "redis:\n  address: localhost:6379\njobs:\n  - name: backup\n    schedule: \"@daily\"\n    command: backup.sh --host ${BACKUP_HOST}\n"
+	path := writeTempFile(t, "config.yaml", content)
+
+	// Two hosts with different values of the same definition
+	t.Setenv("BACKUP_HOST", "node-1")
+	first, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	t.Setenv("BACKUP_HOST", "node-2")
+	second, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if first.Jobs[0].Command.Line == second.Jobs[0].Command.Line {
+		t.Fatalf("Command.Line = %q on both hosts, want it expanded", first.Jobs[0].Command.Line)
+	}
+	if a, b := jobHash(t, first.Jobs[0]), jobHash(t, second.Jobs[0]); a != b {
+		t.Errorf("Hash() = %q and %q for the same definition, want equal", a, b)
+	}
+
+	changed := writeTempFile(t, "config.yaml", strings.Replace(content, "backup.sh", "backup-v2.sh", 1))
+	third, err := Load(changed)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if jobHash(t, third.Jobs[0]) == jobHash(t, second.Jobs[0]) {
+		t.Error("Hash() is unchanged for a changed definition, want it to differ")
+	}
+}
+
+func TestLoad_ConfigSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"bad source", "config:\n  source: etcd\n", `config.source must be "file" or "redis", got "etcd"`},
+		{"local jobs with redis source", "config:\n  source: redis\njobs:\n  - name: test\n    schedule: \"@daily\"\n    command: \"true\"\n", `jobs must not be set when config.source is "redis"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "redis:\n  address: localhost:6379\n" + tt.content
+			_, err := Load(writeTempFile(t, "config.yaml", content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Load() error = %v, want to contain %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	cfg, err := Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if cfg.Config.Source != SourceFile {
+		t.Errorf("Config.Source = %q, want %q by default", cfg.Config.Source, SourceFile)
+	}
+}
+
+func TestParseJobs(t *testing.T) {
+	t.Setenv("BACKUP_SCRIPT", "backup.sh")
+
+	yamlJobs, err := ParseJobs([]byte("jobs:\n  - name: backup\n    schedule: \"@daily\"\n    command: ${BACKUP_SCRIPT}\n    timeout: 5m\n"), FormatYAML)
+	if err != nil {
+		t.Fatalf("ParseJobs(yaml) error = %v", err)
+	}
+	if len(yamlJobs) != 1 || yamlJobs[0].Name != "backup" || yamlJobs[0].Command.Line != "backup.sh" || yamlJobs[0].Timeout != 5*time.Minute {
+		t.Errorf("ParseJobs(yaml) = %+v, want the backup job with its variables expanded", yamlJobs)
+	}
+
+	tomlJobs, err := ParseJobs([]byte("[[jobs]]\nname = \"backup\"\nschedule = \"@daily\"\ncommand = \"${BACKUP_SCRIPT}\"\ntimeout = \"5m\"\n"), FormatTOML)
+	if err != nil {
+		t.Fatalf("ParseJobs(toml) error = %v", err)
+	}
+	if jobHash(t, yamlJobs[0]) != jobHash(t, tomlJobs[0]) {
+		t.Errorf("ParseJobs(toml) = %+v, want the same job as from YAML", tomlJobs)
+	}
+
+	if _, err := ParseJobs([]byte("redis:\n  address: localhost:6379\njobs: []\n"), FormatYAML); err == nil || !strings.Contains(err.Error(), `may only contain jobs, found "redis.address"`) {
+		t.Errorf("ParseJobs() with node settings error = %v, want it rejected", err)
+	}
+	if _, err := ParseJobs([]byte("jobs: []"), "json"); err == nil {
+		t.Error("ParseJobs() with an unsupported format error = nil, want an error")
+	}
+}
+
+func TestLoad_Validation_HostChecksSkipped(t *testing.T) {
+	content := `
+redis:
+  address: localhost:6379
+node:
+  labels:
+    role: web
+jobs:
+  - name: constrained
+    schedule: "@daily"
+    command: [cronlock-test-no-such-binary]
+    user: cronlock-test-no-such-user
+    constraints: ["role=db"]
+  - name: disabled
+    schedule: "@daily"
+    command: "true"
+    shell: /nonexistent/sh
+    user: cronlock-test-no-such-user
+    enabled: false
+`
+	path := writeTempFile(t, "config.yaml", content)
+	if _, err := Load(path); err != nil {
+		t.Errorf("Load() error = %v, want nil for jobs this node doesn't run", err)
+	}
+
+	onNode := strings.Replace(content, "role: web", "role: db", 1)
+	path = writeTempFile(t, "config.yaml", onNode)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "jobs[0].command") {
+		t.Errorf("Load() error = %v, want jobs[0].command not found", err)
+	}
+	if _, err := Load(path, WithoutHostChecks()); err != nil {
+		t.Errorf("Load(WithoutHostChecks()) error = %v, want nil", err)
+	}
+}
+
+func TestValidateJobs(t *testing.T) {
+	// Programs and notifiers of other hosts are accepted
+	jobs := []JobConfig{{
+		Name:     "backup",
+		Schedule: "@daily",
+		Command:  Command{Argv: []string{"/opt/not-on-this-host/backup"}},
+		Notify:   []NotifyConfig{{Notifier: "ops-slack", Events: []string{EventFailure}}},
+	}}
+	if err := ValidateJobs(jobs); err != nil {
+		t.Errorf("ValidateJobs() error = %v, want nil", err)
+	}
+
+	jobs = append(jobs, JobConfig{Name: "backup", Schedule: "@daily", Command: ShellCommand("true")})
+	if err := ValidateJobs(jobs); err == nil || !strings.Contains(err.Error(), "jobs[1].name \"backup\" is a duplicate of jobs[0]") {
+		t.Errorf("ValidateJobs() error = %v, want the duplicate rejected", err)
+	}
+}
+
+func TestConfig_WithJobs(t *testing.T) {
+	cfg, err := Load(writeTempFile(t, "config.yaml", "redis:\n  address: localhost:6379\nconfig:\n  source: redis\n"))
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	got, err := cfg.WithJobs([]JobConfig{{Name: "test", Schedule: "@daily", Command: ShellCommand("true")}})
+	if err != nil {
+		t.Fatalf("WithJobs() error = %v", err)
+	}
+	if len(got.Jobs) != 1 || len(cfg.Jobs) != 0 {
+		t.Errorf("WithJobs() jobs = %d, original = %d, want 1 and 0", len(got.Jobs), len(cfg.Jobs))
+	}
+
+	_, err = cfg.WithJobs([]JobConfig{{
+		Name:     "test",
+		Schedule: "@daily",
+		Command:  ShellCommand("true"),
+		Notify:   []NotifyConfig{{Notifier: "ops-slack", Events: []string{EventFailure}}},
+	}})
+	if err == nil {
+		t.Error("WithJobs() with an undefined notifier error = nil, want an error")
+	}
+}
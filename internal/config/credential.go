@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Linux capability bits allowing a process to change its user and groups.
+const (
+	capSetgid = 6
+	capSetuid = 7
+)
+
+// RunAs is the identity a job's command runs as, resolved from its user,
+// group and supplementary_groups.
+type RunAs struct {
+	UID    uint32
+	GID    uint32
+	Groups []uint32 // supplementary groups
+	// User and Home are set as USER, LOGNAME and HOME in the job's environment.
+	User string
+	Home string
+}
+
+// RunAs resolves the job's user, group and supplementary_groups. It returns
+// nil if none are set, and the job runs as cronlock's own user.
+//
+// The group defaults to the user's primary group, and the supplementary
+// groups to the groups the user is a member of, as login and cron do.
+func (j JobConfig) RunAs() (*RunAs, error) {
+	if j.User == "" && j.Group == "" && len(j.SupplementaryGroups) == 0 {
+		return nil, nil
+	}
+
+	var u *user.User
+	var err error
+	if j.User != "" {
+		u, err = lookupUser(j.User)
+	} else {
+		u, err = user.Current()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	uid, err := parseID(u.Uid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric uid %q", u.Username, u.Uid)
+	}
+	gid, err := parseID(u.Gid)
+	if err != nil {
+		return nil, fmt.Errorf("user %q has a non-numeric gid %q", u.Username, u.Gid)
+	}
+	if j.Group != "" {
+		if gid, err = lookupGroupID(j.Group); err != nil {
+			return nil, err
+		}
+	}
+
+	runAs := &RunAs{UID: uid, GID: gid, User: u.Username, Home: u.HomeDir}
+
+	names := j.SupplementaryGroups
+	if len(names) == 0 && j.User != "" {
+		if names, err = u.GroupIds(); err != nil {
+			return nil, fmt.Errorf("failed to look up groups of user %q: %w", u.Username, err)
+		}
+	}
+	for _, name := range names {
+		id, err := lookupGroupID(name)
+		if err != nil {
+			return nil, err
+		}
+		if !slices.Contains(runAs.Groups, id) {
+			runAs.Groups = append(runAs.Groups, id)
+		}
+	}
+	return runAs, nil
+}
+
+// lookupUser looks up a user by name or numeric ID.
+func lookupUser(name string) (*user.User, error) {
+	if _, err := parseID(name); err == nil {
+		if u, err := user.LookupId(name); err == nil {
+			return u, nil
+		}
+	}
+	u, err := user.Lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("user %q not found", name)
+	}
+	return u, nil
+}
+
+// lookupGroupID looks up a group by name or numeric ID.
+func lookupGroupID(name string) (uint32, error) {
+	if id, err := parseID(name); err == nil {
+		if _, err := user.LookupGroupId(name); err == nil {
+			return id, nil
+		}
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, fmt.Errorf("group %q not found", name)
+	}
+	id, err := parseID(g.Gid)
+	if err != nil {
+		return 0, fmt.Errorf("group %q has a non-numeric gid %q", name, g.Gid)
+	}
+	return id, nil
+}
+
+// parseID parses a numeric user or group ID.
+func parseID(s string) (uint32, error) {
+	id, err := strconv.ParseUint(s, 10, 32)
+	return uint32(id), err
+}
+
+// checkRunAsPrivilege reports an error if cronlock can't switch to the
+// identity: it must be root or have CAP_SETUID and CAP_SETGID, unless the
+// identity is its own.
+func checkRunAsPrivilege(r *RunAs) error {
+	if os.Geteuid() == 0 {
+		return nil
+	}
+	if r.UID == uint32(os.Geteuid()) && r.GID == uint32(os.Getegid()) && sameGroups(r.Groups) {
+		return nil
+	}
+	if caps, err := effectiveCapabilities(); err == nil && caps&(1<<capSetuid) != 0 && caps&(1<<capSetgid) != 0 {
+		return nil
+	}
+	return fmt.Errorf("cronlock runs as uid %d and can't switch to user %q; run it as root or grant CAP_SETUID and CAP_SETGID", os.Geteuid(), r.User)
+}
+
+// sameGroups reports whether groups are cronlock's own supplementary groups.
+func sameGroups(groups []uint32) bool {
+	own, err := os.Getgroups()
+	if err != nil || len(own) != len(groups) {
+		return false
+	}
+	for _, g := range own {
+		if !slices.Contains(groups, uint32(g)) {
+			return false
+		}
+	}
+	return true
+}
+
+// effectiveCapabilities reads the process's effective capability set from
+// /proc/self/status.
+func effectiveCapabilities() (uint64, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if hex, ok := strings.CutPrefix(scanner.Text(), "CapEff:"); ok {
+			return strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("CapEff not found in /proc/self/status")
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// hashLength is the number of hex digits kept of a configuration hash.
+const hashLength = 12
+
+// Hash returns a short hash identifying the job's configuration, so nodes
+// can tell whether they run the job the same way. For a loaded job, it is
+// the hash of the job as written, before ${VAR} expansion, so values that
+// differ between hosts don't make the same definition differ.
+func (j JobConfig) Hash() (string, error) {
+	if j.rawHash != "" {
+		return j.rawHash, nil
+	}
+	return hash(j)
+}
+
+// JobsHash returns a short hash identifying a set of jobs, so nodes can
+// tell whether they run the same configuration. The order of the jobs
+// doesn't matter.
+func JobsHash(jobs []JobConfig) (string, error) {
+	hashes := make(map[string]string, len(jobs))
+	for _, job := range jobs {
+		h, err := job.Hash()
+		if err != nil {
+			return "", fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		hashes[job.Name] = h
+	}
+	return hash(hashes)
+}
+
+// setRawHashes records the hash of each job as written, before ${VAR}
+// expansion. A job that can't be hashed, e.g. with a NaN cpu_max, is left
+// to validation to reject.
+func setRawHashes(jobs []JobConfig) {
+	for i := range jobs {
+		if h, err := hash(jobs[i]); err == nil {
+			jobs[i].rawHash = h
+		}
+	}
+}
+
+// hash returns the truncated SHA-256 of v's JSON encoding. Maps marshal
+// with sorted keys, so the encoding of a configuration is canonical.
+func hash(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:hashLength], nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultCgroupParent is where per-run cgroups are created if
+// node.cgroup_parent is not set.
+const DefaultCgroupParent = "/sys/fs/cgroup/cronlock"
+
+// LimitsConfig caps the resources a job's command can use.
+//
+// The rlimits are set on the command's process as it starts and are
+// inherited by its children. The cgroup limits apply to the command and all
+// its children together, in a cgroup v2 group created for each run under
+// node.cgroup_parent.
+type LimitsConfig struct {
+	// NoFile is the maximum number of open files (RLIMIT_NOFILE).
+	NoFile *uint64 `koanf:"nofile"`
+	// NProc is the maximum number of processes of the job's user, counted
+	// across all its processes, not only the job's (RLIMIT_NPROC).
+	NProc *uint64 `koanf:"nproc"`
+	// Core is the maximum size of a core dump; 0 disables them (RLIMIT_CORE).
+	Core *ByteSize `koanf:"core"`
+	// AS is the maximum size of each process's address space (RLIMIT_AS).
+	AS *ByteSize `koanf:"as"`
+
+	// MemoryMax is the run's memory limit (memory.max). A run going over it
+	// is killed as a whole.
+	MemoryMax ByteSize `koanf:"memory_max"`
+	// CPUWeight is the run's share of CPU under contention, from 1 to
+	// 10000 with a default of 100 (cpu.weight).
+	CPUWeight int `koanf:"cpu_weight"`
+	// CPUMax is how many CPUs' worth of time the run may use, e.g. 0.5
+	// (cpu.max).
+	CPUMax float64 `koanf:"cpu_max"`
+	// PidsMax is the maximum number of processes in the run (pids.max).
+	PidsMax int `koanf:"pids_max"`
+}
+
+// HasRlimits reports whether any rlimit is set.
+func (l LimitsConfig) HasRlimits() bool {
+	return l.NoFile != nil || l.NProc != nil || l.Core != nil || l.AS != nil
+}
+
+// HasCgroup reports whether any cgroup limit is set, so each run needs a
+// cgroup of its own.
+func (l LimitsConfig) HasCgroup() bool {
+	return l.MemoryMax > 0 || l.CPUWeight > 0 || l.CPUMax > 0 || l.PidsMax > 0
+}
+
+// validate checks the limits' ranges.
+func (l LimitsConfig) validate() error {
+	if l.CPUWeight != 0 && (l.CPUWeight < 1 || l.CPUWeight > 10000) {
+		return fmt.Errorf("cpu_weight must be between 1 and 10000, got %d", l.CPUWeight)
+	}
+	if math.IsNaN(l.CPUMax) || math.IsInf(l.CPUMax, 0) {
+		return fmt.Errorf("cpu_max must be a finite number of CPUs, got %v", l.CPUMax)
+	}
+	if l.CPUMax < 0 || (l.CPUMax > 0 && l.CPUMax < 0.01) {
+		return fmt.Errorf("cpu_max must be at least 0.01 CPUs, got %v", l.CPUMax)
+	}
+	if l.PidsMax < 0 {
+		return fmt.Errorf("pids_max must be non-negative, got %d", l.PidsMax)
+	}
+	return nil
+}
+
+// ByteSize is a size in bytes. In the config it is a number of bytes or a
+// string with a K, M, G or T suffix, e.g. "512M"; suffixes are powers of
+// 1024 and may be followed by "B" or "iB".
+type ByteSize uint64
+
+// byteSizeUnits are the multipliers of the ByteSize suffixes.
+var byteSizeUnits = map[string]uint64{
+	"":  1,
+	"K": 1 << 10,
+	"M": 1 << 20,
+	"G": 1 << 30,
+	"T": 1 << 40,
+}
+
+// ParseByteSize parses a size such as "4096", "512M" or "2GiB".
+func ParseByteSize(s string) (ByteSize, error) {
+	str := strings.ToUpper(strings.TrimSpace(s))
+	if str != "B" {
+		str = strings.TrimSuffix(strings.TrimSuffix(str, "B"), "I")
+	}
+	digits := strings.TrimRight(str, "KMGT")
+	unit, ok := byteSizeUnits[str[len(digits):]]
+	if !ok || digits == "" {
+		return 0, fmt.Errorf("invalid size %q: use bytes or a K, M, G or T suffix", s)
+	}
+	n, err := strconv.ParseUint(digits, 10, 64)
+	if err != nil || n > (1<<64-1)/unit {
+		return 0, fmt.Errorf("invalid size %q: use bytes or a K, M, G or T suffix", s)
+	}
+	return ByteSize(n * unit), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	size, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = size
+	return nil
+}
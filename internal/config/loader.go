@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/knadh/koanf/parsers/toml"
 	"github.com/knadh/koanf/parsers/yaml"
 	"github.com/knadh/koanf/providers/file"
+	"github.com/knadh/koanf/providers/rawbytes"
 	"github.com/knadh/koanf/v2"
 	"github.com/robfig/cron/v3"
 )
@@ -19,22 +21,75 @@ var cronParser = cron.NewParser(
 	cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
 )
 
-// Load reads and parses a configuration file. Supports YAML and TOML formats
-// based on file extension. Environment variables in the format ${VAR} or
-// ${VAR:-default} are substituted.
-func Load(path string) (*Config, error) {
-	k := koanf.New(".")
+// ParseSchedule parses a job schedule with the same parser the scheduler uses.
+func ParseSchedule(spec string) (cron.Schedule, error) {
+	return cronParser.Parse(spec)
+}
 
-	ext := strings.ToLower(filepath.Ext(path))
-	var parser koanf.Parser
+// Configuration formats, as returned by Format.
+const (
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
 
+// Format returns the configuration format of a file, based on its
+// extension.
+func Format(path string) (string, error) {
+	ext := strings.ToLower(filepath.Ext(path))
 	switch ext {
 	case ".yaml", ".yml":
-		parser = yaml.Parser()
+		return FormatYAML, nil
 	case ".toml":
-		parser = toml.Parser()
-	default:
-		return nil, fmt.Errorf("unsupported config format: %s", ext)
+		return FormatTOML, nil
+	}
+	return "", fmt.Errorf("unsupported config format: %s", ext)
+}
+
+// parserFor returns the koanf parser of a configuration format.
+func parserFor(format string) (koanf.Parser, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Parser(), nil
+	case FormatTOML:
+		return toml.Parser(), nil
+	}
+	return nil, fmt.Errorf("unsupported config format: %s", format)
+}
+
+// LoadOption configures Load.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	hostChecks bool
+}
+
+// WithoutHostChecks skips the checks that programs, shells and users exist
+// on this host, for commands that read the configuration without running
+// jobs, possibly on a host that isn't a node.
+func WithoutHostChecks() LoadOption {
+	return func(o *loadOptions) {
+		o.hostChecks = false
+	}
+}
+
+// Load reads and parses a configuration file. Supports YAML and TOML formats
+// based on file extension. Environment variables in the format ${VAR} or
+// ${VAR:-default} are substituted.
+func Load(path string, opts ...LoadOption) (*Config, error) {
+	options := loadOptions{hostChecks: true}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	k := koanf.New(".")
+
+	format, err := Format(path)
+	if err != nil {
+		return nil, err
+	}
+	parser, err := parserFor(format)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := k.Load(file.Provider(path), parser); err != nil {
@@ -42,36 +97,131 @@ func Load(path string) (*Config, error) {
 	}
 
 	cfg := Defaults()
-	if err := k.Unmarshal("", &cfg); err != nil {
+	if err := k.UnmarshalWithConf("", &cfg, koanf.UnmarshalConf{DecoderConfig: decoderConfig()}); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
-	// Expand environment variables in string fields
+	// Expand environment variables in string fields, after hashing the jobs
+	// as written
+	setRawHashes(cfg.Jobs)
 	expandEnvInConfig(&cfg)
 
 	// Validate configuration
-	if err := validate(&cfg); err != nil {
+	if err := validate(&cfg, options.hostChecks); err != nil {
 		return nil, err
 	}
 
 	return &cfg, nil
 }
 
+// ParseJobs parses a jobs document: the "jobs" list of a configuration
+// file, in the given format, as pushed to Redis for nodes with config.source
+// set to redis. Environment variables are substituted as in Load, but the
+// jobs are not validated; see ValidateJobs and Config.WithJobs.
+func ParseJobs(data []byte, format string) ([]JobConfig, error) {
+	parser, err := parserFor(format)
+	if err != nil {
+		return nil, err
+	}
+	k := koanf.New(".")
+	if err := k.Load(rawbytes.Provider(data), parser); err != nil {
+		return nil, fmt.Errorf("failed to parse jobs: %w", err)
+	}
+	for _, key := range k.Keys() {
+		if key != "jobs" && !strings.HasPrefix(key, "jobs.") {
+			return nil, fmt.Errorf("jobs document may only contain jobs, found %q", key)
+		}
+	}
+
+	var cfg Config
+	if err := k.UnmarshalWithConf("", &cfg, koanf.UnmarshalConf{DecoderConfig: decoderConfig()}); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal jobs: %w", err)
+	}
+	setRawHashes(cfg.Jobs)
+	expandEnvInJobs(cfg.Jobs)
+	return cfg.Jobs, nil
+}
+
+// ValidateJobs checks the jobs of a jobs document with the checks that
+// don't depend on the host: programs, shells and users aren't looked up,
+// and notifier names aren't checked, as nodes configure their own
+// notifiers. Config.WithJobs makes the full checks on each node.
+func ValidateJobs(jobs []JobConfig) error {
+	return validateJobs(jobs, nil, nil)
+}
+
+// WithJobs returns a copy of the configuration with its jobs replaced,
+// validating them against the rest of the configuration and this host.
+func (c *Config) WithJobs(jobs []JobConfig) (*Config, error) {
+	cfg := *c
+	cfg.Jobs = jobs
+	notifiers, err := validateNotifiers(cfg.Notifiers)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateJobs(cfg.Jobs, notifiers, &cfg.Node); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
 // expandEnvInConfig expands environment variables in configuration values.
 func expandEnvInConfig(cfg *Config) {
 	cfg.Node.ID = expandEnv(cfg.Node.ID)
+	cfg.Node.Shell = expandEnv(cfg.Node.Shell)
+	cfg.Node.CgroupParent = expandEnv(cfg.Node.CgroupParent)
+	for k, v := range cfg.Node.Labels {
+		cfg.Node.Labels[k] = expandEnv(v)
+	}
 	cfg.Redis.Address = expandEnv(cfg.Redis.Address)
 	cfg.Redis.Password = expandEnv(cfg.Redis.Password)
 	cfg.Redis.KeyPrefix = expandEnv(cfg.Redis.KeyPrefix)
+	cfg.API.Listen = expandEnv(cfg.API.Listen)
+	cfg.API.Token = expandEnv(cfg.API.Token)
+	cfg.Output.Dir = expandEnv(cfg.Output.Dir)
+	cfg.Log.Output = expandEnv(cfg.Log.Output)
+	cfg.OTel.Endpoint = expandEnv(cfg.OTel.Endpoint)
+	cfg.OTel.ServiceName = expandEnv(cfg.OTel.ServiceName)
+	for k, v := range cfg.OTel.Headers {
+		cfg.OTel.Headers[k] = expandEnv(v)
+	}
+
+	for i := range cfg.Notifiers {
+		n := &cfg.Notifiers[i]
+		n.URL = expandEnv(n.URL)
+		n.Secret = expandEnv(n.Secret)
+		for k, v := range n.Headers {
+			n.Headers[k] = expandEnv(v)
+		}
+		n.SMTP.Host = expandEnv(n.SMTP.Host)
+		n.SMTP.Username = expandEnv(n.SMTP.Username)
+		n.SMTP.Password = expandEnv(n.SMTP.Password)
+	}
+
+	expandEnvInJobs(cfg.Jobs)
+}
 
-	for i := range cfg.Jobs {
-		cfg.Jobs[i].Name = expandEnv(cfg.Jobs[i].Name)
-		cfg.Jobs[i].Command = expandEnv(cfg.Jobs[i].Command)
-		cfg.Jobs[i].WorkDir = expandEnv(cfg.Jobs[i].WorkDir)
-		cfg.Jobs[i].OnFailure = expandEnv(cfg.Jobs[i].OnFailure)
-		cfg.Jobs[i].OnSuccess = expandEnv(cfg.Jobs[i].OnSuccess)
-		for k, v := range cfg.Jobs[i].Env {
-			cfg.Jobs[i].Env[k] = expandEnv(v)
+// expandEnvInJobs expands environment variables in job configuration values.
+func expandEnvInJobs(jobs []JobConfig) {
+	for i := range jobs {
+		jobs[i].Name = expandEnv(jobs[i].Name)
+		jobs[i].Command.Line = expandEnv(jobs[i].Command.Line)
+		for j, arg := range jobs[i].Command.Argv {
+			jobs[i].Command.Argv[j] = expandEnv(arg)
+		}
+		jobs[i].Shell = expandEnv(jobs[i].Shell)
+		jobs[i].User = expandEnv(jobs[i].User)
+		jobs[i].Group = expandEnv(jobs[i].Group)
+		jobs[i].WorkDir = expandEnv(jobs[i].WorkDir)
+		jobs[i].OnFailure = expandEnv(jobs[i].OnFailure)
+		jobs[i].OnSuccess = expandEnv(jobs[i].OnSuccess)
+		jobs[i].OnStart = expandEnv(jobs[i].OnStart)
+		jobs[i].OnTimeout = expandEnv(jobs[i].OnTimeout)
+		jobs[i].OnComplete = expandEnv(jobs[i].OnComplete)
+		jobs[i].OnSlow = expandEnv(jobs[i].OnSlow)
+		jobs[i].OnOverdue = expandEnv(jobs[i].OnOverdue)
+		for k, v := range jobs[i].Env {
+			jobs[i].Env[k] = expandEnv(v)
 		}
 	}
 }
@@ -93,8 +243,9 @@ func expandEnv(s string) string {
 	})
 }
 
-// validate checks the configuration for errors.
-func validate(cfg *Config) error {
+// validate checks the configuration for errors. hostChecks also checks
+// that programs, shells and users exist on this host.
+func validate(cfg *Config, hostChecks bool) error {
 	if cfg.Redis.Address == "" {
 		return fmt.Errorf("redis.address is required")
 	}
@@ -109,8 +260,104 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("node.grace_period must be non-negative, got %v", cfg.Node.GracePeriod)
 	}
 
+	// Validate coordination mode
+	switch cfg.Node.Coordination {
+	case CoordinationLock, CoordinationLeader:
+	default:
+		return fmt.Errorf("node.coordination must be %q or %q, got %q", CoordinationLock, CoordinationLeader, cfg.Node.Coordination)
+	}
+	if cfg.Node.LeaderTTL < time.Second {
+		return fmt.Errorf("node.leader_ttl must be at least 1s, got %v", cfg.Node.LeaderTTL)
+	}
+
+	if !validDriftPolicy(cfg.Node.DriftPolicy) {
+		return fmt.Errorf("node.drift_policy must be %q, %q or %q, got %q", DriftWarn, DriftRefuseToRun, DriftNewestWins, cfg.Node.DriftPolicy)
+	}
+
+	if cfg.Node.MaxConcurrentJobs < 0 {
+		return fmt.Errorf("node.max_concurrent_jobs must be non-negative, got %d", cfg.Node.MaxConcurrentJobs)
+	}
+
+	if cfg.Node.Shell != "" && hostChecks {
+		if err := validateShell(cfg.Node.Shell); err != nil {
+			return fmt.Errorf("node.shell %q is invalid: %w", cfg.Node.Shell, err)
+		}
+	}
+
+	if !filepath.IsAbs(cfg.Node.CgroupParent) {
+		return fmt.Errorf("node.cgroup_parent must be an absolute path, got %q", cfg.Node.CgroupParent)
+	}
+
+	for k := range cfg.Node.Labels {
+		if !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("node.labels key %q is invalid", k)
+		}
+	}
+
+	if cfg.API.Listen != "" {
+		if _, _, err := net.SplitHostPort(cfg.API.Listen); err != nil {
+			return fmt.Errorf("api.listen %q is invalid: %w", cfg.API.Listen, err)
+		}
+	}
+
+	if cfg.Output.MaxCapture < 1024 {
+		return fmt.Errorf("output.max_capture must be at least 1024 bytes, got %d", cfg.Output.MaxCapture)
+	}
+	if cfg.Output.Keep < 0 {
+		return fmt.Errorf("output.keep must be non-negative, got %d", cfg.Output.Keep)
+	}
+	if cfg.Output.MaxAge < 0 {
+		return fmt.Errorf("output.max_age must be non-negative, got %v", cfg.Output.MaxAge)
+	}
+
+	switch cfg.Log.Format {
+	case LogFormatText, LogFormatLogfmt, LogFormatJSON:
+	default:
+		return fmt.Errorf("log.format must be %q, %q or %q, got %q", LogFormatText, LogFormatLogfmt, LogFormatJSON, cfg.Log.Format)
+	}
+	if _, err := ParseLogLevel(cfg.Log.Level); err != nil {
+		return fmt.Errorf("log.level: %w", err)
+	}
+	if cfg.Log.Output == "" {
+		return fmt.Errorf("log.output is required")
+	}
+	if err := cfg.OTel.validate(); err != nil {
+		return err
+	}
+	if cfg.Events.MaxLen < 1 {
+		return fmt.Errorf("events.max_len must be at least 1, got %d", cfg.Events.MaxLen)
+	}
+	switch cfg.Config.Source {
+	case SourceFile:
+	case SourceRedis:
+		if len(cfg.Jobs) > 0 {
+			return fmt.Errorf("jobs must not be set when config.source is %q; push them with \"cronlock config push\"", SourceRedis)
+		}
+	default:
+		return fmt.Errorf("config.source must be %q or %q, got %q", SourceFile, SourceRedis, cfg.Config.Source)
+	}
+
+	notifiers, err := validateNotifiers(cfg.Notifiers)
+	if err != nil {
+		return err
+	}
+
+	var host *NodeConfig
+	if hostChecks {
+		host = &cfg.Node
+	}
+	return validateJobs(cfg.Jobs, notifiers, host)
+}
+
+// validateJobs checks job settings. notifiers are the names of the
+// configured notifiers; nil skips checking them. host is this node: the
+// programs, shells and users of enabled jobs it can run are checked to
+// exist on this host. A nil host skips these checks.
+func validateJobs(jobs []JobConfig, notifiers map[string]bool, host *NodeConfig) error {
 	seen := make(map[string]int)
-	for i, job := range cfg.Jobs {
+	for i, job := range jobs {
+		onHost := host != nil && job.IsEnabled() && job.CanRunOn(host.Labels)
+
 		if job.Name == "" {
 			return fmt.Errorf("jobs[%d].name is required", i)
 		}
@@ -125,9 +372,62 @@ func validate(cfg *Config) error {
 		if _, err := cronParser.Parse(job.Schedule); err != nil {
 			return fmt.Errorf("jobs[%d].schedule %q is invalid: %w", i, job.Schedule, err)
 		}
-		if job.Command == "" {
+		if job.Command.IsZero() {
 			return fmt.Errorf("jobs[%d].command is required", i)
 		}
+		if len(job.Command.Argv) > 0 && onHost {
+			if err := lookPath(job.Command.Argv[0], job.WorkDir); err != nil {
+				return fmt.Errorf("jobs[%d].command: %w", i, err)
+			}
+		}
+		if onHost {
+			runAs, err := job.RunAs()
+			if err != nil {
+				return fmt.Errorf("jobs[%d]: %w", i, err)
+			}
+			if runAs != nil {
+				if err := checkRunAsPrivilege(runAs); err != nil {
+					return fmt.Errorf("jobs[%d]: %w", i, err)
+				}
+			}
+		}
+		if err := job.Limits.validate(); err != nil {
+			return fmt.Errorf("jobs[%d].limits.%w", i, err)
+		}
+		if err := validateNotify(job.Notify, notifiers); err != nil {
+			return fmt.Errorf("jobs[%d].%w", i, err)
+		}
+		if job.AlertAfterFailures < 0 {
+			return fmt.Errorf("jobs[%d].alert_after_failures must be non-negative, got %d", i, job.AlertAfterFailures)
+		}
+		if job.RenotifyInterval < 0 {
+			return fmt.Errorf("jobs[%d].renotify_interval must be non-negative, got %v", i, job.RenotifyInterval)
+		}
+		if job.RenotifyInterval > 0 && job.RenotifyInterval < time.Second {
+			return fmt.Errorf("jobs[%d].renotify_interval %v is suspiciously small (did you forget the time unit like '30s' or '5m'?)", i, job.RenotifyInterval)
+		}
+		if job.RenotifyInterval > 0 && job.AlertAfterFailures == 0 {
+			return fmt.Errorf("jobs[%d].renotify_interval requires alert_after_failures", i)
+		}
+		if job.ExpectSuccessWithin < 0 {
+			return fmt.Errorf("jobs[%d].expect_success_within must be non-negative, got %v", i, job.ExpectSuccessWithin)
+		}
+		if job.ExpectSuccessWithin > 0 && job.ExpectSuccessWithin < MinExpectSuccessWithin {
+			return fmt.Errorf("jobs[%d].expect_success_within must be at least %v, got %v", i, MinExpectSuccessWithin, job.ExpectSuccessWithin)
+		}
+		if job.OnOverdue != "" && job.ExpectSuccessWithin == 0 {
+			return fmt.Errorf("jobs[%d].on_overdue requires expect_success_within", i)
+		}
+		if job.LogLevel != "" {
+			if _, err := ParseLogLevel(job.LogLevel); err != nil {
+				return fmt.Errorf("jobs[%d].log_level: %w", i, err)
+			}
+		}
+		if job.Shell != "" && onHost {
+			if err := validateShell(job.Shell); err != nil {
+				return fmt.Errorf("jobs[%d].shell %q is invalid: %w", i, job.Shell, err)
+			}
+		}
 		// Validate duration fields
 		if job.Timeout < 0 {
 			return fmt.Errorf("jobs[%d].timeout must be non-negative, got %v", i, job.Timeout)
@@ -135,12 +435,55 @@ func validate(cfg *Config) error {
 		if job.Timeout > 0 && job.Timeout < time.Second {
 			return fmt.Errorf("jobs[%d].timeout %v is suspiciously small (did you forget the time unit like '30s' or '5m'?)", i, job.Timeout)
 		}
+		if job.WarnAfter < 0 {
+			return fmt.Errorf("jobs[%d].warn_after must be non-negative, got %v", i, job.WarnAfter)
+		}
+		if job.WarnAfter > 0 && job.WarnAfter < time.Second {
+			return fmt.Errorf("jobs[%d].warn_after %v is suspiciously small (did you forget the time unit like '30s' or '5m'?)", i, job.WarnAfter)
+		}
+		if job.WarnAfter > 0 && job.Timeout > 0 && job.WarnAfter >= job.Timeout {
+			return fmt.Errorf("jobs[%d].warn_after %v must be less than timeout %v", i, job.WarnAfter, job.Timeout)
+		}
+		if job.OnSlow != "" && job.WarnAfter == 0 {
+			return fmt.Errorf("jobs[%d].on_slow requires warn_after", i)
+		}
 		if job.LockTTL < 0 {
 			return fmt.Errorf("jobs[%d].lock_ttl must be non-negative, got %v", i, job.LockTTL)
 		}
 		if job.LockTTL > 0 && job.LockTTL < time.Second {
 			return fmt.Errorf("jobs[%d].lock_ttl %v is suspiciously small (did you forget the time unit like '30s' or '5m'?)", i, job.LockTTL)
 		}
+		if job.HookTimeout < 0 {
+			return fmt.Errorf("jobs[%d].hook_timeout must be non-negative, got %v", i, job.HookTimeout)
+		}
+		if job.HookTimeout > 0 && job.HookTimeout < time.Second {
+			return fmt.Errorf("jobs[%d].hook_timeout %v is suspiciously small (did you forget the time unit like '30s' or '5m'?)", i, job.HookTimeout)
+		}
+		if job.Shards < 0 {
+			return fmt.Errorf("jobs[%d].shards must be non-negative, got %d", i, job.Shards)
+		}
+		if job.MaxShardsPerNode < 0 {
+			return fmt.Errorf("jobs[%d].max_shards_per_node must be non-negative, got %d", i, job.MaxShardsPerNode)
+		}
+		for _, expr := range job.Constraints {
+			if _, err := ParseLabelExpr(expr); err != nil {
+				return fmt.Errorf("jobs[%d].constraints: %w", i, err)
+			}
+		}
+		for _, expr := range job.Prefer {
+			if _, err := ParseLabelExpr(expr); err != nil {
+				return fmt.Errorf("jobs[%d].prefer: %w", i, err)
+			}
+		}
+		if job.PreferDelay < 0 {
+			return fmt.Errorf("jobs[%d].prefer_delay must be non-negative, got %v", i, job.PreferDelay)
+		}
+		if job.Placement != "" && job.Placement != PlacementLeastLoaded {
+			return fmt.Errorf("jobs[%d].placement must be %q if set, got %q", i, PlacementLeastLoaded, job.Placement)
+		}
+		if job.DriftPolicy != "" && !validDriftPolicy(job.DriftPolicy) {
+			return fmt.Errorf("jobs[%d].drift_policy must be %q, %q or %q if set, got %q", i, DriftWarn, DriftRefuseToRun, DriftNewestWins, job.DriftPolicy)
+		}
 	}
 
 	return nil
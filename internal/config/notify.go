@@ -0,0 +1,171 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// Notifier types for NotifierConfig.Type.
+const (
+	NotifierWebhook = "webhook"
+	NotifierSlack   = "slack" // also Mattermost and other Slack-compatible webhooks
+	NotifierEmail   = "email"
+)
+
+// Notification events a job can subscribe a notifier to.
+const (
+	EventSuccess  = "success"
+	EventFailure  = "failure" // any failed run, including timeouts
+	EventTimeout  = "timeout"
+	EventLockLost = "lock_lost"
+	// EventSlow is sent when a run is still going after warn_after.
+	EventSlow = "slow"
+	// EventRecovered is sent on the first success after an alert fired,
+	// with alert_after_failures set.
+	EventRecovered = "recovered"
+	// EventOverdue is sent when a job has not succeeded within
+	// expect_success_within.
+	EventOverdue = "overdue"
+)
+
+// Notifier defaults.
+const (
+	DefaultNotifierTimeout = 10 * time.Second
+	DefaultNotifierRetries = 3
+)
+
+// NotifierConfig defines a named notification channel.
+type NotifierConfig struct {
+	Name string `koanf:"name"`
+	// Type is "webhook", "slack" or "email".
+	Type string `koanf:"type"`
+
+	// URL is where webhook and slack notifications are posted.
+	URL string `koanf:"url"`
+	// Headers are added to webhook requests.
+	Headers map[string]string `koanf:"headers"`
+	// Secret, if set, signs webhook bodies with HMAC-SHA256 in the
+	// X-Cronlock-Signature header.
+	Secret string `koanf:"secret"`
+
+	// Channel and Username override the slack webhook's defaults.
+	Channel  string `koanf:"channel"`
+	Username string `koanf:"username"`
+
+	// SMTP configures email notifications.
+	SMTP SMTPConfig `koanf:"smtp"`
+
+	// Timeout bounds each delivery attempt (default 10s).
+	Timeout time.Duration `koanf:"timeout"`
+	// Retries is how many times a failed delivery is retried (default 3).
+	Retries *int `koanf:"retries"`
+}
+
+// SMTPConfig contains the settings of an email notifier.
+type SMTPConfig struct {
+	// Host and Port of the SMTP server. STARTTLS is used if offered.
+	Host string `koanf:"host"`
+	Port int    `koanf:"port"`
+	// Username and Password authenticate with PLAIN auth, if set.
+	Username string   `koanf:"username"`
+	Password string   `koanf:"password"`
+	From     string   `koanf:"from"`
+	To       []string `koanf:"to"`
+}
+
+// NotifyConfig subscribes a notifier to some of a job's events.
+type NotifyConfig struct {
+	// Notifier is the name of a notifier in the notifiers section.
+	Notifier string `koanf:"notifier"`
+	// Events are the events sent: success, failure, timeout, lock_lost,
+	// slow, recovered and overdue. Defaults to failure, recovered and
+	// overdue.
+	Events []string `koanf:"events"`
+}
+
+// Matches reports whether the subscription includes event. A failure
+// subscription includes timeouts, which are failures too.
+func (n NotifyConfig) Matches(event string) bool {
+	events := n.Events
+	if len(events) == 0 {
+		events = []string{EventFailure, EventRecovered, EventOverdue}
+	}
+	if slices.Contains(events, event) {
+		return true
+	}
+	return event == EventTimeout && slices.Contains(events, EventFailure)
+}
+
+// RetryCount returns how many times a failed delivery is retried.
+func (n NotifierConfig) RetryCount() int {
+	if n.Retries == nil {
+		return DefaultNotifierRetries
+	}
+	return *n.Retries
+}
+
+// validateNotifiers checks the notifiers section and returns the names of
+// the notifiers defined.
+func validateNotifiers(notifiers []NotifierConfig) (map[string]bool, error) {
+	names := make(map[string]bool, len(notifiers))
+	for i, n := range notifiers {
+		if n.Name == "" {
+			return nil, fmt.Errorf("notifiers[%d].name is required", i)
+		}
+		if names[n.Name] {
+			return nil, fmt.Errorf("notifiers[%d].name %q is a duplicate", i, n.Name)
+		}
+		names[n.Name] = true
+
+		switch n.Type {
+		case NotifierWebhook, NotifierSlack:
+			u, err := url.Parse(n.URL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, fmt.Errorf("notifiers[%d].url must be an http or https URL, got %q", i, n.URL)
+			}
+		case NotifierEmail:
+			if n.SMTP.Host == "" {
+				return nil, fmt.Errorf("notifiers[%d].smtp.host is required", i)
+			}
+			if n.SMTP.Port < 0 || n.SMTP.Port > 65535 {
+				return nil, fmt.Errorf("notifiers[%d].smtp.port must be between 0 and 65535, got %d", i, n.SMTP.Port)
+			}
+			if n.SMTP.From == "" {
+				return nil, fmt.Errorf("notifiers[%d].smtp.from is required", i)
+			}
+			if len(n.SMTP.To) == 0 {
+				return nil, fmt.Errorf("notifiers[%d].smtp.to is required", i)
+			}
+		default:
+			return nil, fmt.Errorf("notifiers[%d].type must be %q, %q or %q, got %q", i, NotifierWebhook, NotifierSlack, NotifierEmail, n.Type)
+		}
+
+		if n.Timeout < 0 {
+			return nil, fmt.Errorf("notifiers[%d].timeout must be non-negative, got %v", i, n.Timeout)
+		}
+		if n.RetryCount() < 0 {
+			return nil, fmt.Errorf("notifiers[%d].retries must be non-negative, got %d", i, n.RetryCount())
+		}
+	}
+	return names, nil
+}
+
+// validateNotify checks a job's notify subscriptions against the notifiers
+// defined; nil notifiers skips checking the names.
+func validateNotify(notify []NotifyConfig, notifiers map[string]bool) error {
+	for i, n := range notify {
+		if notifiers != nil && !notifiers[n.Notifier] {
+			return fmt.Errorf("notify[%d].notifier %q is not defined in notifiers", i, n.Notifier)
+		}
+		for _, event := range n.Events {
+			switch event {
+			case EventSuccess, EventFailure, EventTimeout, EventLockLost, EventSlow, EventRecovered, EventOverdue:
+			default:
+				return fmt.Errorf("notify[%d].events: unknown event %q, use success, failure, timeout, lock_lost, slow, recovered or overdue", i, event)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import "fmt"
+
+// OTLP protocols for OTelConfig.Protocol.
+const (
+	OTelProtocolGRPC = "grpc"
+	OTelProtocolHTTP = "http/protobuf"
+)
+
+// OTelConfig configures OpenTelemetry tracing of job runs, exported over
+// OTLP.
+type OTelConfig struct {
+	// Enabled turns tracing on.
+	Enabled bool `koanf:"enabled"`
+	// Endpoint is the collector's host:port, or a URL for http/protobuf.
+	// Empty uses OTEL_EXPORTER_OTLP_ENDPOINT, falling back to localhost on
+	// the protocol's default port.
+	Endpoint string `koanf:"endpoint"`
+	// Protocol is "grpc" (default) or "http/protobuf".
+	Protocol string `koanf:"protocol"`
+	// Insecure disables TLS to the collector.
+	Insecure bool `koanf:"insecure"`
+	// Headers are sent with every export, e.g. for authentication.
+	Headers map[string]string `koanf:"headers"`
+	// ServiceName is the service.name resource attribute (default "cronlock").
+	ServiceName string `koanf:"service_name"`
+	// SampleRatio is the fraction of runs traced, from 0 to 1 (default 1).
+	SampleRatio float64 `koanf:"sample_ratio"`
+}
+
+// validate checks the otel section.
+func (o OTelConfig) validate() error {
+	switch o.Protocol {
+	case OTelProtocolGRPC, OTelProtocolHTTP:
+	default:
+		return fmt.Errorf("otel.protocol must be %q or %q, got %q", OTelProtocolGRPC, OTelProtocolHTTP, o.Protocol)
+	}
+	if o.SampleRatio < 0 || o.SampleRatio > 1 {
+		return fmt.Errorf("otel.sample_ratio must be between 0 and 1, got %v", o.SampleRatio)
+	}
+	if o.Enabled && o.ServiceName == "" {
+		return fmt.Errorf("otel.service_name is required")
+	}
+	return nil
+}
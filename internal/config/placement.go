@@ -0,0 +1,131 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// labelKeyPattern restricts label keys to a conservative character set.
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// labelOp is the comparison a LabelExpr performs.
+type labelOp int
+
+const (
+	opEquals labelOp = iota
+	opNotEquals
+	opExists
+	opNotExists
+)
+
+// LabelExpr is a placement expression matched against node labels.
+// Supported forms:
+//
+//	key=value   label is present and equal to value
+//	key!=value  label is absent or not equal to value
+//	key         label is present
+//	!key        label is absent
+type LabelExpr struct {
+	Key   string
+	Value string
+	op    labelOp
+}
+
+// ParseLabelExpr parses a placement expression.
+func ParseLabelExpr(s string) (LabelExpr, error) {
+	s = strings.TrimSpace(s)
+
+	var expr LabelExpr
+	switch {
+	case strings.Contains(s, "!="):
+		key, value, _ := strings.Cut(s, "!=")
+		expr = LabelExpr{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), op: opNotEquals}
+	case strings.Contains(s, "="):
+		key, value, _ := strings.Cut(s, "=")
+		expr = LabelExpr{Key: strings.TrimSpace(key), Value: strings.TrimSpace(value), op: opEquals}
+	case strings.HasPrefix(s, "!"):
+		expr = LabelExpr{Key: strings.TrimSpace(s[1:]), op: opNotExists}
+	default:
+		expr = LabelExpr{Key: s, op: opExists}
+	}
+
+	if !labelKeyPattern.MatchString(expr.Key) {
+		return LabelExpr{}, fmt.Errorf("invalid label expression %q", s)
+	}
+	return expr, nil
+}
+
+// Matches reports whether the labels satisfy the expression.
+func (e LabelExpr) Matches(labels map[string]string) bool {
+	value, ok := labels[e.Key]
+	switch e.op {
+	case opEquals:
+		return ok && value == e.Value
+	case opNotEquals:
+		return !ok || value != e.Value
+	case opExists:
+		return ok
+	default:
+		return !ok
+	}
+}
+
+// String returns the expression in its configuration form.
+func (e LabelExpr) String() string {
+	switch e.op {
+	case opEquals:
+		return e.Key + "=" + e.Value
+	case opNotEquals:
+		return e.Key + "!=" + e.Value
+	case opExists:
+		return e.Key
+	default:
+		return "!" + e.Key
+	}
+}
+
+// matchAll reports whether the labels satisfy every expression.
+// Expressions that fail to parse never match; Load rejects them up front.
+func matchAll(exprs []string, labels map[string]string) bool {
+	for _, s := range exprs {
+		expr, err := ParseLabelExpr(s)
+		if err != nil || !expr.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// CanRunOn reports whether a node with the given labels satisfies the job's constraints.
+func (j JobConfig) CanRunOn(labels map[string]string) bool {
+	return matchAll(j.Constraints, labels)
+}
+
+// Prefers reports whether a node with the given labels matches the job's
+// preferences. Jobs without preferences prefer every node.
+func (j JobConfig) Prefers(labels map[string]string) bool {
+	return matchAll(j.Prefer, labels)
+}
+
+// UnplaceableJobs returns the names of enabled jobs whose constraints no
+// node in the given label sets can satisfy.
+func UnplaceableJobs(jobs []JobConfig, nodeLabels []map[string]string) []string {
+	var names []string
+	for _, job := range jobs {
+		if !job.IsEnabled() {
+			continue
+		}
+		placeable := false
+		for _, labels := range nodeLabels {
+			if job.CanRunOn(labels) {
+				placeable = true
+				break
+			}
+		}
+		if !placeable {
+			names = append(names, job.Name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,55 @@
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// overlapSamples is how many consecutive fires are compared when looking
+// for the shortest interval of a schedule. It covers a week of a schedule
+// firing every 20 minutes, enough to include gaps such as nights and weekends.
+const overlapSamples = 500
+
+// FireTimes returns the next n times schedule fires after from, in from's
+// location unless the schedule sets its own with CRON_TZ=. It returns fewer
+// times if the schedule stops firing, and none for schedules that can never
+// fire, such as "0 0 31 2 *".
+func FireTimes(schedule cron.Schedule, from time.Time, n int) []time.Time {
+	var times []time.Time
+	for t := from; len(times) < n; {
+		t = schedule.Next(t)
+		if t.IsZero() {
+			break
+		}
+		times = append(times, t)
+	}
+	return times
+}
+
+// MinInterval returns the shortest gap between consecutive times, or 0 for
+// fewer than two times.
+func MinInterval(times []time.Time) time.Duration {
+	var shortest time.Duration
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+	}
+	return shortest
+}
+
+// Overlaps reports whether the job's timeout is longer than the shortest
+// interval between its fires after from. Such a job routinely still holds
+// its lock at the next fire, so that run is skipped. Jobs without a timeout
+// never overlap by this measure.
+func (j JobConfig) Overlaps(from time.Time, loc *time.Location) (interval time.Duration, overlaps bool, err error) {
+	schedule, err := ParseSchedule(j.Schedule)
+	if err != nil {
+		return 0, false, fmt.Errorf("job %s: invalid schedule %q: %w", j.Name, j.Schedule, err)
+	}
+
+	interval = MinInterval(FireTimes(schedule, from.In(loc), overlapSamples))
+	return interval, j.Timeout > 0 && interval > 0 && j.Timeout > interval, nil
+}
@@ -0,0 +1,214 @@
+// Package configstore stores versioned job configuration documents in Redis,
+// for nodes that load their jobs from the cluster instead of their
+// configuration file.
+package configstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// historyLength is how many versions are kept.
+const historyLength = 100
+
+// pollInterval is how often Watch checks for a new version besides being
+// notified, so a node catches up on updates published while it was
+// disconnected.
+const pollInterval = 30 * time.Second
+
+// ErrVersionNotFound is returned for a version that was never pushed or has
+// been trimmed from the history.
+var ErrVersionNotFound = errors.New("config version not found")
+
+// Lua script for pushing a document: number it with the next version, store
+// it, drop the version that falls out of the history and announce the new
+// version. Returns the version.
+var pushScript = redis.NewScript(`
+local version = redis.call("incr", KEYS[1])
+redis.call("hset", KEYS[2], version, ARGV[1])
+local trimmed = version - tonumber(ARGV[2])
+if trimmed > 0 then
+	redis.call("hdel", KEYS[2], trimmed)
+end
+redis.call("publish", KEYS[3], version)
+return version
+`)
+
+// Document is one version of the job configuration: the "jobs" list of a
+// configuration file, kept as pushed.
+type Document struct {
+	// Version numbers documents from 1; the newest is current.
+	Version int    `json:"-"`
+	Format  string `json:"format"` // "yaml" or "toml"
+	Data    string `json:"data"`
+	// Hash identifies the jobs, as config.JobsHash.
+	Hash     string    `json:"hash"`
+	Jobs     int       `json:"jobs"`
+	PushedAt time.Time `json:"pushed_at"`
+	PushedBy string    `json:"pushed_by"` // user@host
+	// RollbackOf is the version a rollback restored.
+	RollbackOf int `json:"rollback_of,omitempty"`
+}
+
+// Store reads and writes job configuration documents under
+// {prefix}config:*.
+type Store struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewStore creates a Store.
+func NewStore(client *redis.Client, keyPrefix string) *Store {
+	return &Store{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// seqKey returns the key holding the newest version.
+func (s *Store) seqKey() string {
+	return s.keyPrefix + "config:version"
+}
+
+// versionsKey returns the hash holding each kept version's document.
+func (s *Store) versionsKey() string {
+	return s.keyPrefix + "config:versions"
+}
+
+// updatesChannel returns the channel new versions are published on.
+func (s *Store) updatesChannel() string {
+	return s.keyPrefix + "config:updates"
+}
+
+// Push stores doc as the new current version, which it returns with its
+// version set, and notifies watching nodes.
+func (s *Store) Push(ctx context.Context, doc Document) (Document, error) {
+	if doc.PushedAt.IsZero() {
+		doc.PushedAt = time.Now().UTC()
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	keys := []string{s.seqKey(), s.versionsKey(), s.updatesChannel()}
+	version, err := pushScript.Run(ctx, s.client, keys, data, historyLength).Int()
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to push config: %w", err)
+	}
+	doc.Version = version
+	return doc, nil
+}
+
+// Current returns the current version, or ok false if none was pushed.
+func (s *Store) Current(ctx context.Context) (doc Document, ok bool, err error) {
+	version, err := s.client.Get(ctx, s.seqKey()).Int()
+	if errors.Is(err, redis.Nil) {
+		return Document{}, false, nil
+	}
+	if err != nil {
+		return Document{}, false, fmt.Errorf("failed to read config version: %w", err)
+	}
+	doc, err = s.Get(ctx, version)
+	if err != nil {
+		return Document{}, false, err
+	}
+	return doc, true, nil
+}
+
+// Get returns a version, or ErrVersionNotFound.
+func (s *Store) Get(ctx context.Context, version int) (Document, error) {
+	data, err := s.client.HGet(ctx, s.versionsKey(), strconv.Itoa(version)).Result()
+	if errors.Is(err, redis.Nil) {
+		return Document{}, fmt.Errorf("%w: %d", ErrVersionNotFound, version)
+	}
+	if err != nil {
+		return Document{}, fmt.Errorf("failed to read config version %d: %w", version, err)
+	}
+	return decode(version, data)
+}
+
+// History returns the kept versions, newest first.
+func (s *Store) History(ctx context.Context) ([]Document, error) {
+	items, err := s.client.HGetAll(ctx, s.versionsKey()).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config history: %w", err)
+	}
+
+	docs := make([]Document, 0, len(items))
+	for field, data := range items {
+		version, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid config version %q", field)
+		}
+		doc, err := decode(version, data)
+		if err != nil {
+			return nil, err
+		}
+		docs = append(docs, doc)
+	}
+	slices.SortFunc(docs, func(a, b Document) int { return b.Version - a.Version })
+	return docs, nil
+}
+
+// Rollback pushes a copy of an earlier version as the new current version,
+// keeping the history linear, and returns it.
+func (s *Store) Rollback(ctx context.Context, version int, pushedBy string) (Document, error) {
+	doc, err := s.Get(ctx, version)
+	if err != nil {
+		return Document{}, err
+	}
+	doc.PushedAt = time.Time{}
+	doc.PushedBy = pushedBy
+	doc.RollbackOf = version
+	return s.Push(ctx, doc)
+}
+
+// Watch calls apply with each version newer than after, in order, until
+// ctx is canceled. It is notified of new versions as they are pushed and
+// also checks every pollInterval. Only the newest version is applied when
+// several were pushed since the last check.
+func (s *Store) Watch(ctx context.Context, after int, logger *slog.Logger, apply func(Document)) {
+	sub := s.client.Subscribe(ctx, s.updatesChannel())
+	defer sub.Close()
+	updates := sub.Channel()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		doc, ok, err := s.Current(ctx)
+		switch {
+		case err != nil && ctx.Err() == nil:
+			logger.Warn("failed to check for a new config version", "error", err)
+		case ok && doc.Version > after:
+			apply(doc)
+			after = doc.Version
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-updates:
+		case <-ticker.C:
+		}
+	}
+}
+
+// decode parses a stored document.
+func decode(version int, data string) (Document, error) {
+	var doc Document
+	if err := json.Unmarshal([]byte(data), &doc); err != nil {
+		return Document{}, fmt.Errorf("failed to decode config version %d: %w", version, err)
+	}
+	doc.Version = version
+	return doc, nil
+}
@@ -0,0 +1,169 @@
+package configstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	t.Cleanup(func() {
+		client.Close()
+		s.Close()
+	})
+
+	return s, client
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestStore_PushAndCurrent(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewStore(client, "test:")
+	ctx := context.Background()
+
+	if _, ok, err := store.Current(ctx); err != nil || ok {
+		t.Fatalf("Current() = _, %v, %v, want no version", ok, err)
+	}
+
+	for i, data := range []string{"jobs: []\n", "jobs:\n  - name: backup\n"} {
+		doc, err := store.Push(ctx, Document{Format: "yaml", Data: data, Hash: "abc", PushedBy: "alice@host"})
+		if err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+		if doc.Version != i+1 || doc.PushedAt.IsZero() {
+			t.Errorf("Push() = version %d, pushed at %v, want version %d and the push time", doc.Version, doc.PushedAt, i+1)
+		}
+	}
+
+	doc, ok, err := store.Current(ctx)
+	if err != nil || !ok {
+		t.Fatalf("Current() = _, %v, %v, want a version", ok, err)
+	}
+	if doc.Version != 2 || doc.Data != "jobs:\n  - name: backup\n" || doc.PushedBy != "alice@host" {
+		t.Errorf("Current() = %+v, want version 2 as pushed", doc)
+	}
+}
+
+func TestStore_HistoryTrimmed(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewStore(client, "test:")
+	ctx := context.Background()
+
+	for range historyLength + 5 {
+		if _, err := store.Push(ctx, Document{Format: "yaml", Data: "jobs: []\n"}); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	}
+
+	history, err := store.History(ctx)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(history) != historyLength {
+		t.Fatalf("len(History()) = %d, want %d", len(history), historyLength)
+	}
+	if history[0].Version != historyLength+5 || history[len(history)-1].Version != 6 {
+		t.Errorf("History() spans versions %d to %d, want %d to 6", history[0].Version, history[len(history)-1].Version, historyLength+5)
+	}
+
+	if _, err := store.Get(ctx, 5); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Get(5) error = %v, want ErrVersionNotFound", err)
+	}
+}
+
+func TestStore_Rollback(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewStore(client, "test:")
+	ctx := context.Background()
+
+	for _, data := range []string{"jobs: [a]\n", "jobs: [b]\n"} {
+		if _, err := store.Push(ctx, Document{Format: "yaml", Data: data, PushedBy: "alice@host"}); err != nil {
+			t.Fatalf("Push() error = %v", err)
+		}
+	}
+
+	doc, err := store.Rollback(ctx, 1, "bob@host")
+	if err != nil {
+		t.Fatalf("Rollback() error = %v", err)
+	}
+	if doc.Version != 3 || doc.RollbackOf != 1 || doc.Data != "jobs: [a]\n" || doc.PushedBy != "bob@host" {
+		t.Errorf("Rollback() = %+v, want version 3 restoring version 1", doc)
+	}
+	if current, _, _ := store.Current(ctx); current.Version != 3 {
+		t.Errorf("Current().Version = %d, want 3", current.Version)
+	}
+
+	if _, err := store.Rollback(ctx, 9, "bob@host"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("Rollback(9) error = %v, want ErrVersionNotFound", err)
+	}
+}
+
+func TestStore_Watch(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewStore(client, "test:")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if _, err := store.Push(ctx, Document{Format: "yaml", Data: "jobs: []\n"}); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	applied := make(chan int, 10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		store.Watch(ctx, 1, testLogger(), func(doc Document) {
+			applied <- doc.Version
+		})
+	}()
+
+	// Wait for the subscription, so the push below is published to it
+	deadline := time.Now().Add(time.Second)
+	for {
+		n, err := client.PubSubNumSub(ctx, store.updatesChannel()).Result()
+		if err == nil && n[store.updatesChannel()] == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Watch() did not subscribe to config updates")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if _, err := store.Push(ctx, Document{Format: "yaml", Data: "jobs: [a]\n"}); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	select {
+	case v := <-applied:
+		if v != 2 {
+			t.Errorf("applied version %d, want 2", v)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Watch() did not apply the pushed version")
+	}
+
+	cancel()
+	<-done
+	if len(applied) != 0 {
+		t.Errorf("Watch() applied %d more versions, want none", len(applied))
+	}
+}
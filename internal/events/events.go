@@ -0,0 +1,78 @@
+// Package events publishes the lifecycle events of nodes and job runs to a
+// Redis Stream shared by the cluster, and reads them back.
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// SchemaVersion is the version of the Event schema, in each event's "v"
+// field. Fields may be added within a version; removing or changing the
+// meaning of one bumps it.
+const SchemaVersion = 1
+
+// Event types.
+const (
+	// TypeScheduled is published by every node when a job's schedule fires.
+	TypeScheduled = "scheduled"
+	// TypeLockAcquired is published when the node wins a job or shard lock.
+	TypeLockAcquired = "lock_acquired"
+	// TypeLockSkipped is published when the node doesn't run a fired job,
+	// with the reason: lock_held, paused, config_drift, draining or
+	// max_concurrent_jobs.
+	TypeLockSkipped = "lock_skipped"
+	// TypeStarted is published when the job's command starts.
+	TypeStarted = "started"
+	// TypeExtended and TypeExtendFailed report each lock renewal.
+	TypeExtended     = "extended"
+	TypeExtendFailed = "extend_failed"
+	// TypeFinished is published when the command exits, with its outcome.
+	TypeFinished = "finished"
+	// TypeHookFailed is published when a hook fails or times out.
+	TypeHookFailed = "hook_failed"
+	// TypeReleased is published when the node releases a lock.
+	TypeReleased = "released"
+	// TypeNodeStarted and TypeNodeStopping report a node's scheduler
+	// starting and stopping.
+	TypeNodeStarted  = "node_started"
+	TypeNodeStopping = "node_stopping"
+)
+
+// Event is one entry of the event stream. Fields that don't apply to an
+// event's type are omitted from its JSON.
+type Event struct {
+	// ID is the stream entry ID, set on events read from the stream.
+	ID string `json:"id,omitempty"`
+	// Version is the schema version, SchemaVersion when published.
+	Version int       `json:"v"`
+	Type    string    `json:"type"`
+	Time    time.Time `json:"time"`
+	NodeID  string    `json:"node_id"`
+
+	// Job, RunID and ScheduledAt identify the run; they are empty for node
+	// events, and RunID is set once the node holds the run's lock.
+	Job         string    `json:"job,omitempty"`
+	RunID       string    `json:"run_id,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at,omitzero"`
+
+	// Lock is the lock acquired, renewed or released, e.g. "backup" or
+	// "backup:shard:2" for a shard.
+	Lock string `json:"lock,omitempty"`
+	// Hook is the hook that failed, e.g. "on_success".
+	Hook string `json:"hook,omitempty"`
+	// Reason is why a fired job was skipped.
+	Reason string `json:"reason,omitempty"`
+
+	// Outcome (success, failure or timeout), ExitCode and Duration (in
+	// seconds) describe a finished command or failed hook.
+	Outcome  string  `json:"outcome,omitempty"`
+	ExitCode *int    `json:"exit_code,omitempty"`
+	Duration float64 `json:"duration,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Sink stores published events.
+type Sink interface {
+	Add(ctx context.Context, e Event) error
+}
@@ -0,0 +1,259 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	t.Cleanup(func() {
+		client.Close()
+		s.Close()
+	})
+
+	return s, client
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestStream_AddAndLast(t *testing.T) {
+	s, client := setupMiniredis(t)
+	stream := NewStream(client, "test:", 0)
+	ctx := context.Background()
+
+	exitCode := 3
+	added := []Event{
+		{Version: SchemaVersion, Type: TypeStarted, NodeID: "node-1", Job: "backup", RunID: "run-1"},
+		{Version: SchemaVersion, Type: TypeFinished, NodeID: "node-1", Job: "backup", RunID: "run-1", Outcome: "failure", ExitCode: &exitCode, Duration: 1.5},
+		{Version: SchemaVersion, Type: TypeStarted, NodeID: "node-2", Job: "report"},
+	}
+	for _, e := range added {
+		if err := stream.Add(ctx, e); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+	if !s.Exists("test:events") {
+		t.Fatal("stream key test:events does not exist")
+	}
+
+	got, err := stream.Last(ctx, "+", 10, nil)
+	if err != nil {
+		t.Fatalf("Last() error = %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("Last() returned %d events, want 3", len(got))
+	}
+	if got[0].Type != TypeStarted || got[1].Type != TypeFinished || got[2].Job != "report" {
+		t.Errorf("Last() = %+v, want the events oldest first", got)
+	}
+	if got[1].ID == "" || got[1].ExitCode == nil || *got[1].ExitCode != 3 || got[1].Outcome != "failure" {
+		t.Errorf("Last()[1] = %+v, want the finished event with its ID", got[1])
+	}
+
+	got, err = stream.Last(ctx, "+", 1, func(e Event) bool { return e.Job == "backup" })
+	if err != nil {
+		t.Fatalf("Last() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Type != TypeFinished {
+		t.Errorf("Last(1, backup) = %+v, want the finished event", got)
+	}
+}
+
+func TestStream_Last_ManyBatches(t *testing.T) {
+	_, client := setupMiniredis(t)
+	stream := NewStream(client, "test:", 0)
+	ctx := context.Background()
+
+	for i := range 2*scanBatch + 10 {
+		job := "noise"
+		if i%50 == 0 {
+			job = "backup"
+		}
+		if err := stream.Add(ctx, Event{Type: TypeScheduled, Job: job}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	got, err := stream.Last(ctx, "+", 10, func(e Event) bool { return e.Job == "backup" })
+	if err != nil {
+		t.Fatalf("Last() error = %v", err)
+	}
+	if len(got) != 5 {
+		t.Errorf("Last() returned %d events, want 5", len(got))
+	}
+}
+
+func TestStream_MaxLen(t *testing.T) {
+	s, client := setupMiniredis(t)
+	stream := NewStream(client, "test:", 5)
+	ctx := context.Background()
+
+	for range 20 {
+		if err := stream.Add(ctx, Event{Type: TypeScheduled, Job: "backup"}); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+	}
+
+	entries, err := s.Stream("test:events")
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if len(entries) > 5 {
+		t.Errorf("stream has %d events, want at most 5", len(entries))
+	}
+}
+
+func TestStream_Read(t *testing.T) {
+	_, client := setupMiniredis(t)
+	stream := NewStream(client, "test:", 0)
+	ctx := context.Background()
+
+	if err := stream.Add(ctx, Event{Type: TypeScheduled, Job: "old"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	last, err := stream.LastID(ctx)
+	if err != nil {
+		t.Fatalf("LastID() error = %v", err)
+	}
+
+	got, err := stream.Read(ctx, last, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("Read() = %+v, want no events", got)
+	}
+
+	if err := stream.Add(ctx, Event{Type: TypeScheduled, Job: "new"}); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	got, err = stream.Read(ctx, last, 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if len(got) != 1 || got[0].Job != "new" {
+		t.Errorf("Read() = %+v, want the new event", got)
+	}
+}
+
+func TestStream_LastID_Empty(t *testing.T) {
+	_, client := setupMiniredis(t)
+	stream := NewStream(client, "test:", 0)
+
+	id, err := stream.LastID(context.Background())
+	if err != nil {
+		t.Fatalf("LastID() error = %v", err)
+	}
+	if id != "0-0" {
+		t.Errorf("LastID() = %q, want %q", id, "0-0")
+	}
+}
+
+// recordingSink records added events, optionally blocking until released.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []Event
+	block  chan struct{}
+}
+
+func (s *recordingSink) Add(ctx context.Context, e Event) error {
+	if s.block != nil {
+		select {
+		case <-s.block:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+func (s *recordingSink) recorded() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Event(nil), s.events...)
+}
+
+func TestPublisher_Publish(t *testing.T) {
+	sink := &recordingSink{}
+	p := NewPublisher(sink, "node-1", testLogger())
+
+	for _, typ := range []string{TypeScheduled, TypeLockAcquired, TypeStarted, TypeFinished, TypeReleased} {
+		p.Publish(Event{Type: typ, Job: "backup"})
+	}
+	p.Close(context.Background())
+
+	got := sink.recorded()
+	if len(got) != 5 {
+		t.Fatalf("published %d events, want 5", len(got))
+	}
+	for i, typ := range []string{TypeScheduled, TypeLockAcquired, TypeStarted, TypeFinished, TypeReleased} {
+		if got[i].Type != typ {
+			t.Errorf("event %d type = %q, want %q", i, got[i].Type, typ)
+		}
+	}
+	e := got[0]
+	if e.Version != SchemaVersion || e.NodeID != "node-1" || e.Time.IsZero() {
+		t.Errorf("event = %+v, want version, node and time filled in", e)
+	}
+
+	// Publishing after Close is a no-op.
+	p.Publish(Event{Type: TypeScheduled})
+	if n := len(sink.recorded()); n != 5 {
+		t.Errorf("published %d events after Close, want 5", n)
+	}
+}
+
+func TestPublisher_DropsWhenFull(t *testing.T) {
+	sink := &recordingSink{block: make(chan struct{})}
+	p := NewPublisher(sink, "node-1", testLogger())
+
+	for range defaultQueueSize + 10 {
+		p.Publish(Event{Type: TypeScheduled})
+	}
+	close(sink.block)
+	p.Close(context.Background())
+
+	if n := len(sink.recorded()); n > defaultQueueSize+1 {
+		t.Errorf("published %d events, want at most %d", n, defaultQueueSize+1)
+	}
+}
+
+func TestPublisher_CloseAbandons(t *testing.T) {
+	sink := &recordingSink{block: make(chan struct{})}
+	p := NewPublisher(sink, "node-1", testLogger())
+	p.Publish(Event{Type: TypeScheduled})
+	p.Publish(Event{Type: TypeScheduled})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	p.Close(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Close() took %v, want it to give up when ctx is done", elapsed)
+	}
+	if n := len(sink.recorded()); n != 0 {
+		t.Errorf("published %d events, want 0", n)
+	}
+}
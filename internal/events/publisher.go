@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Publisher defaults.
+const (
+	defaultQueueSize  = 1000
+	defaultAddTimeout = 5 * time.Second
+)
+
+// Publisher adds events to a Sink in the background, in the order they were
+// published, so an unreachable Redis never delays a job. Events that don't
+// fit in the queue are dropped.
+type Publisher struct {
+	sink   Sink
+	nodeID string
+	logger *slog.Logger
+
+	queue  chan Event
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewPublisher creates a Publisher for the node and starts its worker.
+// Close stops it.
+func NewPublisher(sink Sink, nodeID string, logger *slog.Logger) *Publisher {
+	p := &Publisher{
+		sink:   sink,
+		nodeID: nodeID,
+		logger: logger,
+		queue:  make(chan Event, defaultQueueSize),
+		done:   make(chan struct{}),
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	go p.work()
+	return p
+}
+
+// Publish queues the event without blocking, filling in its version, node
+// and, if unset, time.
+func (p *Publisher) Publish(e Event) {
+	e.Version = SchemaVersion
+	e.NodeID = p.nodeID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return
+	}
+	select {
+	case p.queue <- e:
+	default:
+		p.logger.Warn("event queue is full, dropping event", "type", e.Type, "job", e.Job)
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be added until
+// ctx is done, then abandons the rest.
+func (p *Publisher) Close(ctx context.Context) {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.mu.Unlock()
+
+	select {
+	case <-p.done:
+	case <-ctx.Done():
+		p.logger.Warn("abandoning unpublished events", "queued", len(p.queue))
+		p.cancel()
+		<-p.done
+	}
+	p.cancel()
+}
+
+// work adds queued events until the queue is closed and drained.
+func (p *Publisher) work() {
+	defer close(p.done)
+	for e := range p.queue {
+		if p.ctx.Err() != nil {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(p.ctx, defaultAddTimeout)
+		err := p.sink.Add(ctx, e)
+		cancel()
+		if err != nil {
+			p.logger.Error("failed to publish event", "type", e.Type, "job", e.Job, "error", err)
+		}
+	}
+}
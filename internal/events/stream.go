@@ -0,0 +1,140 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// eventField is the stream entry field holding the event's JSON.
+const eventField = "event"
+
+// scanBatch is how many entries Last reads from Redis at a time.
+const scanBatch = 100
+
+// Stream is the cluster's event stream, stored in Redis under
+// {prefix}events.
+type Stream struct {
+	client *redis.Client
+	key    string
+	maxLen int64
+}
+
+// NewStream creates a Stream. Adding an event trims the stream to about
+// maxLen events; 0 leaves it untrimmed, for readers.
+func NewStream(client *redis.Client, keyPrefix string, maxLen int64) *Stream {
+	return &Stream{
+		client: client,
+		key:    keyPrefix + "events",
+		maxLen: maxLen,
+	}
+}
+
+// Add implements Sink.Add, appending the event to the stream.
+func (s *Stream) Add(ctx context.Context, e Event) error {
+	e.ID = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	args := &redis.XAddArgs{
+		Stream: s.key,
+		Values: []any{eventField, data},
+	}
+	if s.maxLen > 0 {
+		args.MaxLen = s.maxLen
+		args.Approx = true
+	}
+	if err := s.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to add event: %w", err)
+	}
+	return nil
+}
+
+// LastID returns the ID of the newest event, or "0-0" if the stream is
+// empty. Reading after it returns only events added since.
+func (s *Stream) LastID(ctx context.Context) (string, error) {
+	msgs, err := s.client.XRevRangeN(ctx, s.key, "+", "-", 1).Result()
+	if err != nil {
+		return "", fmt.Errorf("failed to read events: %w", err)
+	}
+	if len(msgs) == 0 {
+		return "0-0", nil
+	}
+	return msgs[0].ID, nil
+}
+
+// Last returns up to n of the newest events up to and including the one
+// with ID end, oldest first. If match is set, only events it accepts are
+// returned and counted.
+func (s *Stream) Last(ctx context.Context, end string, n int, match func(Event) bool) ([]Event, error) {
+	var events []Event
+	for len(events) < n {
+		msgs, err := s.client.XRevRangeN(ctx, s.key, end, "-", scanBatch).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read events: %w", err)
+		}
+		for _, msg := range msgs {
+			e, err := decode(msg)
+			if err != nil {
+				return nil, err
+			}
+			if match == nil || match(e) {
+				events = append(events, e)
+				if len(events) == n {
+					break
+				}
+			}
+		}
+		if len(msgs) < scanBatch {
+			break
+		}
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+	slices.Reverse(events)
+	return events, nil
+}
+
+// Read returns the events added after the one with ID after, oldest first,
+// waiting up to block for one to arrive. It returns no events if none did.
+func (s *Stream) Read(ctx context.Context, after string, block time.Duration) ([]Event, error) {
+	streams, err := s.client.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{s.key, after},
+		Count:   scanBatch,
+		Block:   block,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events: %w", err)
+	}
+
+	var events []Event
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			e, err := decode(msg)
+			if err != nil {
+				return nil, err
+			}
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// decode parses a stream entry.
+func decode(msg redis.XMessage) (Event, error) {
+	var e Event
+	data, _ := msg.Values[eventField].(string)
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		return Event{}, fmt.Errorf("failed to decode event %s: %w", msg.ID, err)
+	}
+	e.ID = msg.ID
+	return e, nil
+}
@@ -0,0 +1,82 @@
+package executor
+
+import "fmt"
+
+// capture is an io.Writer that keeps the output of a stream in memory. With
+// a limit it keeps only the first and last limit/2 bytes, so a chatty
+// command can't grow the process without bound while the start and end of
+// its output, usually the most useful parts, are still kept.
+type capture struct {
+	limit   int
+	head    []byte
+	tail    []byte
+	dropped int64
+}
+
+// newCapture creates a capture keeping at most limit bytes (0 = no limit).
+func newCapture(limit int) *capture {
+	return &capture{limit: limit}
+}
+
+// Write implements io.Writer. It never fails.
+func (c *capture) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.limit <= 0 {
+		c.head = append(c.head, p...)
+		return n, nil
+	}
+
+	headSize := c.limit / 2
+	if room := headSize - len(c.head); room > 0 {
+		take := min(room, len(p))
+		c.head = append(c.head, p[:take]...)
+		p = p[take:]
+	}
+
+	tailSize := c.limit - headSize
+	if len(p) >= tailSize {
+		c.dropped += int64(len(c.tail) + len(p) - tailSize)
+		c.tail = append(c.tail[:0], p[len(p)-tailSize:]...)
+		return n, nil
+	}
+
+	// Let the tail grow to twice its size before dropping its start, so
+	// small writes don't each move the whole buffer.
+	c.tail = append(c.tail, p...)
+	if len(c.tail) > 2*tailSize {
+		excess := len(c.tail) - tailSize
+		c.dropped += int64(excess)
+		c.tail = append(c.tail[:0], c.tail[excess:]...)
+	}
+	return n, nil
+}
+
+// kept returns the tail that is kept and how many bytes were dropped in all.
+func (c *capture) kept() ([]byte, int64) {
+	tailSize := c.limit - c.limit/2
+	if excess := len(c.tail) - tailSize; excess > 0 {
+		return c.tail[excess:], c.dropped + int64(excess)
+	}
+	return c.tail, c.dropped
+}
+
+// Truncated reports whether output was dropped from the middle.
+func (c *capture) Truncated() bool {
+	if c.limit <= 0 {
+		return false
+	}
+	_, dropped := c.kept()
+	return dropped > 0
+}
+
+// String returns the kept output, with a marker where output was dropped.
+func (c *capture) String() string {
+	if c.limit <= 0 {
+		return string(c.head)
+	}
+	tail, dropped := c.kept()
+	if dropped == 0 {
+		return string(c.head) + string(tail)
+	}
+	return fmt.Sprintf("%s\n[... %d bytes truncated ...]\n%s", c.head, dropped, tail)
+}
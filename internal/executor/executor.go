@@ -1,11 +1,12 @@
 package executor
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
 	"time"
 )
 
@@ -14,8 +15,14 @@ type Result struct {
 	ExitCode int
 	Stdout   string
 	Stderr   string
-	Duration time.Duration
-	Err      error
+	// Truncated is set when Stdout or Stderr exceeded Options.MaxCapture
+	// and output was dropped from the middle.
+	Truncated bool
+	// OOMKilled is set when the run's memory limit killed the command or
+	// one of its children.
+	OOMKilled bool
+	Duration  time.Duration
+	Err       error
 }
 
 // Success returns true if the command executed successfully (exit code 0).
@@ -23,18 +30,49 @@ func (r *Result) Success() bool {
 	return r.Err == nil && r.ExitCode == 0
 }
 
-// Executor handles shell command execution.
+// waitDelay is how long Execute waits for the output to close after the
+// command was killed.
+const waitDelay = 5 * time.Second
+
+// Executor handles command execution.
 type Executor struct {
-	shell string
+	shell        string
+	cgroupParent string
+}
+
+// Option configures an Executor.
+type Option func(*Executor)
+
+// WithShell sets the default shell for command lines, e.g. "/bin/bash" or
+// "/bin/bash -eo pipefail". Empty keeps the default.
+func WithShell(shell string) Option {
+	return func(e *Executor) {
+		if shell != "" {
+			e.shell = shell
+		}
+	}
+}
+
+// WithCgroupParent sets the cgroup v2 directory under which a cgroup is
+// created for each command with cgroup limits.
+func WithCgroupParent(dir string) Option {
+	return func(e *Executor) {
+		e.cgroupParent = dir
+	}
 }
 
-// New creates a new Executor.
-func New() *Executor {
+// New creates a new Executor. Command lines run with $SHELL, or /bin/sh if
+// it is unset, unless WithShell or Options.Shell says otherwise.
+func New(opts ...Option) *Executor {
 	shell := os.Getenv("SHELL")
 	if shell == "" {
 		shell = "/bin/sh"
 	}
-	return &Executor{shell: shell}
+	e := &Executor{shell: shell}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
 }
 
 // Execute runs a command with the given options.
@@ -42,8 +80,37 @@ func (e *Executor) Execute(ctx context.Context, opts Options) *Result {
 	start := time.Now()
 	result := &Result{}
 
-	// Create command with shell
-	cmd := exec.CommandContext(ctx, e.shell, "-c", opts.Command)
+	cmd := e.command(ctx, opts)
+	if err := setProcess(cmd, opts.Credential); err != nil {
+		result.ExitCode = -1
+		result.Err = err
+		return result
+	}
+	// Wait gives up on output still held open after the command was
+	// killed, e.g. by a daemon that left its process group.
+	cmd.WaitDelay = waitDelay
+
+	if opts.Limits.hasRlimits() {
+		if err := setRlimits(cmd, opts.Limits); err != nil {
+			result.ExitCode = -1
+			result.Err = err
+			return result
+		}
+	}
+
+	// Start the command in a cgroup of its own if it has cgroup limits
+	var cg *cgroup
+	if opts.Limits.hasCgroup() {
+		var err error
+		if cg, err = createCgroup(e.cgroupParent, opts.Limits.Cgroup, opts.Limits); err != nil {
+			result.Duration = time.Since(start)
+			result.ExitCode = -1
+			result.Err = err
+			return result
+		}
+		defer cg.remove()
+		cg.attach(cmd.SysProcAttr)
+	}
 
 	// Set working directory if specified
 	if opts.WorkDir != "" {
@@ -56,16 +123,19 @@ func (e *Executor) Execute(ctx context.Context, opts Options) *Result {
 		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", k, v))
 	}
 
-	// Capture stdout and stderr
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	// Capture stdout and stderr, copying them to any extra writers
+	stdout := newCapture(opts.MaxCapture)
+	stderr := newCapture(opts.MaxCapture)
+	cmd.Stdout = teeWriter(stdout, opts.Stdout)
+	cmd.Stderr = teeWriter(stderr, opts.Stderr)
 
 	// Run the command
 	err := cmd.Run()
 	result.Duration = time.Since(start)
 	result.Stdout = stdout.String()
 	result.Stderr = stderr.String()
+	result.Truncated = stdout.Truncated() || stderr.Truncated()
+	result.OOMKilled = cg != nil && cg.oomKilled()
 
 	if err != nil {
 		result.Err = err
@@ -79,10 +149,56 @@ func (e *Executor) Execute(ctx context.Context, opts Options) *Result {
 	return result
 }
 
+// command builds the process to run: Args directly, or Command with the shell.
+func (e *Executor) command(ctx context.Context, opts Options) *exec.Cmd {
+	if len(opts.Args) > 0 {
+		return exec.CommandContext(ctx, opts.Args[0], opts.Args[1:]...)
+	}
+
+	shell := opts.Shell
+	if shell == "" {
+		shell = e.shell
+	}
+	args := strings.Fields(shell)
+	args = append(args, "-c", opts.Command)
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+// teeWriter returns a writer copying to the capture and, if set, w.
+func teeWriter(c *capture, w io.Writer) io.Writer {
+	if w == nil {
+		return c
+	}
+	return io.MultiWriter(c, w)
+}
+
+// Credential is a user and groups to run a command as.
+type Credential struct {
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+}
+
 // Options contains execution options for a command.
 type Options struct {
+	// Command is a command line run with the shell. Ignored if Args is set.
 	Command string
+	// Args is a program and its arguments, executed without a shell.
+	Args []string
+	// Shell overrides the executor's shell for Command.
+	Shell   string
 	WorkDir string
 	Env     map[string]string
 	Timeout time.Duration
+	// Credential, if set, runs the command as another user and group.
+	Credential *Credential
+	// Limits, if set, caps the resources the command can use.
+	Limits *Limits
+	// MaxCapture limits how many bytes of each stream Result keeps, split
+	// between the start and end of the output (0 = no limit).
+	MaxCapture int
+	// Stdout and Stderr, if set, also receive the command's output as it
+	// is written.
+	Stdout io.Writer
+	Stderr io.Writer
 }
@@ -338,6 +338,28 @@ func TestExecute_Timeout(t *testing.T) {
 	}
 }
 
+func TestExecute_Timeout_KillsChildren(t *testing.T) {
+	exec := New()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// The shell forks sleep, which holds stdout open after the shell is killed
+	start := time.Now()
+	result := exec.Execute(ctx, Options{
+		Command: "sleep 10; echo done",
+		Shell:   "/bin/sh",
+	})
+	elapsed := time.Since(start)
+
+	if result.Err == nil {
+		t.Error("Execute() Err = nil, want context deadline exceeded")
+	}
+	if elapsed > 1*time.Second {
+		t.Errorf("Execute() took %v, want the shell's children killed at the timeout", elapsed)
+	}
+}
+
 func TestExecute_ContextCancellation(t *testing.T) {
 	exec := New()
 
@@ -468,3 +490,228 @@ func TestExecute_Duration(t *testing.T) {
 		t.Errorf("Execute() Duration = %v, want >= 100ms", result.Duration)
 	}
 }
+
+func TestExecute_Args(t *testing.T) {
+	exec := New()
+	ctx := context.Background()
+
+	// Arguments are passed as-is: no word splitting or expansion
+	result := exec.Execute(ctx, Options{
+		Command: "echo ignored",
+		Args:    []string{"echo", "a  b", "$HOME", "*"},
+	})
+
+	if result.Err != nil {
+		t.Fatalf("Execute() Err = %v", result.Err)
+	}
+
+	stdout := strings.TrimSpace(result.Stdout)
+	if stdout != "a  b $HOME *" {
+		t.Errorf("Execute() Stdout = %q, want %q", stdout, "a  b $HOME *")
+	}
+}
+
+func TestExecute_Args_NotFound(t *testing.T) {
+	exec := New()
+	ctx := context.Background()
+
+	result := exec.Execute(ctx, Options{
+		Args: []string{"cronlock-test-no-such-binary"},
+	})
+
+	if result.Err == nil {
+		t.Fatal("Execute() Err = nil, want error")
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("Execute() ExitCode = %d, want -1", result.ExitCode)
+	}
+}
+
+func TestExecute_Shell(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+
+	tests := []struct {
+		name     string
+		exec     *Executor
+		shell    string
+		command  string
+		expected string
+	}{
+		{
+			name:     "default shell",
+			exec:     New(),
+			command:  "echo $0",
+			expected: "/bin/sh",
+		},
+		{
+			name:     "executor shell",
+			exec:     New(WithShell("/bin/bash")),
+			command:  "echo $0",
+			expected: "/bin/bash",
+		},
+		{
+			name:     "options override executor shell",
+			exec:     New(WithShell("/bin/bash")),
+			shell:    "/bin/sh",
+			command:  "echo $0",
+			expected: "/bin/sh",
+		},
+		{
+			name:     "shell with flags",
+			exec:     New(),
+			shell:    "/bin/sh -e",
+			command:  "false; echo not reached",
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := os.Stat(strings.Fields(tt.exec.shell)[0]); err != nil {
+				t.Skipf("shell not available: %v", err)
+			}
+			result := tt.exec.Execute(context.Background(), Options{
+				Command: tt.command,
+				Shell:   tt.shell,
+			})
+			if stdout := strings.TrimSpace(result.Stdout); stdout != tt.expected {
+				t.Errorf("Execute() Stdout = %q, want %q", stdout, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCapture(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		writes        []string
+		want          string
+		wantTruncated bool
+	}{
+		{
+			name:   "no limit",
+			limit:  0,
+			writes: []string{"hello ", "world"},
+			want:   "hello world",
+		},
+		{
+			name:   "under limit",
+			limit:  10,
+			writes: []string{"abc", "def"},
+			want:   "abcdef",
+		},
+		{
+			name:   "exactly at limit",
+			limit:  10,
+			writes: []string{"0123456789"},
+			want:   "0123456789",
+		},
+		{
+			name:          "one large write",
+			limit:         10,
+			writes:        []string{"0123456789abcdefghij"},
+			want:          "01234\n[... 10 bytes truncated ...]\nfghij",
+			wantTruncated: true,
+		},
+		{
+			name:          "many small writes",
+			limit:         10,
+			writes:        strings.Split("0123456789abcdefghijklmnopqrstuvwxyz", ""),
+			want:          "01234\n[... 26 bytes truncated ...]\nvwxyz",
+			wantTruncated: true,
+		},
+		{
+			name:          "odd limit",
+			limit:         5,
+			writes:        []string{"abcdefgh"},
+			want:          "ab\n[... 3 bytes truncated ...]\nfgh",
+			wantTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCapture(tt.limit)
+			for _, w := range tt.writes {
+				if n, err := c.Write([]byte(w)); n != len(w) || err != nil {
+					t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(w))
+				}
+			}
+			if got := c.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+			if got := c.Truncated(); got != tt.wantTruncated {
+				t.Errorf("Truncated() = %v, want %v", got, tt.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestExecute_MaxCapture(t *testing.T) {
+	exec := New()
+	ctx := context.Background()
+
+	result := exec.Execute(ctx, Options{
+		Command:    "echo start; seq 1 100000; echo end",
+		MaxCapture: 1024,
+	})
+
+	if result.Err != nil {
+		t.Fatalf("Execute() Err = %v", result.Err)
+	}
+	if !result.Truncated {
+		t.Error("Execute() Truncated = false, want true")
+	}
+	if len(result.Stdout) > 1024+100 {
+		t.Errorf("len(Stdout) = %d, want about 1024", len(result.Stdout))
+	}
+	if !strings.HasPrefix(result.Stdout, "start\n1\n") || !strings.HasSuffix(result.Stdout, "100000\nend\n") {
+		t.Errorf("Stdout = %q, want to keep the head and tail", result.Stdout)
+	}
+	if !strings.Contains(result.Stdout, "bytes truncated") {
+		t.Errorf("Stdout = %q, want a truncation marker", result.Stdout)
+	}
+}
+
+func TestExecute_OutputWriters(t *testing.T) {
+	exec := New()
+	ctx := context.Background()
+
+	var stdout, stderr strings.Builder
+	result := exec.Execute(ctx, Options{
+		Command: "echo out; echo err >&2",
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+	})
+
+	if result.Err != nil {
+		t.Fatalf("Execute() Err = %v", result.Err)
+	}
+	if stdout.String() != "out\n" || result.Stdout != "out\n" {
+		t.Errorf("stdout = %q, Result.Stdout = %q, want %q", stdout.String(), result.Stdout, "out\n")
+	}
+	if stderr.String() != "err\n" || result.Stderr != "err\n" {
+		t.Errorf("stderr = %q, Result.Stderr = %q, want %q", stderr.String(), result.Stderr, "err\n")
+	}
+}
+
+func TestExecute_Credential(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("switching users requires root")
+	}
+	exec := New()
+	ctx := context.Background()
+
+	result := exec.Execute(ctx, Options{
+		Command:    "echo $(id -u) $(id -g) $(id -G)",
+		Credential: &Credential{UID: 65534, GID: 65534, Groups: []uint32{65534}},
+	})
+
+	if result.Err != nil {
+		t.Fatalf("Execute() Err = %v, stderr = %q", result.Err, result.Stderr)
+	}
+	if stdout := strings.TrimSpace(result.Stdout); stdout != "65534 65534 65534" {
+		t.Errorf("Execute() Stdout = %q, want uid, gid and groups 65534", stdout)
+	}
+}
@@ -0,0 +1,36 @@
+package executor
+
+// Limits caps the resources a command can use. The rlimits are set on the
+// command's process as it starts; the cgroup limits apply to the command
+// and all its children in a cgroup created for the run. Both are supported
+// on Linux only.
+type Limits struct {
+	// Rlimits, each set as both the soft and hard limit. Nil leaves the
+	// limit inherited from cronlock.
+	NoFile *uint64
+	NProc  *uint64
+	Core   *uint64
+	AS     *uint64
+
+	// Cgroup names the run's cgroup under the executor's cgroup parent. It
+	// must be unique among running commands.
+	Cgroup string
+	// MemoryMax is the memory limit in bytes (0 = no limit).
+	MemoryMax uint64
+	// CPUWeight is the share of CPU under contention, 1-10000 (0 = default).
+	CPUWeight int
+	// CPUMax is the number of CPUs' worth of time the run may use (0 = no limit).
+	CPUMax float64
+	// PidsMax is the maximum number of processes (0 = no limit).
+	PidsMax int
+}
+
+// hasRlimits reports whether any rlimit is set.
+func (l *Limits) hasRlimits() bool {
+	return l != nil && (l.NoFile != nil || l.NProc != nil || l.Core != nil || l.AS != nil)
+}
+
+// hasCgroup reports whether any cgroup limit is set.
+func (l *Limits) hasCgroup() bool {
+	return l != nil && (l.MemoryMax > 0 || l.CPUWeight > 0 || l.CPUMax > 0 || l.PidsMax > 0)
+}
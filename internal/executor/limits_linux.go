@@ -0,0 +1,250 @@
+//go:build linux
+
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// cpuPeriod is the cpu.max period in microseconds.
+const cpuPeriod = 100000
+
+// rlimitHelper is argv[0] of cronlock re-executed to set a command's
+// rlimits and then execute it, so that they apply from its first instruction.
+const rlimitHelper = "cronlock-rlimit"
+
+// rlimitResources maps the rlimit names passed to the helper to resources.
+var rlimitResources = map[string]int{
+	"nofile": unix.RLIMIT_NOFILE,
+	"nproc":  unix.RLIMIT_NPROC,
+	"core":   unix.RLIMIT_CORE,
+	"as":     unix.RLIMIT_AS,
+}
+
+// setRlimits makes cmd start through the rlimit helper.
+func setRlimits(cmd *exec.Cmd, l *Limits) error {
+	if cmd.Err != nil {
+		// Leave the lookup error for Start to report
+		return nil
+	}
+
+	args := []string{rlimitHelper}
+	for _, r := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"nofile", l.NoFile},
+		{"nproc", l.NProc},
+		{"core", l.Core},
+		{"as", l.AS},
+	} {
+		if r.value != nil {
+			args = append(args, fmt.Sprintf("%s=%d", r.name, *r.value))
+		}
+	}
+	args = append(args, "--", cmd.Path)
+	args = append(args, cmd.Args...)
+
+	cmd.Path = "/proc/self/exe"
+	cmd.Args = args
+	return nil
+}
+
+// Init runs the rlimit helper if cronlock was re-executed as one, in which
+// case it doesn't return. main must call it before doing anything else.
+func Init() {
+	if len(os.Args) == 0 || os.Args[0] != rlimitHelper {
+		return
+	}
+	if err := runRlimitHelper(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "cronlock: %v\n", err)
+		// As a shell does when a command can't be executed
+		os.Exit(126)
+	}
+}
+
+// runRlimitHelper sets the rlimits in args, given as name=value up to "--",
+// and executes the program and argv following it. It only returns on error.
+// A limit above the hard limit is lowered to it, since raising the hard
+// limit needs privileges.
+func runRlimitHelper(args []string) error {
+	for len(args) > 0 && args[0] != "--" {
+		name, value, _ := strings.Cut(args[0], "=")
+		resource, ok := rlimitResources[name]
+		if !ok {
+			return fmt.Errorf("unknown rlimit %q", name)
+		}
+		limit, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s limit %q", name, value)
+		}
+
+		var cur syscall.Rlimit
+		if err := syscall.Getrlimit(resource, &cur); err != nil {
+			return fmt.Errorf("failed to get %s limit: %w", name, err)
+		}
+		limit = min(limit, cur.Max)
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit, Max: limit}); err != nil {
+			return fmt.Errorf("failed to set %s limit: %w", name, err)
+		}
+		args = args[1:]
+	}
+	if len(args) < 3 {
+		return errors.New("no command given")
+	}
+	if err := syscall.Exec(args[1], args[2:], os.Environ()); err != nil {
+		return fmt.Errorf("failed to execute %s: %w", args[1], err)
+	}
+	return nil
+}
+
+// cgroup is a cgroup v2 group created for one run.
+type cgroup struct {
+	path string
+	dir  *os.File
+}
+
+// createCgroup creates the run's cgroup under parent with the limits set,
+// enabling the controllers it needs in parent.
+func createCgroup(parent, name string, l *Limits) (*cgroup, error) {
+	if parent == "" {
+		return nil, errors.New("failed to create cgroup: no cgroup parent configured")
+	}
+	if name == "" || strings.Contains(name, "/") {
+		return nil, fmt.Errorf("failed to create cgroup: invalid name %q", name)
+	}
+
+	var controllers []string
+	var files [][2]string
+	if l.MemoryMax > 0 {
+		controllers = append(controllers, "memory")
+		files = append(files,
+			[2]string{"memory.max", strconv.FormatUint(l.MemoryMax, 10)},
+			// Kill the whole run, not only its largest process
+			[2]string{"memory.oom.group", "1"},
+		)
+	}
+	if l.CPUWeight > 0 || l.CPUMax > 0 {
+		controllers = append(controllers, "cpu")
+	}
+	if l.CPUWeight > 0 {
+		files = append(files, [2]string{"cpu.weight", strconv.Itoa(l.CPUWeight)})
+	}
+	if l.CPUMax > 0 {
+		quota := max(int(l.CPUMax*cpuPeriod), 1000)
+		files = append(files, [2]string{"cpu.max", fmt.Sprintf("%d %d", quota, cpuPeriod)})
+	}
+	if l.PidsMax > 0 {
+		controllers = append(controllers, "pids")
+		files = append(files, [2]string{"pids.max", strconv.Itoa(l.PidsMax)})
+	}
+
+	if err := enableControllers(parent, controllers); err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(parent, name)
+	if err := os.Mkdir(path, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cgroup: %w", err)
+	}
+	c := &cgroup{path: path}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(path, f[0]), []byte(f[1]), 0o644); err != nil {
+			_ = c.remove()
+			return nil, fmt.Errorf("failed to set %s: %w", f[0], err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		_ = c.remove()
+		return nil, fmt.Errorf("failed to open cgroup: %w", err)
+	}
+	c.dir = dir
+	return c, nil
+}
+
+// enableControllers creates parent if needed and enables the controllers
+// for its children.
+func enableControllers(parent string, controllers []string) error {
+	if _, err := os.Stat(parent); errors.Is(err, os.ErrNotExist) {
+		// Only create the parent inside an existing cgroup v2 hierarchy
+		if _, err := os.Stat(filepath.Join(filepath.Dir(parent), "cgroup.controllers")); err != nil {
+			return fmt.Errorf("cgroup parent %s doesn't exist and %s is not a cgroup v2 directory", parent, filepath.Dir(parent))
+		}
+		if err := os.Mkdir(parent, 0o755); err != nil && !errors.Is(err, os.ErrExist) {
+			return fmt.Errorf("failed to create cgroup parent: %w", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(parent, "cgroup.subtree_control"))
+	if err != nil {
+		return fmt.Errorf("cgroup parent %s is not a cgroup v2 directory: %w", parent, err)
+	}
+	enabled := strings.Fields(string(data))
+
+	var add []string
+	for _, c := range controllers {
+		if !slices.Contains(enabled, c) {
+			add = append(add, "+"+c)
+		}
+	}
+	if len(add) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte(strings.Join(add, " ")), 0o644); err != nil {
+		return fmt.Errorf("failed to enable cgroup controllers %v in %s: %w", controllers, parent, err)
+	}
+	return nil
+}
+
+// attach makes the command start in the cgroup.
+func (c *cgroup) attach(attr *syscall.SysProcAttr) {
+	attr.UseCgroupFD = true
+	attr.CgroupFD = int(c.dir.Fd())
+}
+
+// oomKilled reports whether the memory limit killed any of the run's processes.
+func (c *cgroup) oomKilled() bool {
+	data, err := os.ReadFile(filepath.Join(c.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if count, ok := strings.CutPrefix(line, "oom_kill "); ok {
+			return count != "0"
+		}
+	}
+	return false
+}
+
+// remove kills any processes left in the cgroup, such as children the
+// command left running in the background, and removes it.
+func (c *cgroup) remove() error {
+	if c.dir != nil {
+		_ = c.dir.Close()
+	}
+	// cgroup.kill needs Linux 5.14; older kernels leave the processes running
+	_ = os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0o644)
+
+	// The cgroup can only be removed once the killed processes have exited
+	var err error
+	for range 50 {
+		if err = os.Remove(c.path); err == nil || !errors.Is(err, unix.EBUSY) {
+			return err
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return err
+}
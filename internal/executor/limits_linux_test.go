@@ -0,0 +1,169 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestMain(m *testing.M) {
+	// Commands with rlimits start through the test binary
+	Init()
+	os.Exit(m.Run())
+}
+
+func TestExecute_Rlimits(t *testing.T) {
+	var nofile unix.Rlimit
+	if err := unix.Getrlimit(unix.RLIMIT_NOFILE, &nofile); err != nil {
+		t.Fatal(err)
+	}
+
+	uint64p := func(v uint64) *uint64 { return &v }
+
+	const script = "echo $(ulimit -Sn) $(ulimit -Hn) $(ulimit -c)"
+
+	tests := []struct {
+		name   string
+		args   []string
+		limits *Limits
+		want   string
+	}{
+		{
+			name:   "nofile and core",
+			limits: &Limits{NoFile: uint64p(64), Core: uint64p(0)},
+			want:   "64 64 0",
+		},
+		{
+			name:   "above the hard limit",
+			limits: &Limits{NoFile: uint64p(nofile.Max + 1), Core: uint64p(0)},
+			want:   fmt.Sprintf("%d %d 0", nofile.Max, nofile.Max),
+		},
+		{
+			name:   "argv command",
+			args:   []string{"sh", "-c", script + " $0", "arg0"},
+			limits: &Limits{NoFile: uint64p(128), Core: uint64p(0)},
+			want:   "128 128 0 arg0",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := New().Execute(context.Background(), Options{
+				Command: script,
+				Args:    tt.args,
+				Shell:   "/bin/sh",
+				Limits:  tt.limits,
+			})
+
+			if result.Err != nil {
+				t.Fatalf("Execute() Err = %v, stderr = %q", result.Err, result.Stderr)
+			}
+			if stdout := strings.TrimSpace(result.Stdout); stdout != tt.want {
+				t.Errorf("Execute() Stdout = %q, want %q", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_Rlimits_NotFound(t *testing.T) {
+	result := New().Execute(context.Background(), Options{
+		Args:   []string{"nonexistent-command-xyz"},
+		Limits: &Limits{NoFile: new(uint64)},
+	})
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "executable file not found") {
+		t.Errorf("Execute() Err = %v, want executable file not found", result.Err)
+	}
+}
+
+func TestExecute_CgroupParentInvalid(t *testing.T) {
+	dir := t.TempDir()
+	marker := filepath.Join(dir, "ran")
+
+	result := New(WithCgroupParent(dir)).Execute(context.Background(), Options{
+		Command: "touch " + marker,
+		Limits:  &Limits{Cgroup: "job-run", MemoryMax: 64 << 20},
+	})
+
+	if result.Err == nil || !strings.Contains(result.Err.Error(), "not a cgroup v2 directory") {
+		t.Errorf("Execute() Err = %v, want not a cgroup v2 directory", result.Err)
+	}
+	if result.Success() {
+		t.Error("Execute() succeeded, want failure")
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("command ran without its cgroup")
+	}
+}
+
+func TestExecute_MemoryLimit(t *testing.T) {
+	parent := testCgroupParent(t, "memory", "pids")
+
+	result := New(WithCgroupParent(parent)).Execute(context.Background(), Options{
+		// tail buffers the whole newline-free input
+		Command: "head -c 512M /dev/zero | tail -n 1 >/dev/null",
+		Limits:  &Limits{Cgroup: "job-run", MemoryMax: 32 << 20, PidsMax: 16},
+	})
+
+	if result.Success() {
+		t.Fatal("Execute() succeeded, want the memory limit to kill it")
+	}
+	if !result.OOMKilled {
+		t.Errorf("Execute() OOMKilled = false, want true (exit code %d, err %v)", result.ExitCode, result.Err)
+	}
+	if _, err := os.Stat(filepath.Join(parent, "job-run")); !os.IsNotExist(err) {
+		t.Errorf("run cgroup was not removed: %v", err)
+	}
+}
+
+func TestExecute_CgroupKillsLeftovers(t *testing.T) {
+	parent := testCgroupParent(t, "pids")
+	pidFile := filepath.Join(t.TempDir(), "pid")
+
+	result := New(WithCgroupParent(parent)).Execute(context.Background(), Options{
+		Command: "sleep 60 >/dev/null 2>&1 & echo $! > " + pidFile,
+		Limits:  &Limits{Cgroup: "job-run", PidsMax: 16},
+	})
+	if result.Err != nil {
+		t.Fatalf("Execute() Err = %v, stderr = %q", result.Err, result.Stderr)
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err := unix.Kill(pid, 0); err == nil {
+		_ = unix.Kill(pid, unix.SIGKILL)
+		t.Error("background process is still running after the run")
+	}
+}
+
+// testCgroupParent creates a cgroup parent for the test, skipping it unless
+// cgroup v2 is mounted at /sys/fs/cgroup with the controllers available and
+// the test may create cgroups.
+func testCgroupParent(t *testing.T, controllers ...string) string {
+	t.Helper()
+	data, err := os.ReadFile("/sys/fs/cgroup/cgroup.controllers")
+	if err != nil {
+		t.Skip("cgroup v2 is not mounted at /sys/fs/cgroup")
+	}
+	for _, c := range controllers {
+		if !slices.Contains(strings.Fields(string(data)), c) {
+			t.Skipf("cgroup v2 %s controller is not available", c)
+		}
+	}
+
+	parent := filepath.Join("/sys/fs/cgroup", fmt.Sprintf("cronlock-test-%d", os.Getpid()))
+	if err := os.Mkdir(parent, 0o755); err != nil {
+		t.Skipf("can't create cgroups: %v", err)
+	}
+	t.Cleanup(func() { _ = os.Remove(parent) })
+	return parent
+}
@@ -0,0 +1,36 @@
+//go:build !linux
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// errLimitsUnsupported is returned when a command has limits on a
+// platform other than Linux.
+var errLimitsUnsupported = errors.New("resource limits are only supported on Linux")
+
+func setRlimits(cmd *exec.Cmd, l *Limits) error {
+	return errLimitsUnsupported
+}
+
+// Init does nothing: cronlock only re-executes itself to set rlimits on Linux.
+func Init() {}
+
+type cgroup struct{}
+
+func createCgroup(parent, name string, l *Limits) (*cgroup, error) {
+	return nil, errLimitsUnsupported
+}
+
+func (c *cgroup) attach(attr *syscall.SysProcAttr) {}
+
+func (c *cgroup) oomKilled() bool {
+	return false
+}
+
+func (c *cgroup) remove() error {
+	return nil
+}
@@ -0,0 +1,23 @@
+//go:build !unix
+
+package executor
+
+import (
+	"errors"
+	"os/exec"
+	"syscall"
+)
+
+// errCredentialUnsupported is returned when a command should run as
+// another user on a platform other than Unix.
+var errCredentialUnsupported = errors.New("running commands as another user is only supported on Unix")
+
+// setProcess rejects cred: only Unix can switch users. When the command's
+// context is done, only the command itself is killed, not its children.
+func setProcess(cmd *exec.Cmd, cred *Credential) error {
+	if cred != nil {
+		return errCredentialUnsupported
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	return nil
+}
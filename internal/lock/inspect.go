@@ -0,0 +1,93 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// scanBatch is the COUNT hint passed to SCAN when listing locks.
+const scanBatch = 100
+
+// Inspect returns the lock held for jobName, or nil if it is free.
+func (r *RedisLocker) Inspect(ctx context.Context, jobName string) (*LockInfo, error) {
+	infos, err := r.inspectKeys(ctx, []string{r.lockKey(jobName)})
+	if err != nil {
+		return nil, err
+	}
+	if len(infos) == 0 {
+		return nil, nil
+	}
+	return &infos[0], nil
+}
+
+// List returns every held job lock, sorted by name.
+func (r *RedisLocker) List(ctx context.Context) ([]LockInfo, error) {
+	var keys []string
+	iter := r.client.Scan(ctx, 0, r.lockKey("*"), scanBatch).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list locks: %w", err)
+	}
+
+	infos, err := r.inspectKeys(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(infos, func(a, b LockInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos, nil
+}
+
+// ForceRelease deletes the lock for jobName if it still holds value,
+// whichever node acquired it.
+func (r *RedisLocker) ForceRelease(ctx context.Context, jobName, value string) (bool, error) {
+	result, err := releaseScript.Run(ctx, r.client, []string{r.lockKey(jobName)}, value).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to release lock: %w", err)
+	}
+	return result == 1, nil
+}
+
+// inspectKeys reads the value and remaining TTL of each lock key, skipping
+// keys that expire or are released while being read.
+func (r *RedisLocker) inspectKeys(ctx context.Context, keys []string) ([]LockInfo, error) {
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	gets := make([]*redis.StringCmd, len(keys))
+	ttls := make([]*redis.DurationCmd, len(keys))
+	for i, key := range keys {
+		gets[i] = pipe.Get(ctx, key)
+		ttls[i] = pipe.PTTL(ctx, key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to inspect locks: %w", err)
+	}
+
+	prefix := r.lockKey("")
+	infos := make([]LockInfo, 0, len(keys))
+	for i, key := range keys {
+		value, err := gets[i].Result()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, LockInfo{
+			Name:       strings.TrimPrefix(key, prefix),
+			Holder:     HolderNode(value),
+			Value:      value,
+			TTL:        max(ttls[i].Val(), 0),
+			AcquiredAt: AcquiredAt(value),
+		})
+	}
+	return infos, nil
+}
@@ -0,0 +1,87 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisLease implements Lease on top of a RedisLocker's client, node ID,
+// and atomic acquire/extend/release primitives.
+type RedisLease struct {
+	locker *RedisLocker
+	key    string
+
+	mu    sync.Mutex
+	value string // empty when not held
+}
+
+// Lease returns a cluster-wide lease stored at {prefix}{name}, outside the
+// job lock namespace so it can't collide with a job name.
+func (r *RedisLocker) Lease(name string) *RedisLease {
+	return &RedisLease{
+		locker: r,
+		key:    r.keyPrefix + name,
+	}
+}
+
+// Acquire attempts to take the lease using SET NX PX.
+func (l *RedisLease) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	value := l.locker.lockValue()
+
+	ok, err := l.locker.acquireKey(ctx, l.key, value, ttl)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		l.mu.Lock()
+		l.value = value
+		l.mu.Unlock()
+	}
+
+	return ok, nil
+}
+
+// Extend extends the lease TTL if this node still holds it.
+func (l *RedisLease) Extend(ctx context.Context, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	value := l.value
+	l.mu.Unlock()
+
+	if value == "" {
+		return false, nil
+	}
+
+	return l.locker.extendKey(ctx, l.key, value, ttl)
+}
+
+// Release gives up the lease if this node holds it.
+func (l *RedisLease) Release(ctx context.Context) error {
+	l.mu.Lock()
+	value := l.value
+	l.value = ""
+	l.mu.Unlock()
+
+	if value == "" {
+		return nil
+	}
+
+	return l.locker.releaseKey(ctx, l.key, value)
+}
+
+// Holder returns the node ID currently holding the lease.
+func (l *RedisLease) Holder(ctx context.Context) (string, error) {
+	value, err := l.locker.client.Get(ctx, l.key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read lease holder: %w", err)
+	}
+	return HolderNode(value), nil
+}
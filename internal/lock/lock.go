@@ -2,7 +2,10 @@ package lock
 
 import (
 	"context"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 // Locker defines the interface for distributed locking.
@@ -23,6 +26,72 @@ type Locker interface {
 	Close() error
 }
 
+// Inspector is implemented by lockers that can report which locks are
+// currently held across the cluster.
+type Inspector interface {
+	// Inspect returns the lock held for the given name, or nil if it is free.
+	Inspect(ctx context.Context, jobName string) (*LockInfo, error)
+
+	// List returns every held job lock, including shard locks.
+	List(ctx context.Context) ([]LockInfo, error)
+
+	// ForceRelease deletes a lock regardless of which node holds it, but only
+	// while it still has the given value as returned by Inspect, so a lock
+	// re-acquired since it was inspected is left alone.
+	// Returns false if the lock is free or now holds a different value.
+	ForceRelease(ctx context.Context, jobName, value string) (bool, error)
+}
+
+// LockInfo describes a held lock.
+type LockInfo struct {
+	Name       string        // job name, or job:shard:N for shard locks
+	Holder     string        // node ID of the holder
+	Value      string        // raw lock value, nodeID:uuid
+	TTL        time.Duration // remaining time to live
+	AcquiredAt time.Time     // zero if the value doesn't record it
+}
+
+// Lease is a single cluster-wide lock held by at most one node at a time,
+// such as the leader lease.
+type Lease interface {
+	// Acquire attempts to take the lease.
+	// Returns true if the lease was acquired, false if another node holds it.
+	Acquire(ctx context.Context, ttl time.Duration) (bool, error)
+
+	// Extend extends the TTL of the lease.
+	// Only extends if the current node holds the lease.
+	Extend(ctx context.Context, ttl time.Duration) (bool, error)
+
+	// Release gives up the lease if the current node holds it.
+	Release(ctx context.Context) error
+
+	// Holder returns the node ID of the current holder, or "" if the lease is free.
+	Holder(ctx context.Context) (string, error)
+}
+
+// HolderNode extracts the node ID from a lock value of the form nodeID:uuid.
+func HolderNode(value string) string {
+	if i := strings.LastIndex(value, ":"); i != -1 {
+		return value[:i]
+	}
+	return value
+}
+
+// AcquiredAt returns when a lock with the given value was acquired. Lock
+// values carry a version 7 UUID, which embeds its creation time; values
+// written by older versions use random UUIDs and return the zero time.
+func AcquiredAt(value string) time.Time {
+	i := strings.LastIndex(value, ":")
+	if i == -1 {
+		return time.Time{}
+	}
+	id, err := uuid.Parse(value[i+1:])
+	if err != nil || id.Version() != 7 {
+		return time.Time{}
+	}
+	return time.Unix(id.Time().UnixTime())
+}
+
 // Lock represents an acquired distributed lock.
 type Lock struct {
 	JobName string
@@ -2,6 +2,8 @@ package lock
 
 import (
 	"context"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,10 +20,14 @@ type MockLocker struct {
 	ExtendError   error
 	CloseError    error
 
+	// HolderID is reported as the holder of held locks by Inspect and List
+	HolderID string
+
 	// Call tracking
-	AcquireCalls []AcquireCall
-	ReleaseCalls []string
-	ExtendCalls  []ExtendCall
+	AcquireCalls      []AcquireCall
+	ReleaseCalls      []string
+	ExtendCalls       []ExtendCall
+	ForceReleaseCalls []string
 
 	// Simulate held locks
 	heldLocks map[string]bool
@@ -105,6 +111,46 @@ func (m *MockLocker) Close() error {
 	return m.CloseError
 }
 
+// Inspect implements Inspector.Inspect.
+func (m *MockLocker) Inspect(ctx context.Context, jobName string) (*LockInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.heldLocks[jobName] {
+		return nil, nil
+	}
+	return &LockInfo{Name: jobName, Holder: m.HolderID}, nil
+}
+
+// List implements Inspector.List.
+func (m *MockLocker) List(ctx context.Context) ([]LockInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var infos []LockInfo
+	for name := range m.heldLocks {
+		infos = append(infos, LockInfo{Name: name, Holder: m.HolderID})
+	}
+	slices.SortFunc(infos, func(a, b LockInfo) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return infos, nil
+}
+
+// ForceRelease implements Inspector.ForceRelease. The mock ignores value.
+func (m *MockLocker) ForceRelease(ctx context.Context, jobName, value string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ForceReleaseCalls = append(m.ForceReleaseCalls, jobName)
+
+	if !m.heldLocks[jobName] {
+		return false, nil
+	}
+	delete(m.heldLocks, jobName)
+	return true, nil
+}
+
 // SetLockHeld simulates a lock being held (for testing contention).
 func (m *MockLocker) SetLockHeld(jobName string, held bool) {
 	m.mu.Lock()
@@ -123,5 +169,118 @@ func (m *MockLocker) Reset() {
 	m.AcquireCalls = nil
 	m.ReleaseCalls = nil
 	m.ExtendCalls = nil
+	m.ForceReleaseCalls = nil
 	m.heldLocks = make(map[string]bool)
 }
+
+// MockLease is a test implementation of the Lease interface.
+type MockLease struct {
+	mu sync.Mutex
+
+	// Configurable return values
+	AcquireResult bool
+	AcquireError  error
+	ExtendResult  bool
+	ExtendError   error
+	HolderID      string
+
+	// extendFailures is how many more Extend calls fail, see FailExtends
+	extendFailures int
+	extendFailErr  error
+
+	// Call tracking
+	AcquireCalls int
+	ExtendCalls  int
+	ReleaseCalls int
+
+	held bool
+}
+
+// NewMockLease creates a new MockLease that is acquired and extended successfully.
+func NewMockLease() *MockLease {
+	return &MockLease{
+		AcquireResult: true,
+		ExtendResult:  true,
+	}
+}
+
+// Acquire implements Lease.Acquire.
+func (m *MockLease) Acquire(ctx context.Context, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.AcquireCalls++
+	if m.AcquireError != nil {
+		return false, m.AcquireError
+	}
+	m.held = m.AcquireResult
+	return m.AcquireResult, nil
+}
+
+// Extend implements Lease.Extend.
+func (m *MockLease) Extend(ctx context.Context, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ExtendCalls++
+	if m.extendFailures > 0 {
+		m.extendFailures--
+		return false, m.extendFailErr
+	}
+	if m.ExtendError != nil {
+		return false, m.ExtendError
+	}
+	if !m.ExtendResult {
+		m.held = false
+	}
+	return m.held && m.ExtendResult, nil
+}
+
+// Release implements Lease.Release.
+func (m *MockLease) Release(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ReleaseCalls++
+	m.held = false
+	return nil
+}
+
+// Holder implements Lease.Holder.
+func (m *MockLease) Holder(ctx context.Context) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.HolderID, nil
+}
+
+// Set updates the mock's results under its lock, for changing behavior while
+// code under test is running.
+func (m *MockLease) Set(acquire, extend bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.AcquireResult = acquire
+	m.ExtendResult = extend
+}
+
+// FailExtends makes the next n Extend calls fail with err, as on a brief
+// loss of the Redis connection.
+func (m *MockLease) FailExtends(n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.extendFailures = n
+	m.extendFailErr = err
+}
+
+// SetExtendError sets ExtendError under the mock's lock.
+func (m *MockLease) SetExtendError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ExtendError = err
+}
+
+// Calls returns the number of Extend and Release calls so far.
+func (m *MockLease) Calls() (extend, release int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.ExtendCalls, m.ReleaseCalls
+}
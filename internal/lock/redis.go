@@ -52,20 +52,23 @@ func (r *RedisLocker) lockKey(jobName string) string {
 	return fmt.Sprintf("%sjob:%s", r.keyPrefix, jobName)
 }
 
-// lockValue generates a unique value for this lock acquisition.
+// lockValue generates a unique value for this lock acquisition. The UUID
+// is version 7 so the value also records when the lock was acquired.
 func (r *RedisLocker) lockValue() string {
-	return fmt.Sprintf("%s:%s", r.nodeID, uuid.New().String())
+	id, err := uuid.NewV7()
+	if err != nil {
+		id = uuid.New()
+	}
+	return fmt.Sprintf("%s:%s", r.nodeID, id.String())
 }
 
 // Acquire attempts to acquire a lock using SET NX EX.
 func (r *RedisLocker) Acquire(ctx context.Context, jobName string, ttl time.Duration) (bool, error) {
-	key := r.lockKey(jobName)
 	value := r.lockValue()
 
-	// SET key value NX PX milliseconds
-	ok, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	ok, err := r.acquireKey(ctx, r.lockKey(jobName), value, ttl)
 	if err != nil {
-		return false, fmt.Errorf("failed to acquire lock: %w", err)
+		return false, err
 	}
 
 	if ok {
@@ -77,6 +80,34 @@ func (r *RedisLocker) Acquire(ctx context.Context, jobName string, ttl time.Dura
 	return ok, nil
 }
 
+// acquireKey sets key to value only if it does not exist.
+func (r *RedisLocker) acquireKey(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	// SET key value NX PX milliseconds
+	ok, err := r.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire lock: %w", err)
+	}
+	return ok, nil
+}
+
+// releaseKey deletes key only if it still holds value.
+func (r *RedisLocker) releaseKey(ctx context.Context, key, value string) error {
+	_, err := releaseScript.Run(ctx, r.client, []string{key}, value).Int64()
+	if err != nil {
+		return fmt.Errorf("failed to release lock: %w", err)
+	}
+	return nil
+}
+
+// extendKey resets the TTL of key only if it still holds value.
+func (r *RedisLocker) extendKey(ctx context.Context, key, value string, ttl time.Duration) (bool, error) {
+	result, err := extendScript.Run(ctx, r.client, []string{key}, value, ttl.Milliseconds()).Int64()
+	if err != nil {
+		return false, fmt.Errorf("failed to extend lock: %w", err)
+	}
+	return result == 1, nil
+}
+
 // Release releases the lock using a Lua script for atomicity.
 func (r *RedisLocker) Release(ctx context.Context, jobName string) error {
 	key := r.lockKey(jobName)
@@ -91,12 +122,7 @@ func (r *RedisLocker) Release(ctx context.Context, jobName string) error {
 	delete(r.locks, jobName)
 	r.mu.Unlock()
 
-	_, err := releaseScript.Run(ctx, r.client, []string{key}, value).Int64()
-	if err != nil {
-		return fmt.Errorf("failed to release lock: %w", err)
-	}
-
-	return nil
+	return r.releaseKey(ctx, key, value)
 }
 
 // Extend extends the lock TTL using a Lua script for atomicity.
@@ -112,12 +138,7 @@ func (r *RedisLocker) Extend(ctx context.Context, jobName string, ttl time.Durat
 		return false, nil
 	}
 
-	result, err := extendScript.Run(ctx, r.client, []string{key}, value, ttl.Milliseconds()).Int64()
-	if err != nil {
-		return false, fmt.Errorf("failed to extend lock: %w", err)
-	}
-
-	return result == 1, nil
+	return r.extendKey(ctx, key, value, ttl)
 }
 
 // Close releases any resources held by the locker.
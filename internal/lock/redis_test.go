@@ -469,3 +469,246 @@ func TestRedisLocker_ConcurrentDifferentOperations(t *testing.T) {
 
 	wg.Wait()
 }
+
+func TestRedisLease_SingleHolder(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	lease1 := NewRedisLocker(client, "node-1", "test:").Lease("leader")
+	lease2 := NewRedisLocker(client, "node-2", "test:").Lease("leader")
+
+	ctx := context.Background()
+
+	acquired, err := lease1.Acquire(ctx, 30*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if !acquired {
+		t.Fatal("lease1.Acquire() = false, want true")
+	}
+
+	acquired, err = lease2.Acquire(ctx, 30*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if acquired {
+		t.Error("lease2.Acquire() = true, want false while lease1 holds it")
+	}
+
+	holder, err := lease2.Holder(ctx)
+	if err != nil {
+		t.Fatalf("Holder() error = %v", err)
+	}
+	if holder != "node-1" {
+		t.Errorf("Holder() = %q, want %q", holder, "node-1")
+	}
+
+	// Only the holder can extend
+	if ok, _ := lease2.Extend(ctx, 30*time.Second); ok {
+		t.Error("lease2.Extend() = true, want false")
+	}
+	if ok, _ := lease1.Extend(ctx, 30*time.Second); !ok {
+		t.Error("lease1.Extend() = false, want true")
+	}
+
+	if err := lease1.Release(ctx); err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	acquired, err = lease2.Acquire(ctx, 30*time.Second)
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if !acquired {
+		t.Error("lease2.Acquire() = false after release, want true")
+	}
+}
+
+func TestRedisLease_SeparateFromJobLocks(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	locker := NewRedisLocker(client, "node-1", "test:")
+	ctx := context.Background()
+
+	// A job named "leader" must not collide with the leader lease
+	if ok, err := locker.Lease("leader").Acquire(ctx, 30*time.Second); err != nil || !ok {
+		t.Fatalf("Lease.Acquire() = %v, %v", ok, err)
+	}
+	if ok, err := locker.Acquire(ctx, "leader", 30*time.Second); err != nil || !ok {
+		t.Errorf("Acquire(job leader) = %v, %v, want true", ok, err)
+	}
+}
+
+func TestRedisLease_Expires(t *testing.T) {
+	s, client := setupMiniredis(t)
+
+	lease1 := NewRedisLocker(client, "node-1", "test:").Lease("leader")
+	lease2 := NewRedisLocker(client, "node-2", "test:").Lease("leader")
+
+	ctx := context.Background()
+	if ok, _ := lease1.Acquire(ctx, 5*time.Second); !ok {
+		t.Fatal("lease1.Acquire() = false, want true")
+	}
+
+	// Leader dies and stops renewing
+	s.FastForward(6 * time.Second)
+
+	if ok, _ := lease2.Acquire(ctx, 5*time.Second); !ok {
+		t.Error("lease2.Acquire() = false after expiry, want true")
+	}
+	if ok, _ := lease1.Extend(ctx, 5*time.Second); ok {
+		t.Error("lease1.Extend() = true after losing the lease, want false")
+	}
+}
+
+func TestHolderNode(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"node-1:6f1c2b9e-3a4d-4e5f-8a9b-0c1d2e3f4a5b", "node-1"},
+		{"host:with:colons:6f1c2b9e-3a4d-4e5f-8a9b-0c1d2e3f4a5b", "host:with:colons"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := HolderNode(tt.value); got != tt.want {
+			t.Errorf("HolderNode(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRedisLocker_Inspect(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	locker1 := NewRedisLocker(client, "node-1", "test:")
+	locker2 := NewRedisLocker(client, "node-2", "test:")
+	ctx := context.Background()
+
+	info, err := locker2.Inspect(ctx, "backup")
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if info != nil {
+		t.Fatalf("Inspect() = %+v for free lock, want nil", info)
+	}
+
+	if ok, _ := locker1.Acquire(ctx, "backup", 30*time.Second); !ok {
+		t.Fatal("Acquire() = false, want true")
+	}
+
+	info, err = locker2.Inspect(ctx, "backup")
+	if err != nil {
+		t.Fatalf("Inspect() error = %v", err)
+	}
+	if info == nil {
+		t.Fatal("Inspect() = nil for held lock")
+	}
+	if info.Name != "backup" || info.Holder != "node-1" {
+		t.Errorf("Inspect() = %+v, want backup held by node-1", info)
+	}
+	if info.TTL <= 0 || info.TTL > 30*time.Second {
+		t.Errorf("Inspect().TTL = %v, want (0, 30s]", info.TTL)
+	}
+}
+
+func TestRedisLocker_List(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	locker1 := NewRedisLocker(client, "node-1", "test:")
+	locker2 := NewRedisLocker(client, "node-2", "test:")
+	ctx := context.Background()
+
+	locker1.Acquire(ctx, "report", 30*time.Second)
+	locker2.Acquire(ctx, "backup", 30*time.Second)
+	locker2.Acquire(ctx, "reindex:shard:0", 30*time.Second)
+
+	// Neither the leader lease nor another prefix's locks are listed
+	locker1.Lease("leader").Acquire(ctx, 30*time.Second)
+	NewRedisLocker(client, "node-3", "other:").Acquire(ctx, "backup", 30*time.Second)
+
+	infos, err := locker1.List(ctx)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+
+	want := []LockInfo{
+		{Name: "backup", Holder: "node-2"},
+		{Name: "reindex:shard:0", Holder: "node-2"},
+		{Name: "report", Holder: "node-1"},
+	}
+	if len(infos) != len(want) {
+		t.Fatalf("List() = %+v, want %d locks", infos, len(want))
+	}
+	for i, w := range want {
+		if infos[i].Name != w.Name || infos[i].Holder != w.Holder {
+			t.Errorf("List()[%d] = %s held by %s, want %s held by %s", i, infos[i].Name, infos[i].Holder, w.Name, w.Holder)
+		}
+	}
+}
+
+func TestRedisLocker_Inspect_AcquiredAt(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	locker := NewRedisLocker(client, "node-1", "test:")
+	ctx := context.Background()
+
+	before := time.Now().Truncate(time.Millisecond)
+	locker.Acquire(ctx, "backup", 30*time.Second)
+
+	info, err := locker.Inspect(ctx, "backup")
+	if err != nil || info == nil {
+		t.Fatalf("Inspect() = %v, %v", info, err)
+	}
+	if info.AcquiredAt.Before(before) || time.Since(info.AcquiredAt) > time.Second {
+		t.Errorf("AcquiredAt = %v, want about %v", info.AcquiredAt, before)
+	}
+}
+
+func TestAcquiredAt(t *testing.T) {
+	if got := AcquiredAt("node-1:6f1c2b9e-3a4d-4e5f-8a9b-0c1d2e3f4a5b"); !got.IsZero() {
+		t.Errorf("AcquiredAt(v4 value) = %v, want zero", got)
+	}
+	if got := AcquiredAt("garbage"); !got.IsZero() {
+		t.Errorf("AcquiredAt(garbage) = %v, want zero", got)
+	}
+
+	// 0x018bcfe56800 ms = 2023-11-14T22:13:20Z
+	got := AcquiredAt("node-1:018bcfe5-6800-7000-8000-000000000000")
+	if want := time.Unix(1700000000, 0); !got.Equal(want) {
+		t.Errorf("AcquiredAt(v7 value) = %v, want %v", got, want)
+	}
+}
+
+func TestRedisLocker_ForceRelease(t *testing.T) {
+	_, client := setupMiniredis(t)
+
+	owner := NewRedisLocker(client, "node-1", "test:")
+	admin := NewRedisLocker(client, "admin", "test:")
+	ctx := context.Background()
+
+	owner.Acquire(ctx, "backup", 30*time.Second)
+	info, _ := admin.Inspect(ctx, "backup")
+
+	// A stale value doesn't release the current lock
+	released, err := admin.ForceRelease(ctx, "backup", "node-1:stale")
+	if err != nil {
+		t.Fatalf("ForceRelease() error = %v", err)
+	}
+	if released {
+		t.Error("ForceRelease() with stale value = true, want false")
+	}
+
+	released, err = admin.ForceRelease(ctx, "backup", info.Value)
+	if err != nil {
+		t.Fatalf("ForceRelease() error = %v", err)
+	}
+	if !released {
+		t.Error("ForceRelease() = false, want true")
+	}
+	if info, _ := admin.Inspect(ctx, "backup"); info != nil {
+		t.Errorf("Inspect() after ForceRelease() = %+v, want nil", info)
+	}
+
+	// Another node can take the lock right away
+	if ok, _ := NewRedisLocker(client, "node-2", "test:").Acquire(ctx, "backup", 30*time.Second); !ok {
+		t.Error("Acquire() after ForceRelease() = false, want true")
+	}
+}
@@ -0,0 +1,143 @@
+// Package logging builds cronlock's logger from the log configuration.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"cronlock/internal/config"
+)
+
+// New creates the logger configured by cfg. If cfg.Output is a file, the
+// returned File can be reopened after the file is rotated; otherwise it is nil.
+func New(cfg config.LogConfig) (*slog.Logger, *File, error) {
+	level, err := config.ParseLogLevel(cfg.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var out io.Writer
+	var file *File
+	switch cfg.Output {
+	case "", "stderr":
+		out = os.Stderr
+	case "stdout":
+		out = os.Stdout
+	default:
+		if file, err = OpenFile(cfg.Output); err != nil {
+			return nil, nil, err
+		}
+		out = file
+	}
+
+	// The inner handler logs everything, so WithLevel can lower the level
+	// for one job below the node's level.
+	opts := &slog.HandlerOptions{
+		Level:     slog.LevelDebug,
+		AddSource: cfg.IncludeSource,
+	}
+	var h slog.Handler
+	switch cfg.Format {
+	case config.LogFormatJSON:
+		h = slog.NewJSONHandler(out, opts)
+	case "", config.LogFormatText, config.LogFormatLogfmt:
+		h = slog.NewTextHandler(out, opts)
+	default:
+		return nil, nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+
+	return slog.New(&levelHandler{inner: h, level: level}), file, nil
+}
+
+// WithLevel returns a logger that logs records at level and above. For a
+// logger created by New the level may be lower than the logger's own, down
+// to debug; otherwise it can only be raised.
+func WithLevel(logger *slog.Logger, level slog.Level) *slog.Logger {
+	inner := logger.Handler()
+	if h, ok := inner.(*levelHandler); ok {
+		inner = h.inner
+	}
+	return slog.New(&levelHandler{inner: inner, level: level})
+}
+
+// levelHandler filters records below its level before passing them on.
+type levelHandler struct {
+	inner slog.Handler
+	level slog.Level
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level && h.inner.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.inner.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{inner: h.inner.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{inner: h.inner.WithGroup(name), level: h.level}
+}
+
+// File is a log file that can be reopened, so that after logrotate moves it
+// away new records go to a fresh file at the configured path.
+type File struct {
+	path string
+
+	mu sync.Mutex
+	f  *os.File
+}
+
+// OpenFile opens a log file for appending, creating it if needed.
+func OpenFile(path string) (*File, error) {
+	f, err := openLogFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &File{path: path, f: f}, nil
+}
+
+// Write implements io.Writer.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Write(p)
+}
+
+// Reopen closes the file and opens the configured path again. If the path
+// can't be opened, logging continues to the old file.
+func (f *File) Reopen() error {
+	next, err := openLogFile(f.path)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	prev := f.f
+	f.f = next
+	f.mu.Unlock()
+	return prev.Close()
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.f.Close()
+}
+
+// openLogFile opens path for appending.
+func openLogFile(path string) (*os.File, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open log file: %w", err)
+	}
+	return f, nil
+}
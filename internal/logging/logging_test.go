@@ -0,0 +1,181 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"cronlock/internal/config"
+)
+
+func TestNew_FormatAndLevel(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.LogConfig
+		wantPrefix string
+		wantDebug  bool
+	}{
+		{
+			name:       "text",
+			cfg:        config.LogConfig{Format: "text", Level: "info"},
+			wantPrefix: "time=",
+		},
+		{
+			name:       "logfmt",
+			cfg:        config.LogConfig{Format: "logfmt", Level: "INFO"},
+			wantPrefix: "time=",
+		},
+		{
+			name:       "json at debug",
+			cfg:        config.LogConfig{Format: "json", Level: "debug"},
+			wantPrefix: "{",
+			wantDebug:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Output = filepath.Join(t.TempDir(), "cronlock.log")
+			logger, file, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			defer file.Close()
+
+			logger.Debug("debug line")
+			logger.Info("info line", "job", "backup")
+
+			data, _ := os.ReadFile(tt.cfg.Output)
+			logs := string(data)
+			if !strings.HasPrefix(logs, tt.wantPrefix) {
+				t.Errorf("logs = %q, want prefix %q", logs, tt.wantPrefix)
+			}
+			if !strings.Contains(logs, "info line") {
+				t.Errorf("logs = %q, want the info line", logs)
+			}
+			if got := strings.Contains(logs, "debug line"); got != tt.wantDebug {
+				t.Errorf("debug line logged = %v, want %v", got, tt.wantDebug)
+			}
+		})
+	}
+}
+
+func TestNew_IncludeSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cronlock.log")
+	logger, file, err := New(config.LogConfig{Format: "json", Level: "info", Output: path, IncludeSource: true})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer file.Close()
+
+	logger.Info("hello")
+
+	data, _ := os.ReadFile(path)
+	var record struct {
+		Source struct {
+			File string `json:"file"`
+		} `json:"source"`
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("failed to parse log record %q: %v", data, err)
+	}
+	if !strings.HasSuffix(record.Source.File, "logging_test.go") {
+		t.Errorf("source file = %q, want logging_test.go", record.Source.File)
+	}
+}
+
+func TestNew_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LogConfig
+	}{
+		{"bad level", config.LogConfig{Format: "text", Level: "loud"}},
+		{"bad format", config.LogConfig{Format: "xml", Level: "info"}},
+		{"unwritable file", config.LogConfig{Format: "text", Level: "info", Output: "/nonexistent/dir/cronlock.log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := New(tt.cfg); err == nil {
+				t.Error("New() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestWithLevel(t *testing.T) {
+	var buf bytes.Buffer
+	inner := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := slog.New(&levelHandler{inner: inner, level: slog.LevelInfo}).With("node", "n1")
+
+	debugJob := WithLevel(logger.With("job", "noisy"), slog.LevelDebug)
+	quietJob := WithLevel(logger.With("job", "quiet"), slog.LevelWarn)
+
+	logger.Debug("node debug")
+	debugJob.Debug("noisy debug")
+	quietJob.Info("quiet info")
+	quietJob.Warn("quiet warn")
+
+	logs := buf.String()
+	for _, want := range []string{"node=n1 job=noisy", "noisy debug", "quiet warn"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs missing %q:\n%s", want, logs)
+		}
+	}
+	for _, unwanted := range []string{"node debug", "quiet info"} {
+		if strings.Contains(logs, unwanted) {
+			t.Errorf("logs contain %q:\n%s", unwanted, logs)
+		}
+	}
+}
+
+func TestWithLevel_OtherHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	// A plain handler's own level still applies
+	WithLevel(logger, slog.LevelDebug).Debug("debug")
+	WithLevel(logger, slog.LevelError).Warn("warn")
+
+	if buf.Len() != 0 {
+		t.Errorf("logs = %q, want nothing", buf.String())
+	}
+}
+
+func TestFile_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cronlock.log")
+
+	file, err := OpenFile(path)
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+	defer file.Close()
+
+	_, _ = file.Write([]byte("before\n"))
+
+	// Simulate logrotate moving the file away
+	rotated := filepath.Join(dir, "cronlock.log.1")
+	if err := os.Rename(path, rotated); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.Write([]byte("still old\n"))
+
+	if err := file.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	_, _ = file.Write([]byte("after\n"))
+
+	old, _ := os.ReadFile(rotated)
+	if string(old) != "before\nstill old\n" {
+		t.Errorf("rotated file = %q, want %q", old, "before\nstill old\n")
+	}
+	current, _ := os.ReadFile(path)
+	if string(current) != "after\n" {
+		t.Errorf("new file = %q, want %q", current, "after\n")
+	}
+}
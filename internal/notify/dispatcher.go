@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+// Dispatcher defaults.
+const (
+	defaultQueueSize = 100
+	defaultWorkers   = 4
+	defaultBackoff   = time.Second
+)
+
+// channel is a named notifier with its delivery settings.
+type channel struct {
+	notifier Notifier
+	timeout  time.Duration
+	retries  int
+}
+
+// delivery is an event queued for one notifier.
+type delivery struct {
+	name  string
+	event Event
+}
+
+// Dispatcher delivers events to notifiers in the background, so a slow or
+// unreachable notifier never delays a job. Failed deliveries are retried
+// with exponential backoff; events that don't fit in the queue are dropped.
+type Dispatcher struct {
+	channels map[string]channel
+	logger   *slog.Logger
+	backoff  time.Duration
+
+	queue  chan delivery
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Option configures optional Dispatcher settings.
+type Option func(*Dispatcher)
+
+// WithBackoff sets the delay before the first retry, doubled for each
+// further retry.
+func WithBackoff(backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+	}
+}
+
+// WithNotifier adds a notifier under name, replacing any configured one.
+func WithNotifier(name string, n Notifier, timeout time.Duration, retries int) Option {
+	return func(d *Dispatcher) {
+		d.channels[name] = channel{notifier: n, timeout: timeout, retries: retries}
+	}
+}
+
+// NewDispatcher creates a Dispatcher for the configured notifiers and starts
+// its workers. Close stops them.
+func NewDispatcher(cfgs []config.NotifierConfig, logger *slog.Logger, opts ...Option) (*Dispatcher, error) {
+	d := &Dispatcher{
+		channels: make(map[string]channel, len(cfgs)),
+		logger:   logger,
+		backoff:  defaultBackoff,
+		queue:    make(chan delivery, defaultQueueSize),
+	}
+	for _, cfg := range cfgs {
+		n, err := New(cfg)
+		if err != nil {
+			return nil, err
+		}
+		timeout := cfg.Timeout
+		if timeout == 0 {
+			timeout = config.DefaultNotifierTimeout
+		}
+		d.channels[cfg.Name] = channel{notifier: n, timeout: timeout, retries: cfg.RetryCount()}
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+	for range defaultWorkers {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d, nil
+}
+
+// Send queues the event for the named notifiers without blocking. Unknown
+// names are ignored.
+func (d *Dispatcher) Send(names []string, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Stderr = tail(e.Stderr, maxStderr)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	for _, name := range names {
+		if _, ok := d.channels[name]; !ok {
+			continue
+		}
+		select {
+		case d.queue <- delivery{name: name, event: e}:
+		default:
+			d.logger.Warn("notification queue is full, dropping notification",
+				"notifier", name,
+				"event", e.Event,
+				"job", e.Job,
+			)
+		}
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered
+// until ctx is done, then abandons the rest.
+func (d *Dispatcher) Close(ctx context.Context) {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		d.logger.Warn("abandoning undelivered notifications", "queued", len(d.queue))
+		d.cancel()
+		<-done
+	}
+	d.cancel()
+}
+
+// work delivers queued events until the queue is closed and drained.
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for del := range d.queue {
+		if d.ctx.Err() != nil {
+			continue
+		}
+		d.deliver(del)
+	}
+}
+
+// deliver sends one event, retrying failures with exponential backoff.
+func (d *Dispatcher) deliver(del delivery) {
+	ch := d.channels[del.name]
+	logger := d.logger.With("notifier", del.name, "event", del.event.Event, "job", del.event.Job)
+
+	backoff := d.backoff
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(d.ctx, ch.timeout)
+		err := ch.notifier.Notify(ctx, del.event)
+		cancel()
+		if err == nil {
+			logger.Debug("sent notification")
+			return
+		}
+		if isPermanent(err) || attempt >= ch.retries || d.ctx.Err() != nil {
+			logger.Error("failed to send notification", "attempts", attempt+1, "error", err)
+			return
+		}
+		logger.Debug("failed to send notification, retrying", "retry_in", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+		case <-d.ctx.Done():
+			logger.Error("failed to send notification", "attempts", attempt+1, "error", err)
+			return
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+// defaultSMTPPort is the submission port, used if smtp.port is not set.
+const defaultSMTPPort = 587
+
+// Email sends events as plain text emails over SMTP, upgrading the
+// connection with STARTTLS when the server offers it.
+type Email struct {
+	SMTP config.SMTPConfig
+}
+
+// Notify sends the event to every recipient.
+func (m *Email) Notify(ctx context.Context, e Event) error {
+	port := m.SMTP.Port
+	if port == 0 {
+		port = defaultSMTPPort
+	}
+	addr := net.JoinHostPort(m.SMTP.Host, strconv.Itoa(port))
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	c, err := smtp.NewClient(conn, m.SMTP.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.SMTP.Host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.SMTP.Username != "" {
+		auth := smtp.PlainAuth("", m.SMTP.Username, m.SMTP.Password, m.SMTP.Host)
+		if err := c.Auth(auth); err != nil {
+			return &permanentError{fmt.Errorf("failed to authenticate: %w", err)}
+		}
+	}
+
+	if err := c.Mail(m.SMTP.From); err != nil {
+		return err
+	}
+	for _, to := range m.SMTP.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(m.message(e)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// message formats the event as an email with CRLF line endings.
+func (m *Email) message(e Event) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", m.SMTP.From)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(m.SMTP.To, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", e.Summary()))
+	fmt.Fprintf(&b, "Date: %s\r\n", e.Time.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(e.Details(), "\n", "\r\n"))
+	return b.Bytes()
+}
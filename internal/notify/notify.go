@@ -0,0 +1,141 @@
+// Package notify delivers job events to webhooks, Slack-compatible chat
+// webhooks and email, asynchronously and with retries.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+// maxStderr is how much of a failed run's stderr an event carries. Longer
+// output is cut to its last maxStderr bytes, where errors usually are.
+const maxStderr = 4096
+
+// Event describes something that happened to a job. It is the JSON body of
+// webhook notifications.
+type Event struct {
+	// Event is the kind of event: success, failure, timeout, lock_lost,
+	// slow, recovered or overdue.
+	Event  string `json:"event"`
+	Job    string `json:"job"`
+	NodeID string `json:"node_id"`
+	// RunID and ScheduledAt identify the run, except for overdue.
+	RunID       string    `json:"run_id,omitempty"`
+	ScheduledAt time.Time `json:"scheduled_at,omitzero"`
+	// Time is when the event happened.
+	Time time.Time `json:"time"`
+
+	// ExitCode, Duration (in seconds), Error and Stderr describe a finished
+	// run; they are empty for lock_lost and overdue. For slow, Duration is
+	// how long the run had been going.
+	ExitCode int     `json:"exit_code"`
+	Duration float64 `json:"duration"`
+	Error    string  `json:"error,omitempty"`
+	Stderr   string  `json:"stderr,omitempty"`
+
+	// ConsecutiveFailures is the number of runs in a row that failed, with
+	// alert_after_failures set. For recovered, it is the streak just ended.
+	ConsecutiveFailures int `json:"consecutive_failures,omitempty"`
+	// LastSuccess is when the job last succeeded, for overdue. It is unset
+	// if the job never has.
+	LastSuccess time.Time `json:"last_success,omitzero"`
+}
+
+// Summary returns a one-line description of the event, used as the chat
+// message and email subject.
+func (e Event) Summary() string {
+	switch e.Event {
+	case config.EventSuccess:
+		return fmt.Sprintf("cronlock: job %s succeeded on %s", e.Job, e.NodeID)
+	case config.EventFailure:
+		return fmt.Sprintf("cronlock: job %s failed on %s (exit code %d)", e.Job, e.NodeID, e.ExitCode)
+	case config.EventTimeout:
+		return fmt.Sprintf("cronlock: job %s timed out on %s", e.Job, e.NodeID)
+	case config.EventLockLost:
+		return fmt.Sprintf("cronlock: job %s lost its lock on %s", e.Job, e.NodeID)
+	case config.EventSlow:
+		return fmt.Sprintf("cronlock: job %s is still running on %s after %s", e.Job, e.NodeID, time.Duration(e.Duration*float64(time.Second)).Round(time.Second))
+	case config.EventOverdue:
+		return fmt.Sprintf("cronlock: job %s is overdue: %s", e.Job, e.Error)
+	case config.EventRecovered:
+		return fmt.Sprintf("cronlock: job %s recovered on %s after %d failed runs", e.Job, e.NodeID, e.ConsecutiveFailures)
+	}
+	return fmt.Sprintf("cronlock: job %s: %s on %s", e.Job, e.Event, e.NodeID)
+}
+
+// Details returns the event's fields as text, one per line, followed by the
+// stderr if any.
+func (e Event) Details() string {
+	s := fmt.Sprintf("Job: %s\nNode: %s\n", e.Job, e.NodeID)
+	if e.RunID != "" {
+		s += fmt.Sprintf("Run ID: %s\nScheduled at: %s\n", e.RunID, e.ScheduledAt.Format(time.RFC3339))
+	}
+	switch e.Event {
+	case config.EventLockLost:
+	case config.EventSlow:
+		s += fmt.Sprintf("Running for: %.2fs\n", e.Duration)
+	case config.EventOverdue:
+		last := "never"
+		if !e.LastSuccess.IsZero() {
+			last = e.LastSuccess.Format(time.RFC3339)
+		}
+		s += "Last success: " + last + "\n"
+	default:
+		s += fmt.Sprintf("Exit code: %d\nDuration: %.2fs\n", e.ExitCode, e.Duration)
+	}
+	if e.ConsecutiveFailures > 0 && e.Event != config.EventRecovered {
+		s += fmt.Sprintf("Consecutive failures: %d\n", e.ConsecutiveFailures)
+	}
+	if e.Error != "" {
+		s += "Error: " + e.Error + "\n"
+	}
+	if e.Stderr != "" {
+		s += "\nStderr:\n" + e.Stderr + "\n"
+	}
+	return s
+}
+
+// Notifier sends an event to one channel.
+type Notifier interface {
+	Notify(ctx context.Context, e Event) error
+}
+
+// permanentError is a delivery error that retrying won't fix, such as a
+// webhook rejecting the request as invalid.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent reports whether err must not be retried.
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+// New returns the notifier a config describes.
+func New(cfg config.NotifierConfig) (Notifier, error) {
+	switch cfg.Type {
+	case config.NotifierWebhook:
+		return &Webhook{URL: cfg.URL, Headers: cfg.Headers, Secret: cfg.Secret}, nil
+	case config.NotifierSlack:
+		return &Slack{URL: cfg.URL, Channel: cfg.Channel, Username: cfg.Username}, nil
+	case config.NotifierEmail:
+		return &Email{SMTP: cfg.SMTP}, nil
+	}
+	return nil, fmt.Errorf("unknown notifier type %q", cfg.Type)
+}
+
+// tail returns the last n bytes of s, marking it as cut if it was longer.
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return "[...]" + s[len(s)-n:]
+}
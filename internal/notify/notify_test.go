@@ -0,0 +1,346 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func testEvent() Event {
+	return Event{
+		Event:       config.EventFailure,
+		Job:         "backup",
+		NodeID:      "node-1",
+		RunID:       "run-1",
+		ScheduledAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		ExitCode:    3,
+		Duration:    1.5,
+		Stderr:      "disk full",
+	}
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	var (
+		body    []byte
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		headers = r.Header
+	}))
+	defer srv.Close()
+
+	w := &Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer abc"}, Secret: "s3cret"}
+	if err := w.Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("body is not JSON: %v", err)
+	}
+	if got.Event != "failure" || got.Job != "backup" || got.ExitCode != 3 || got.Stderr != "disk full" {
+		t.Errorf("body = %s, want the event", body)
+	}
+	if headers.Get("Authorization") != "Bearer abc" {
+		t.Errorf("Authorization = %q, want %q", headers.Get("Authorization"), "Bearer abc")
+	}
+	if want := "sha256=" + Sign("s3cret", body); headers.Get(SignatureHeader) != want {
+		t.Errorf("%s = %q, want %q", SignatureHeader, headers.Get(SignatureHeader), want)
+	}
+}
+
+func TestWebhook_Notify_Status(t *testing.T) {
+	tests := []struct {
+		status    int
+		wantErr   bool
+		permanent bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNoContent, false, false},
+		{http.StatusBadRequest, true, true},
+		{http.StatusNotFound, true, true},
+		{http.StatusTooManyRequests, true, false},
+		{http.StatusBadGateway, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			err := (&Webhook{URL: srv.URL}).Notify(context.Background(), testEvent())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && isPermanent(err) != tt.permanent {
+				t.Errorf("isPermanent() = %v, want %v", isPermanent(err), tt.permanent)
+			}
+		})
+	}
+}
+
+func TestSlack_Notify(t *testing.T) {
+	var msg map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&msg)
+	}))
+	defer srv.Close()
+
+	s := &Slack{URL: srv.URL, Channel: "#ops"}
+	if err := s.Notify(context.Background(), testEvent()); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if !strings.Contains(msg["text"], "job backup failed on node-1 (exit code 3)") {
+		t.Errorf("text = %q, want the event summary", msg["text"])
+	}
+	if !strings.Contains(msg["text"], "disk full") {
+		t.Errorf("text = %q, want the stderr", msg["text"])
+	}
+	if msg["channel"] != "#ops" {
+		t.Errorf("channel = %q, want %q", msg["channel"], "#ops")
+	}
+	if _, ok := msg["username"]; ok {
+		t.Errorf("username = %q, want it omitted", msg["username"])
+	}
+}
+
+// smtpStub is a minimal SMTP server that accepts every message.
+type smtpStub struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	rcpts []string
+	data  string
+}
+
+func newSMTPStub(t *testing.T) *smtpStub {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &smtpStub{ln: ln}
+	go s.serve()
+	t.Cleanup(func() { ln.Close() })
+	return s
+}
+
+func (s *smtpStub) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpStub) serve() {
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *smtpStub) handle(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = io.WriteString(conn, line+"\r\n") }
+
+	reply("220 localhost ESMTP stub")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			reply("250 localhost")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.mu.Lock()
+			s.rcpts = append(s.rcpts, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "DATA":
+			reply("354 Go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			s.mu.Lock()
+			s.data = data.String()
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmail_Notify(t *testing.T) {
+	stub := newSMTPStub(t)
+
+	m := &Email{SMTP: config.SMTPConfig{
+		Host: "127.0.0.1",
+		Port: stub.port(),
+		From: "cronlock@example.com",
+		To:   []string{"ops@example.com", "oncall@example.com"},
+	}}
+	e := testEvent()
+	e.Time = time.Now()
+	if err := m.Notify(context.Background(), e); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	stub.mu.Lock()
+	defer stub.mu.Unlock()
+	if strings.Join(stub.rcpts, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("recipients = %v, want both", stub.rcpts)
+	}
+	for _, want := range []string{
+		"Subject: cronlock: job backup failed on node-1 (exit code 3)\r\n",
+		"To: ops@example.com, oncall@example.com\r\n",
+		"Exit code: 3\r\n",
+		"disk full\r\n",
+	} {
+		if !strings.Contains(stub.data, want) {
+			t.Errorf("message does not contain %q:\n%s", want, stub.data)
+		}
+	}
+}
+
+// flakyNotifier fails its first failures calls.
+type flakyNotifier struct {
+	failures int32
+	err      error
+	calls    atomic.Int32
+	sent     chan Event
+}
+
+func (n *flakyNotifier) Notify(ctx context.Context, e Event) error {
+	if n.calls.Add(1) <= n.failures {
+		return n.err
+	}
+	n.sent <- e
+	return nil
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	n := &flakyNotifier{failures: 2, err: io.ErrUnexpectedEOF, sent: make(chan Event, 1)}
+	d, err := NewDispatcher(nil, testLogger(),
+		WithBackoff(time.Millisecond),
+		WithNotifier("flaky", n, time.Second, 3),
+	)
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	defer d.Close(context.Background())
+
+	d.Send([]string{"flaky"}, testEvent())
+	select {
+	case e := <-n.sent:
+		if e.Job != "backup" || e.Time.IsZero() {
+			t.Errorf("sent %+v, want the event with its time set", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event was not delivered")
+	}
+	if got := n.calls.Load(); got != 3 {
+		t.Errorf("Notify() called %d times, want 3", got)
+	}
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		wantCalls int32
+	}{
+		{"retries exhausted", io.ErrUnexpectedEOF, 3},
+		{"permanent error", &permanentError{io.ErrUnexpectedEOF}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := &flakyNotifier{failures: 100, err: tt.err, sent: make(chan Event, 1)}
+			d, _ := NewDispatcher(nil, testLogger(),
+				WithBackoff(time.Millisecond),
+				WithNotifier("broken", n, time.Second, 2),
+			)
+			d.Send([]string{"broken"}, testEvent())
+			d.Close(context.Background())
+
+			if got := n.calls.Load(); got != tt.wantCalls {
+				t.Errorf("Notify() called %d times, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+// blockingNotifier blocks until its context is done.
+type blockingNotifier struct{}
+
+func (blockingNotifier) Notify(ctx context.Context, e Event) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestDispatcher_SendDoesNotBlock(t *testing.T) {
+	d, _ := NewDispatcher(nil, testLogger(), WithNotifier("stuck", blockingNotifier{}, time.Hour, 0))
+
+	start := time.Now()
+	for range defaultQueueSize * 2 {
+		d.Send([]string{"stuck", "unknown"}, testEvent())
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Send() took %v with a stuck notifier, want it not to block", elapsed)
+	}
+
+	// Close gives up on the stuck deliveries once its context is done
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	d.Close(ctx)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Close() took %v, want it to return after its context is done", elapsed)
+	}
+
+	// Sending after Close is a no-op
+	d.Send([]string{"stuck"}, testEvent())
+}
+
+func TestTail(t *testing.T) {
+	if got := tail("short", 10); got != "short" {
+		t.Errorf("tail() = %q, want %q", got, "short")
+	}
+	if got := tail("0123456789", 4); got != "[...]6789" {
+		t.Errorf("tail() = %q, want %q", got, "[...]6789")
+	}
+}
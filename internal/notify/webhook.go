@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of a signed webhook's body, as
+// "sha256=<hex>".
+const SignatureHeader = "X-Cronlock-Signature"
+
+// Webhook posts events as JSON to a URL.
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	// Secret, if set, signs each body in the SignatureHeader.
+	Secret string
+}
+
+// Notify posts the event.
+func (w *Webhook) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	headers := make(map[string]string, len(w.Headers)+1)
+	maps.Copy(headers, w.Headers)
+	if w.Secret != "" {
+		headers[SignatureHeader] = "sha256=" + Sign(w.Secret, body)
+	}
+	return postJSON(ctx, w.URL, body, headers)
+}
+
+// Sign returns the hex HMAC-SHA256 of body with secret, for receivers to
+// check the SignatureHeader against.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Slack posts events as messages to a Slack incoming webhook, or any
+// compatible one such as Mattermost's.
+type Slack struct {
+	URL string
+	// Channel and Username, if set, override the webhook's defaults.
+	Channel  string
+	Username string
+}
+
+// slackMessage is the payload of an incoming webhook.
+type slackMessage struct {
+	Text     string `json:"text"`
+	Channel  string `json:"channel,omitempty"`
+	Username string `json:"username,omitempty"`
+}
+
+// Notify posts the event as a message.
+func (s *Slack) Notify(ctx context.Context, e Event) error {
+	body, err := json.Marshal(slackMessage{
+		Text:     "*" + e.Summary() + "*\n```\n" + e.Details() + "```",
+		Channel:  s.Channel,
+		Username: s.Username,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+	return postJSON(ctx, s.URL, body, nil)
+}
+
+// postJSON posts a JSON body. A 4xx response other than 408 and 429 is a
+// permanent error.
+func postJSON(ctx context.Context, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return &permanentError{fmt.Errorf("failed to create request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "cronlock")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	err = fmt.Errorf("unexpected response status %s", resp.Status)
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+		resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,186 @@
+// Package runlog writes job output to per-run log files and prunes old ones.
+package runlog
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileExt is the extension of run log files. Pruning only touches files
+// with this extension.
+const fileExt = ".log"
+
+// JobDir returns the directory holding a job's run logs. Path separators
+// in the job name are replaced so every job stays inside dir.
+func JobDir(dir, job string) string {
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(job)
+	if name == "." || name == ".." {
+		name = "_" + name
+	}
+	return filepath.Join(dir, name)
+}
+
+// Path returns the log file for one run of a job.
+func Path(dir, job, name string) string {
+	return filepath.Join(JobDir(dir, job), name+fileExt)
+}
+
+// File is a run log file shared by a command's stdout and stderr.
+// Writes never fail, so a full disk doesn't break the command's output
+// pipes; the first error is reported by Close.
+type File struct {
+	mu  sync.Mutex
+	f   *os.File
+	err error
+}
+
+// Create creates the log file for one run of a job, creating the job's
+// directory if needed.
+func Create(dir, job, name string) (*File, error) {
+	if err := os.MkdirAll(JobDir(dir, job), 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create log directory: %w", err)
+	}
+	f, err := os.OpenFile(Path(dir, job, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create log file: %w", err)
+	}
+	return &File{f: f}, nil
+}
+
+// Name returns the file's path.
+func (f *File) Name() string {
+	return f.f.Name()
+}
+
+// Write implements io.Writer. It is safe for concurrent use.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err == nil {
+		_, f.err = f.f.Write(p)
+	}
+	return len(p), nil
+}
+
+// Close closes the file, returning the first write error if there was one.
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return errors.Join(f.err, f.f.Close())
+}
+
+// Prune removes a job's oldest log files so that at most keep remain, and
+// any older than maxAge. Zero disables either limit. It returns how many
+// files were removed.
+func Prune(dir, job string, keep int, maxAge time.Duration, now time.Time) (int, error) {
+	if keep <= 0 && maxAge <= 0 {
+		return 0, nil
+	}
+
+	entries, err := os.ReadDir(JobDir(dir, job))
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read log directory: %w", err)
+	}
+
+	type logFile struct {
+		path    string
+		modTime time.Time
+	}
+	var files []logFile
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || filepath.Ext(entry.Name()) != fileExt {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue // removed since ReadDir
+		}
+		files = append(files, logFile{filepath.Join(JobDir(dir, job), entry.Name()), info.ModTime()})
+	}
+
+	// Newest first. Run IDs sort by start time, so they break ties
+	// between files written within the file system's timestamp resolution.
+	slices.SortFunc(files, func(a, b logFile) int {
+		if c := b.modTime.Compare(a.modTime); c != 0 {
+			return c
+		}
+		return strings.Compare(b.path, a.path)
+	})
+
+	removed := 0
+	var errs []error
+	for i, file := range files {
+		tooMany := keep > 0 && i >= keep
+		tooOld := maxAge > 0 && now.Sub(file.modTime) > maxAge
+		if !tooMany && !tooOld {
+			continue
+		}
+		if err := os.Remove(file.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		removed++
+	}
+	return removed, errors.Join(errs...)
+}
+
+// maxLineLength bounds a buffered line, so output without newlines can't
+// grow the buffer without bound. Longer lines are split.
+const maxLineLength = 16 * 1024
+
+// LineWriter is an io.Writer that calls a function for each line written
+// to it, without the trailing newline. It is not safe for concurrent use;
+// use one per stream.
+type LineWriter struct {
+	fn  func(line string)
+	buf []byte
+}
+
+// NewLineWriter creates a LineWriter calling fn for each line.
+func NewLineWriter(fn func(line string)) *LineWriter {
+	return &LineWriter{fn: fn}
+}
+
+// Write implements io.Writer. It never fails.
+func (w *LineWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := slices.Index(p, '\n')
+		if i == -1 {
+			w.buf = append(w.buf, p...)
+			for len(w.buf) >= maxLineLength {
+				w.emit(w.buf[:maxLineLength])
+				w.buf = append(w.buf[:0], w.buf[maxLineLength:]...)
+			}
+			break
+		}
+		w.buf = append(w.buf, p[:i]...)
+		w.emit(w.buf)
+		w.buf = w.buf[:0]
+		p = p[i+1:]
+	}
+	return n, nil
+}
+
+// Flush emits any buffered partial line.
+func (w *LineWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = w.buf[:0]
+	}
+}
+
+// emit passes a line to fn, dropping a trailing carriage return.
+func (w *LineWriter) emit(line []byte) {
+	w.fn(strings.TrimSuffix(string(line), "\r"))
+}
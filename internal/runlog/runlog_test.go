@@ -0,0 +1,208 @@
+package runlog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestJobDir(t *testing.T) {
+	tests := []struct {
+		job  string
+		want string
+	}{
+		{"backup", "/logs/backup"},
+		{"team/backup", "/logs/team_backup"},
+		{"..", "/logs/_.."},
+		{"../etc", "/logs/.._etc"},
+	}
+
+	for _, tt := range tests {
+		if got := JobDir("/logs", tt.job); got != tt.want {
+			t.Errorf("JobDir(%q) = %q, want %q", tt.job, got, tt.want)
+		}
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+
+	f, err := Create(dir, "backup", "run-1")
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if want := filepath.Join(dir, "backup", "run-1.log"); f.Name() != want {
+		t.Errorf("Name() = %q, want %q", f.Name(), want)
+	}
+
+	var wg sync.WaitGroup
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = f.Write([]byte("line\n"))
+		}()
+	}
+	wg.Wait()
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != strings.Repeat("line\n", 10) {
+		t.Errorf("file contents = %q, want 10 lines", got)
+	}
+
+	// Run IDs are unique, so an existing file is an error
+	if _, err := Create(dir, "backup", "run-1"); err == nil {
+		t.Error("Create() of an existing file error = nil, want error")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	now := time.Now()
+	// Files from oldest to newest, one hour apart
+	files := []string{"a.log", "b.log", "c.log", "d.log", "e.log"}
+
+	tests := []struct {
+		name        string
+		keep        int
+		maxAge      time.Duration
+		wantRemoved int
+		wantKept    []string
+	}{
+		{
+			name:        "no limits",
+			wantRemoved: 0,
+			wantKept:    []string{"a.log", "b.log", "c.log", "d.log", "e.log", "notes.txt"},
+		},
+		{
+			name:        "keep newest",
+			keep:        2,
+			wantRemoved: 3,
+			wantKept:    []string{"d.log", "e.log", "notes.txt"},
+		},
+		{
+			name:        "max age",
+			maxAge:      150 * time.Minute,
+			wantRemoved: 2,
+			wantKept:    []string{"c.log", "d.log", "e.log", "notes.txt"},
+		},
+		{
+			name:        "both limits",
+			keep:        4,
+			maxAge:      210 * time.Minute,
+			wantRemoved: 1,
+			wantKept:    []string{"b.log", "c.log", "d.log", "e.log", "notes.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			jobDir := JobDir(dir, "backup")
+			if err := os.MkdirAll(jobDir, 0o750); err != nil {
+				t.Fatal(err)
+			}
+			for i, name := range append(files, "notes.txt") {
+				path := filepath.Join(jobDir, name)
+				if err := os.WriteFile(path, nil, 0o640); err != nil {
+					t.Fatal(err)
+				}
+				age := time.Duration(len(files)-1-i) * time.Hour
+				if name == "notes.txt" {
+					age = 24 * time.Hour
+				}
+				if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			removed, err := Prune(dir, "backup", tt.keep, tt.maxAge, now)
+			if err != nil {
+				t.Fatalf("Prune() error = %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("Prune() removed = %d, want %d", removed, tt.wantRemoved)
+			}
+
+			entries, _ := os.ReadDir(jobDir)
+			var kept []string
+			for _, entry := range entries {
+				kept = append(kept, entry.Name())
+			}
+			if !slices.Equal(kept, tt.wantKept) {
+				t.Errorf("kept files = %v, want %v", kept, tt.wantKept)
+			}
+		})
+	}
+}
+
+func TestPrune_MissingDir(t *testing.T) {
+	removed, err := Prune(t.TempDir(), "never-ran", 5, 0, time.Now())
+	if err != nil || removed != 0 {
+		t.Errorf("Prune() = %d, %v, want 0, nil", removed, err)
+	}
+}
+
+func TestLineWriter(t *testing.T) {
+	long := strings.Repeat("x", maxLineLength)
+
+	tests := []struct {
+		name   string
+		writes []string
+		want   []string
+	}{
+		{
+			name:   "whole lines",
+			writes: []string{"one\ntwo\n"},
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "lines split across writes",
+			writes: []string{"on", "e\ntw", "o\n"},
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "partial last line is flushed",
+			writes: []string{"one\ntwo"},
+			want:   []string{"one", "two"},
+		},
+		{
+			name:   "empty lines and CRLF",
+			writes: []string{"one\r\n\ntwo\r\n"},
+			want:   []string{"one", "", "two"},
+		},
+		{
+			name:   "long line is split",
+			writes: []string{long, "tail\n"},
+			want:   []string{long, "tail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			w := NewLineWriter(func(line string) {
+				got = append(got, line)
+			})
+			for _, s := range tt.writes {
+				if n, err := w.Write([]byte(s)); n != len(s) || err != nil {
+					t.Fatalf("Write() = %d, %v, want %d, nil", n, err, len(s))
+				}
+			}
+			w.Flush()
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package scheduler
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"cronlock/internal/config"
+)
+
+// ApplyJobs replaces the scheduler's jobs with jobs, the full job list of a
+// new version of the job configuration. Unchanged jobs keep their schedule
+// and state; new jobs are added, removed jobs unscheduled and changed jobs
+// replaced. A removed or replaced job that is running finishes its run,
+// holding its lock, so its replacement skips until then. If a job can't be
+// created, the error is returned and the scheduler keeps its current jobs.
+//
+// version is the version of the job configuration and modTime when it was
+// pushed, reported in the node's registry heartbeats; see WithConfigVersion.
+// ApplyJobs must not be called concurrently with Start or Stop.
+func (s *Scheduler) ApplyJobs(jobs []config.JobConfig, version int, modTime time.Time) error {
+	current := s.Jobs()
+	configHash, err := config.JobsHash(jobs)
+	if err != nil {
+		return err
+	}
+
+	// Create every new job before changing anything
+	keep := make(map[string]bool, len(jobs))
+	var added []*Job
+	for _, cfg := range jobs {
+		hash, err := cfg.Hash()
+		if err != nil {
+			return fmt.Errorf("job %s: %w", cfg.Name, err)
+		}
+		if job, ok := current[cfg.Name]; ok && job.configHash == hash {
+			keep[cfg.Name] = true
+			continue
+		}
+		job, err := s.buildJob(cfg)
+		if err != nil {
+			return err
+		}
+		if job != nil {
+			job.setConfigVersion(version, modTime)
+			added = append(added, job)
+		}
+	}
+
+	var removed []string
+	s.mu.Lock()
+	s.retired = slices.DeleteFunc(s.retired, func(j *Job) bool { return !j.IsRunning() })
+	for name, job := range current {
+		if keep[name] {
+			// The unchanged job is current with the new version too
+			job.setConfigVersion(version, modTime)
+			continue
+		}
+		s.cron.Remove(job.entryID)
+		delete(s.jobs, name)
+		if job.IsRunning() {
+			s.retired = append(s.retired, job)
+		}
+		removed = append(removed, name)
+	}
+	s.monitorJobs = monitoredJobs(jobs)
+	s.configHash = configHash
+	s.configVersion = version
+	s.configModTime = modTime
+	s.mu.Unlock()
+
+	for _, name := range removed {
+		s.logger.Info("removed job", "job", name)
+	}
+	for _, job := range added {
+		if err := s.scheduleJob(job); err != nil {
+			// The schedule was validated when the job was created
+			s.logger.Error("failed to schedule job", "job", job.Name(), "error", err)
+		}
+	}
+
+	if !s.startedAt.IsZero() && s.monitorDone == nil {
+		s.startMonitor()
+	}
+
+	s.logger.Info("applied job configuration",
+		"config_version", version,
+		"added", len(added),
+		"removed", len(removed),
+		"job_count", len(s.Jobs()),
+	)
+	return nil
+}
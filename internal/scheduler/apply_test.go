@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestScheduler_ApplyJobs(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger())
+	jobs := []config.JobConfig{
+		{Name: "same", Schedule: "* * * * *", Command: config.ShellCommand("true")},
+		{Name: "changed", Schedule: "* * * * *", Command: config.ShellCommand("true")},
+		{Name: "removed", Schedule: "* * * * *", Command: config.ShellCommand("true")},
+	}
+	for _, cfg := range jobs {
+		if err := s.AddJob(cfg); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+	}
+	before := s.Jobs()
+
+	disabled := false
+	err := s.ApplyJobs([]config.JobConfig{
+		jobs[0],
+		{Name: "changed", Schedule: "*/5 * * * *", Command: config.ShellCommand("true")},
+		{Name: "added", Schedule: "* * * * *", Command: config.ShellCommand("true"), ExpectSuccessWithin: time.Hour},
+		{Name: "disabled", Schedule: "* * * * *", Command: config.ShellCommand("true"), Enabled: &disabled},
+	}, 7, time.Now())
+	if err != nil {
+		t.Fatalf("ApplyJobs() error = %v", err)
+	}
+
+	after := s.Jobs()
+	if len(after) != 3 {
+		t.Fatalf("Jobs() = %v, want same, changed and added", after)
+	}
+	if after["same"] != before["same"] {
+		t.Error("unchanged job was replaced, want it kept")
+	}
+	for _, name := range []string{"same", "changed"} {
+		if v := after[name].version(); v.ConfigVersion != 7 {
+			t.Errorf("%s version() = %+v, want config version 7", name, v)
+		}
+	}
+	if after["changed"] == before["changed"] || after["changed"].Config().Schedule != "*/5 * * * *" {
+		t.Error("changed job was not replaced with its new configuration")
+	}
+	if _, ok := after["removed"]; ok {
+		t.Error("removed job is still scheduled")
+	}
+	if _, ok := after["added"]; !ok {
+		t.Error("added job is not scheduled")
+	}
+	if got := len(s.Entries()); got != 3 {
+		t.Errorf("len(Entries()) = %d, want 3", got)
+	}
+	if got := s.monitored(); len(got) != 1 || got[0].Name != "added" {
+		t.Errorf("monitored() = %v, want [added]", got)
+	}
+	if s.configVersion != 7 || s.configHash == "" {
+		t.Errorf("configVersion = %d, configHash = %q, want 7 and the new hash", s.configVersion, s.configHash)
+	}
+}
+
+func TestScheduler_ApplyJobs_InvalidKeepsJobs(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger())
+	if err := s.AddJob(config.JobConfig{Name: "old", Schedule: "* * * * *", Command: config.ShellCommand("true")}); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+
+	// Sharded jobs need a state store, which this scheduler lacks
+	err := s.ApplyJobs([]config.JobConfig{
+		{Name: "sharded", Schedule: "* * * * *", Command: config.ShellCommand("true"), Shards: 2},
+	}, 2, time.Now())
+	if err == nil {
+		t.Fatal("ApplyJobs() error = nil, want an error")
+	}
+	if _, ok := s.GetJob("old"); !ok || len(s.Jobs()) != 1 {
+		t.Errorf("Jobs() = %v, want the old job kept", s.Jobs())
+	}
+	if len(s.Entries()) != 1 {
+		t.Errorf("len(Entries()) = %d, want 1", len(s.Entries()))
+	}
+}
+
+func TestScheduler_ApplyJobs_StopWaitsForRemovedJob(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger(), WithStore(state.NewMockStore()))
+	if err := s.AddJob(config.JobConfig{Name: "slow", Schedule: "* * * * *", Command: config.ShellCommand("sleep 0.3")}); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	job, _ := s.GetJob("slow")
+	go job.Run()
+	waitFor(t, time.Second, job.IsRunning)
+
+	if err := s.ApplyJobs(nil, 2, time.Now()); err != nil {
+		t.Fatalf("ApplyJobs() error = %v", err)
+	}
+	if len(s.Jobs()) != 0 {
+		t.Errorf("Jobs() = %v, want none", s.Jobs())
+	}
+	if !job.IsRunning() {
+		t.Error("removed job was stopped, want its run to finish")
+	}
+	if got := s.RunningJobs(); len(got) != 1 || got[0] != "slow" {
+		t.Errorf("RunningJobs() = %v, want [slow]", got)
+	}
+
+	s.Stop()
+	if job.IsRunning() {
+		t.Error("Stop() returned while the removed job was running")
+	}
+}
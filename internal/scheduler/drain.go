@@ -0,0 +1,153 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+)
+
+// StatusDraining is reported while the node finishes running jobs without
+// taking new ones.
+const StatusDraining = "draining"
+
+// drainPollInterval is how often a draining node checks whether its
+// running jobs have finished.
+const drainPollInterval = 500 * time.Millisecond
+
+// Drain stops the node from taking new jobs while running jobs finish.
+// With exit set, Drained is closed once no jobs are running.
+func (s *Scheduler) Drain(exit bool) {
+	s.mu.Lock()
+	if s.draining && (s.drainExit || !exit) {
+		s.mu.Unlock()
+		return
+	}
+	wasDraining := s.draining
+	s.draining = true
+	s.drainExit = exit
+	s.mu.Unlock()
+
+	if !wasDraining {
+		s.logger.Info("draining node, no new jobs will start", "running_jobs", s.RunningJobs(), "exit_when_drained", exit)
+		s.notifyStatus()
+	}
+	if exit {
+		go s.waitDrained()
+	}
+
+	// In leader mode a draining leader hands over right away instead of
+	// holding the lease while refusing to run jobs.
+	s.wakeElection()
+}
+
+// Undrain lets the node take new jobs again.
+func (s *Scheduler) Undrain() {
+	s.mu.Lock()
+	if !s.draining {
+		s.mu.Unlock()
+		return
+	}
+	s.draining = false
+	s.drainExit = false
+	s.mu.Unlock()
+
+	s.logger.Info("node undrained, accepting jobs")
+	s.notifyStatus()
+	s.wakeElection()
+}
+
+// IsDraining reports whether the node is draining.
+func (s *Scheduler) IsDraining() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.draining
+}
+
+// Drained returns a channel closed once a drain requested with exit has
+// no running jobs left.
+func (s *Scheduler) Drained() <-chan struct{} {
+	return s.drained
+}
+
+// RunningJobs returns the names of jobs currently running on this node,
+// including jobs ApplyJobs removed or replaced while they were running.
+func (s *Scheduler) RunningJobs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var names []string
+	for name, job := range s.jobs {
+		if job.IsRunning() {
+			names = append(names, name)
+		}
+	}
+	for _, job := range s.retired {
+		if job.IsRunning() {
+			names = append(names, job.Name())
+		}
+	}
+	return names
+}
+
+// waitDrained closes s.drained once no jobs are running, unless the node is
+// undrained first.
+func (s *Scheduler) waitDrained() {
+	ticker := time.NewTicker(drainPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.mu.Lock()
+		draining, exit := s.draining, s.drainExit
+		s.mu.Unlock()
+		if !draining || !exit {
+			return
+		}
+
+		if len(s.RunningJobs()) == 0 {
+			s.logger.Info("node drained, no running jobs")
+			s.clearExitRequest()
+			s.drainedOnce.Do(func() { close(s.drained) })
+			return
+		}
+		<-ticker.C
+	}
+}
+
+// clearExitRequest withdraws a drain-and-exit request from the state store
+// so the node doesn't exit again as soon as it is restarted.
+func (s *Scheduler) clearExitRequest() {
+	s.mu.Lock()
+	fromStore := s.drainRequested && s.drainRequestExit
+	s.mu.Unlock()
+	if !fromStore {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.store.ClearDrain(ctx, s.node.ID); err != nil {
+		s.logger.Warn("failed to clear drain request", "error", err)
+	}
+}
+
+// syncDrainRequest applies a drain request made through the state store.
+// Requests are edge-triggered so that they don't override a drain started
+// locally (e.g. by SIGUSR1) or undo a local undrain.
+func (s *Scheduler) syncDrainRequest(ctx context.Context) {
+	requested, exit, err := s.store.DrainRequest(ctx, s.node.ID)
+	if err != nil {
+		s.logger.Warn("failed to read drain request", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	prevRequested, prevExit := s.drainRequested, s.drainRequestExit
+	s.drainRequested, s.drainRequestExit = requested, exit
+	s.mu.Unlock()
+
+	switch {
+	case requested && (!prevRequested || exit != prevExit):
+		s.Drain(exit)
+	case !requested && prevRequested:
+		s.Undrain()
+	}
+}
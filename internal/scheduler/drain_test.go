@@ -0,0 +1,159 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestScheduler_Drain_SkipsAcquire(t *testing.T) {
+	locker := lock.NewMockLocker()
+	recorder := &statusRecorder{}
+	s := New(locker, config.NodeConfig{}, newTestLogger(), WithStatusHandler(recorder.record))
+
+	if err := s.AddJob(config.JobConfig{Name: "test-job", Schedule: "* * * * *", Command: config.ShellCommand("true")}); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	job, _ := s.GetJob("test-job")
+
+	s.Drain(false)
+	if s.Status() != StatusDraining {
+		t.Errorf("Status() = %q, want %q", s.Status(), StatusDraining)
+	}
+	if recorder.last() != StatusDraining {
+		t.Errorf("last status = %q, want %q", recorder.last(), StatusDraining)
+	}
+
+	job.Run()
+	if len(locker.AcquireCalls) != 0 {
+		t.Errorf("Acquire() called %d times while draining, want 0", len(locker.AcquireCalls))
+	}
+
+	s.Undrain()
+	if s.IsDraining() {
+		t.Error("IsDraining() = true after Undrain()")
+	}
+	job.Run()
+	if len(locker.AcquireCalls) != 1 {
+		t.Errorf("Acquire() called %d times after undrain, want 1", len(locker.AcquireCalls))
+	}
+}
+
+func TestScheduler_Drain_ExitWhenIdle(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger())
+	if err := s.AddJob(config.JobConfig{Name: "slow", Schedule: "* * * * *", Command: config.ShellCommand("sleep 0.3")}); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+	job, _ := s.GetJob("slow")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run()
+	}()
+	waitFor(t, time.Second, job.IsRunning)
+
+	s.Drain(true)
+
+	select {
+	case <-s.Drained():
+		t.Fatal("Drained() closed while a job was still running")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-done
+	select {
+	case <-s.Drained():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drained() not closed after running jobs finished")
+	}
+}
+
+func TestScheduler_Drain_WithoutExitNeverCloses(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger())
+	s.Drain(false)
+
+	select {
+	case <-s.Drained():
+		t.Fatal("Drained() closed for a drain without exit")
+	case <-time.After(2 * drainPollInterval):
+	}
+}
+
+func TestScheduler_SyncDrainRequest(t *testing.T) {
+	store := state.NewMockStore()
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger(), WithStore(store))
+	ctx := context.Background()
+
+	_ = store.SetDrain(ctx, "node-1", false)
+	s.syncDrainRequest(ctx)
+	if !s.IsDraining() {
+		t.Fatal("IsDraining() = false after drain request")
+	}
+
+	// A local undrain sticks while the request is unchanged
+	s.Undrain()
+	s.syncDrainRequest(ctx)
+	if s.IsDraining() {
+		t.Error("unchanged drain request re-drained the node")
+	}
+
+	// Withdrawing the request undrains a node drained by it
+	s.Drain(false)
+	_ = store.ClearDrain(ctx, "node-1")
+	s.syncDrainRequest(ctx)
+	if s.IsDraining() {
+		t.Error("IsDraining() = true after drain request was cleared")
+	}
+
+	// Requests for other nodes are ignored
+	_ = store.SetDrain(ctx, "node-2", false)
+	s.syncDrainRequest(ctx)
+	if s.IsDraining() {
+		t.Error("drain request for another node drained this node")
+	}
+}
+
+func TestScheduler_Drain_ExitClearsRequest(t *testing.T) {
+	store := state.NewMockStore()
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger(), WithStore(store))
+	ctx := context.Background()
+
+	_ = store.SetDrain(ctx, "node-1", true)
+	s.syncDrainRequest(ctx)
+
+	select {
+	case <-s.Drained():
+	case <-time.After(2 * time.Second):
+		t.Fatal("Drained() not closed for idle node")
+	}
+
+	if requested, _, _ := store.DrainRequest(ctx, "node-1"); requested {
+		t.Error("drain-and-exit request should be cleared once the node has drained")
+	}
+}
+
+func TestScheduler_Drain_LeaderStepsDown(t *testing.T) {
+	lease := lock.NewMockLease()
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+	)
+
+	s.Start()
+	defer s.Stop()
+	waitFor(t, time.Second, s.IsLeader)
+
+	s.Drain(false)
+	waitFor(t, time.Second, func() bool { return !s.IsLeader() })
+
+	if s.Status() != StatusDraining {
+		t.Errorf("Status() = %q, want %q", s.Status(), StatusDraining)
+	}
+
+	s.Undrain()
+	waitFor(t, time.Second, s.IsLeader)
+}
@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/state"
+)
+
+// jobVersions returns the configuration of each job this node schedules,
+// for its registry heartbeat.
+func (s *Scheduler) jobVersions() map[string]state.JobVersion {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	versions := make(map[string]state.JobVersion, len(s.jobs))
+	for name, job := range s.jobs {
+		versions[name] = job.version()
+	}
+	return versions
+}
+
+// peerVersions returns the configuration of a job on each peer that
+// schedules it, by node ID, as of their last heartbeat.
+func (l *nodeLoad) peerVersions(jobName string) map[string]state.JobVersion {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	versions := make(map[string]state.JobVersion)
+	for _, peer := range l.peers {
+		if v, ok := peer.JobConfigs[jobName]; ok && peer.ID != l.nodeID {
+			versions[peer.ID] = v
+		}
+	}
+	return versions
+}
+
+// version identifies the job's configuration for its node's heartbeat.
+func (j *Job) version() state.JobVersion {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return state.JobVersion{Hash: j.configHash, ConfigVersion: j.configVersion, ModTime: j.configModTime}
+}
+
+// setConfigVersion records the configuration the job is current with.
+func (j *Job) setConfigVersion(version int, modTime time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.configVersion, j.configModTime = version, modTime
+}
+
+// newerConfig reports whether a is a newer configuration than b: a higher
+// pushed config version if both were pushed, otherwise a later modification
+// time. Ties are broken by hash, so exactly one configuration wins.
+func newerConfig(a, b state.JobVersion) bool {
+	switch {
+	case a.ConfigVersion > 0 && b.ConfigVersion > 0 && a.ConfigVersion != b.ConfigVersion:
+		return a.ConfigVersion > b.ConfigVersion
+	case !a.ModTime.Equal(b.ModTime):
+		return a.ModTime.After(b.ModTime)
+	}
+	return a.Hash > b.Hash
+}
+
+// driftPolicy returns the job's drift policy.
+func (j *Job) driftPolicy() string {
+	if j.config.DriftPolicy != "" {
+		return j.config.DriftPolicy
+	}
+	if j.nodeDriftPolicy != "" {
+		return j.nodeDriftPolicy
+	}
+	return config.DriftWarn
+}
+
+// skipForDrift compares the job's configuration with its live peers' and
+// applies the job's drift policy, reporting whether this node should skip
+// the run.
+func (j *Job) skipForDrift() bool {
+	if j.load == nil {
+		return false
+	}
+	drifted := make(map[string]state.JobVersion)
+	for node, v := range j.load.peerVersions(j.config.Name) {
+		if v.Hash != j.configHash {
+			drifted[node] = v
+		}
+	}
+	if len(drifted) == 0 {
+		return false
+	}
+
+	nodes := slices.Sorted(maps.Keys(drifted))
+	var peers []string
+	newer := false
+	for _, node := range nodes {
+		v := drifted[node]
+		peers = append(peers, node+"="+v.Hash)
+		if newerConfig(v, j.version()) {
+			newer = true
+		}
+	}
+	logger := j.logger.With("config_hash", j.configHash, "peers", strings.Join(peers, ","), "drift_policy", j.driftPolicy())
+
+	switch j.driftPolicy() {
+	case config.DriftRefuseToRun:
+		logger.Error("job configuration differs from other nodes, refusing to run")
+		return true
+	case config.DriftNewestWins:
+		if newer {
+			logger.Warn("job configuration differs from other nodes, leaving the run to a node with a newer one")
+			return true
+		}
+		logger.Warn("job configuration differs from other nodes, running as this node's is the newest")
+		return false
+	}
+	logger.Warn("job configuration differs from other nodes")
+	return false
+}
@@ -0,0 +1,160 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestJob_Run_DriftPolicy(t *testing.T) {
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		policy   string
+		peerHash string
+		peerAge  time.Duration // how much older the peer's config file is than this node's
+		wantRun  bool
+	}{
+		{"in sync", config.DriftRefuseToRun, "", 0, true},
+		{"warn", config.DriftWarn, "other", 0, true},
+		{"refuse to run", config.DriftRefuseToRun, "other", time.Hour, false},
+		{"newest wins, this node newer", config.DriftNewestWins, "other", time.Hour, true},
+		{"newest wins, peer newer", config.DriftNewestWins, "other", -time.Hour, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.JobConfig{
+				Name:        "drift-job",
+				Command:     config.ShellCommand("true"),
+				DriftPolicy: tt.policy,
+			}
+			locker := lock.NewMockLocker()
+			job := newTestJob(cfg, locker)
+			job.nodeID = "node-1"
+			job.configHash, _ = cfg.Hash()
+			job.setConfigVersion(0, modTime)
+			job.load = newNodeLoad("node-1", 0)
+
+			peerHash := tt.peerHash
+			if peerHash == "" {
+				peerHash = job.configHash
+			}
+			job.load.setPeers([]state.NodeInfo{
+				{ID: "node-1", JobConfigs: map[string]state.JobVersion{"drift-job": {Hash: job.configHash, ModTime: modTime}}},
+				{ID: "node-2", JobConfigs: map[string]state.JobVersion{"drift-job": {Hash: peerHash, ModTime: modTime.Add(-tt.peerAge)}}},
+				{ID: "node-3", JobConfigs: map[string]state.JobVersion{"other-job": {Hash: "unrelated"}}},
+			})
+
+			job.Run()
+			if ran := len(locker.AcquireCalls) > 0; ran != tt.wantRun {
+				t.Errorf("ran = %v, want %v", ran, tt.wantRun)
+			}
+		})
+	}
+}
+
+func TestNewerConfig(t *testing.T) {
+	older := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	newer := older.Add(time.Hour)
+	tests := []struct {
+		name string
+		a, b state.JobVersion
+		want bool
+	}{
+		{"higher pushed version", state.JobVersion{ConfigVersion: 5, ModTime: older}, state.JobVersion{ConfigVersion: 4, ModTime: newer}, true},
+		{"lower pushed version", state.JobVersion{ConfigVersion: 4, ModTime: newer}, state.JobVersion{ConfigVersion: 5, ModTime: older}, false},
+		{"newer file", state.JobVersion{ModTime: newer}, state.JobVersion{ModTime: older}, true},
+		{"older file", state.JobVersion{ModTime: older}, state.JobVersion{ModTime: newer}, false},
+		{"file and pushed version", state.JobVersion{ModTime: newer}, state.JobVersion{ConfigVersion: 3, ModTime: older}, true},
+		{"tie broken by hash", state.JobVersion{Hash: "b", ModTime: older}, state.JobVersion{Hash: "a", ModTime: older}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newerConfig(tt.a, tt.b); got != tt.want {
+				t.Errorf("newerConfig() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// A stale node restarting doesn't make its configuration the newest: two
+// nodes run backup-v2.sh, pushed as version 5, and a third restarts with
+// version 4's backup-v1.sh.
+func TestScheduler_DriftPolicy_PeerRestartsWithOlderConfig(t *testing.T) {
+	v2 := config.JobConfig{Name: "backup", Schedule: "* * * * *", Command: config.ShellCommand("backup-v2.sh"), DriftPolicy: config.DriftNewestWins}
+	v1 := v2
+	v1.Command = config.ShellCommand("backup-v1.sh")
+	pushedAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newNode := func(id string, cfg config.JobConfig, version int, modTime time.Time) (*Scheduler, *lock.MockLocker) {
+		locker := lock.NewMockLocker()
+		s := New(locker, config.NodeConfig{ID: id}, newTestLogger(), WithConfigVersion(version, modTime))
+		if err := s.AddJob(cfg); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+		return s, locker
+	}
+	current, currentLocker := newNode("node-1", v2, 5, pushedAt)
+	// The stale node started after node-1
+	stale, staleLocker := newNode("node-3", v1, 4, pushedAt.Add(-time.Hour))
+
+	peers := []state.NodeInfo{
+		{ID: "node-1", JobConfigs: current.jobVersions()},
+		{ID: "node-2", JobConfigs: current.jobVersions()},
+		{ID: "node-3", JobConfigs: stale.jobVersions()},
+	}
+	current.load.setPeers(peers)
+	stale.load.setPeers(peers)
+
+	for _, s := range []*Scheduler{current, stale} {
+		job, _ := s.GetJob("backup")
+		job.Run()
+	}
+	if len(currentLocker.AcquireCalls) != 1 {
+		t.Errorf("node with version 5 acquired %d times, want it to run", len(currentLocker.AcquireCalls))
+	}
+	if len(staleLocker.AcquireCalls) != 0 {
+		t.Errorf("restarted node with version 4 acquired %d times, want it to skip", len(staleLocker.AcquireCalls))
+	}
+}
+
+func TestJob_DriftPolicy_Default(t *testing.T) {
+	job := newTestJob(config.JobConfig{Name: "job"}, lock.NewMockLocker())
+	if got := job.driftPolicy(); got != config.DriftWarn {
+		t.Errorf("driftPolicy() = %q, want %q", got, config.DriftWarn)
+	}
+	job.nodeDriftPolicy = config.DriftNewestWins
+	if got := job.driftPolicy(); got != config.DriftNewestWins {
+		t.Errorf("driftPolicy() = %q, want the node's %q", got, config.DriftNewestWins)
+	}
+	job.config.DriftPolicy = config.DriftRefuseToRun
+	if got := job.driftPolicy(); got != config.DriftRefuseToRun {
+		t.Errorf("driftPolicy() = %q, want the job's %q", got, config.DriftRefuseToRun)
+	}
+}
+
+func TestScheduler_Heartbeat_JobConfigs(t *testing.T) {
+	store := state.NewMockStore()
+	modTime := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, newTestLogger(), WithStore(store), WithConfigVersion(3, modTime))
+	cfg := config.JobConfig{Name: "backup", Schedule: "* * * * *", Command: config.ShellCommand("backup.sh")}
+	if err := s.AddJob(cfg); err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+
+	s.heartbeat()
+	nodes, _ := store.Nodes(t.Context())
+	if len(nodes) != 1 {
+		t.Fatalf("Nodes() = %+v, want this node", nodes)
+	}
+	hash, _ := cfg.Hash()
+	v, ok := nodes[0].JobConfigs["backup"]
+	if !ok || v.Hash != hash || v.ConfigVersion != 3 || !v.ModTime.Equal(modTime) {
+		t.Errorf("JobConfigs[backup] = %+v, want the job's hash, config version 3 and %v", v, modTime)
+	}
+}
@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"cronlock/internal/events"
+	"cronlock/internal/state"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithEvents sets the publisher the scheduler and its jobs publish their
+// lifecycle events to.
+func WithEvents(p *events.Publisher) Option {
+	return func(s *Scheduler) {
+		s.events = p
+	}
+}
+
+// runKey is the context key of the run a job's events belong to.
+type runKey struct{}
+
+// eventRun identifies the run a job's events belong to.
+type eventRun struct {
+	runID       string
+	scheduledAt time.Time
+}
+
+// withRun returns ctx with the run the job's events published under it
+// belong to.
+func withRun(ctx context.Context, runID string, scheduledAt time.Time) context.Context {
+	return context.WithValue(ctx, runKey{}, eventRun{runID: runID, scheduledAt: scheduledAt})
+}
+
+// emit publishes an event for the job, identifying the run from ctx.
+func (j *Job) emit(ctx context.Context, e events.Event) {
+	if j.events == nil {
+		return
+	}
+	run, _ := ctx.Value(runKey{}).(eventRun)
+	e.Job = j.config.Name
+	e.RunID = run.runID
+	e.ScheduledAt = run.scheduledAt
+	j.events.Publish(e)
+}
+
+// Skip reasons of runs this node didn't take, as reported in spans, events
+// and history.
+const (
+	// skipReasonDraining: the node is draining.
+	skipReasonDraining = "draining"
+	// skipReasonPaused: the job is paused cluster-wide.
+	skipReasonPaused = state.OutcomePaused
+	// skipReasonDrift: the job's drift policy kept it from running.
+	skipReasonDrift = "config_drift"
+	// skipReasonMaxConcurrent: the node runs max_concurrent_jobs already.
+	skipReasonMaxConcurrent = "max_concurrent_jobs"
+	// skipReasonLockHeld: another node holds the job's lock, or every
+	// shard lock.
+	skipReasonLockHeld = "lock_held"
+)
+
+// skip marks a run as skipped for reason in its span and events.
+func (j *Job) skip(ctx context.Context, span trace.Span, reason string) {
+	skipSpan(span, reason)
+	j.emit(ctx, events.Event{Type: events.TypeLockSkipped, Reason: reason})
+}
+
+// emitNode publishes an event for the node.
+func (s *Scheduler) emitNode(typ string) {
+	if s.events != nil {
+		s.events.Publish(events.Event{Type: typ})
+	}
+}
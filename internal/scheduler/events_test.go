@@ -0,0 +1,171 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"cronlock/internal/config"
+	"cronlock/internal/events"
+	"cronlock/internal/lock"
+)
+
+// eventSink records published events.
+type eventSink struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (s *eventSink) Add(_ context.Context, e events.Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, e)
+	return nil
+}
+
+// runPublished runs the job and returns the events it published.
+func runPublished(t *testing.T, job *Job) []events.Event {
+	t.Helper()
+	sink := &eventSink{}
+	job.events = events.NewPublisher(sink, "node-1", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	job.Run()
+	job.events.Close(context.Background())
+	return sink.events
+}
+
+func eventTypes(evs []events.Event) []string {
+	types := make([]string, len(evs))
+	for i, e := range evs {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func TestJob_Run_Events(t *testing.T) {
+	tests := []struct {
+		name      string
+		command   string
+		onFailure string
+		held      bool
+		want      []string
+	}{
+		{
+			name:    "success",
+			command: "true",
+			want:    []string{events.TypeScheduled, events.TypeLockAcquired, events.TypeStarted, events.TypeFinished, events.TypeReleased},
+		},
+		{
+			name:      "failing hook",
+			command:   "exit 3",
+			onFailure: "exit 1",
+			want:      []string{events.TypeScheduled, events.TypeLockAcquired, events.TypeStarted, events.TypeFinished, events.TypeHookFailed, events.TypeReleased},
+		},
+		{
+			name:    "lock held",
+			command: "true",
+			held:    true,
+			want:    []string{events.TypeScheduled, events.TypeLockSkipped},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.JobConfig{
+				Name:      "events-job",
+				Command:   config.ShellCommand(tt.command),
+				OnFailure: tt.onFailure,
+			}
+			locker := lock.NewMockLocker()
+			locker.SetLockHeld("events-job", tt.held)
+			job := newTestJob(cfg, locker)
+			got := runPublished(t, job)
+
+			types := eventTypes(got)
+			if len(types) != len(tt.want) {
+				t.Fatalf("event types = %v, want %v", types, tt.want)
+			}
+			for i := range types {
+				if types[i] != tt.want[i] {
+					t.Fatalf("event types = %v, want %v", types, tt.want)
+				}
+			}
+
+			for _, e := range got {
+				if e.Job != "events-job" || e.NodeID != "node-1" || e.Version != events.SchemaVersion || e.ScheduledAt.IsZero() {
+					t.Errorf("event = %+v, want job, node, version and scheduled time set", e)
+				}
+				if e.Type != events.TypeScheduled && e.Type != events.TypeLockSkipped && e.RunID == "" {
+					t.Errorf("%s event has no run ID", e.Type)
+				}
+			}
+			if tt.held {
+				if got[1].Reason != skipReasonLockHeld {
+					t.Errorf("Reason = %q, want %q", got[1].Reason, skipReasonLockHeld)
+				}
+				return
+			}
+
+			finished := got[3]
+			if finished.Lock != "events-job" || finished.ExitCode == nil {
+				t.Fatalf("finished event = %+v, want the lock and exit code", finished)
+			}
+			if tt.onFailure == "" {
+				if finished.Outcome != outcomeSuccess || *finished.ExitCode != 0 || finished.Error != "" {
+					t.Errorf("finished event = %+v, want a success", finished)
+				}
+				return
+			}
+			if finished.Outcome != outcomeFailure || *finished.ExitCode != 3 || finished.Error == "" {
+				t.Errorf("finished event = %+v, want a failure with exit code 3", finished)
+			}
+			if hook := got[4]; hook.Hook != hookFailure || hook.ExitCode == nil || *hook.ExitCode != 1 {
+				t.Errorf("hook_failed event = %+v, want on_failure with exit code 1", hook)
+			}
+		})
+	}
+}
+
+func TestJob_Run_Events_Sharded(t *testing.T) {
+	cfg := config.JobConfig{
+		Name:    "sharded-events",
+		Command: config.ShellCommand("true"),
+		Shards:  2,
+	}
+	job := newTestJob(cfg, lock.NewMockLocker())
+	got := runPublished(t, job)
+
+	counts := make(map[string]int)
+	locks := make(map[string]bool)
+	for _, e := range got {
+		counts[e.Type]++
+		if e.Type == events.TypeLockAcquired {
+			locks[e.Lock] = true
+		}
+	}
+	for _, typ := range []string{events.TypeLockAcquired, events.TypeStarted, events.TypeFinished, events.TypeReleased} {
+		if counts[typ] != 2 {
+			t.Errorf("%d %s events, want one per shard", counts[typ], typ)
+		}
+	}
+	if !locks[shardLockName("sharded-events", 0)] || !locks[shardLockName("sharded-events", 1)] {
+		t.Errorf("acquired locks = %v, want both shard locks", locks)
+	}
+}
+
+func TestScheduler_NodeEvents(t *testing.T) {
+	sink := &eventSink{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	p := events.NewPublisher(sink, "node-1", logger)
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "node-1"}, logger, WithEvents(p))
+
+	s.Start()
+	s.Stop()
+	p.Close(context.Background())
+
+	types := eventTypes(sink.events)
+	if len(types) != 2 || types[0] != events.TypeNodeStarted || types[1] != events.TypeNodeStopping {
+		t.Errorf("event types = %v, want [%s %s]", types, events.TypeNodeStarted, events.TypeNodeStopping)
+	}
+}
@@ -0,0 +1,116 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"slices"
+	"time"
+
+	"cronlock/internal/state"
+)
+
+// Node registry heartbeat settings. A node drops out of the registry once
+// it misses heartbeats for nodeTTL.
+const (
+	heartbeatInterval = 10 * time.Second
+	nodeTTL           = 30 * time.Second
+)
+
+// WithRegistration sets the version and job configuration hash the node
+// reports in its registry heartbeats.
+func WithRegistration(version, configHash string) Option {
+	return func(s *Scheduler) {
+		s.version = version
+		s.configHash = configHash
+	}
+}
+
+// WithConfigVersion sets the version of the job configuration pushed to
+// Redis the node's jobs were loaded from, 0 for its config file, and when
+// that configuration last changed: the file's modification time or when
+// the version was pushed. Both are reported in the node's registry
+// heartbeats and decide which node's configuration of a drifted job is the
+// newest.
+func WithConfigVersion(version int, modTime time.Time) Option {
+	return func(s *Scheduler) {
+		s.configVersion = version
+		s.configModTime = modTime
+	}
+}
+
+// runHeartbeat publishes this node's registration and load to the state
+// store every heartbeatInterval until done is closed, refreshing the cached
+// view of its peers each time.
+func (s *Scheduler) runHeartbeat(done <-chan struct{}) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	s.heartbeat()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.heartbeat()
+		}
+	}
+}
+
+// heartbeat sends one registration, applies any drain request, and
+// refreshes peer load.
+func (s *Scheduler) heartbeat() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s.mu.Lock()
+	configHash, configVersion := s.configHash, s.configVersion
+	s.mu.Unlock()
+
+	jobs := s.RunningJobs()
+	slices.Sort(jobs)
+
+	hostname, _ := os.Hostname()
+	info := state.NodeInfo{
+		ID:            s.node.ID,
+		Hostname:      hostname,
+		Version:       s.version,
+		Labels:        s.node.Labels,
+		RunningJobs:   s.load.running(),
+		Jobs:          jobs,
+		LoadAvg:       loadAverage(),
+		CPUs:          runtime.NumCPU(),
+		Draining:      s.IsDraining(),
+		ConfigHash:    configHash,
+		ConfigVersion: configVersion,
+		JobConfigs:    s.jobVersions(),
+		StartedAt:     s.startedAt,
+
+		LastHeartbeat: time.Now(),
+	}
+	if err := s.store.RegisterNode(ctx, info, nodeTTL); err != nil {
+		s.logger.Warn("failed to send node heartbeat", "error", err)
+		return
+	}
+
+	s.syncDrainRequest(ctx)
+	s.claimStalledShardRuns(ctx)
+
+	peers, err := s.store.Nodes(ctx)
+	if err != nil {
+		s.logger.Warn("failed to read node registry", "error", err)
+		return
+	}
+	s.load.setPeers(peers)
+}
+
+// claimStalledShardRuns fails the stalled runs of the sharded jobs this
+// node schedules. Every node checks, so a run is failed as long as any node
+// scheduling the job is up.
+func (s *Scheduler) claimStalledShardRuns(ctx context.Context) {
+	for _, job := range s.Jobs() {
+		if job.config.IsSharded() {
+			job.claimStalledRuns(ctx)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestScheduler_Heartbeat_Registration(t *testing.T) {
+	store := state.NewMockStore()
+	nodeCfg := config.NodeConfig{ID: "node-1", Labels: map[string]string{"zone": "a"}}
+	s := New(lock.NewMockLocker(), nodeCfg, newTestLogger(),
+		WithStore(store),
+		WithRegistration("cronlock v1.2.3 (abc1234)", "0123456789ab"),
+	)
+	for _, name := range []string{"slow", "idle"} {
+		if err := s.AddJob(config.JobConfig{Name: name, Schedule: "* * * * *", Command: config.ShellCommand("sleep 0.3")}); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+	}
+	s.startedAt = time.Now().Add(-time.Hour)
+
+	job, _ := s.GetJob("slow")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run()
+	}()
+	waitFor(t, time.Second, job.IsRunning)
+
+	s.heartbeat()
+	<-done
+
+	nodes, _ := store.Nodes(context.Background())
+	if len(nodes) != 1 {
+		t.Fatalf("Nodes() = %+v, want this node", nodes)
+	}
+	info := nodes[0]
+	hostname, _ := os.Hostname()
+	if info.ID != "node-1" || info.Hostname != hostname || info.Labels["zone"] != "a" {
+		t.Errorf("registration = %+v, want the node's ID, hostname and labels", info)
+	}
+	if info.Version != "cronlock v1.2.3 (abc1234)" || info.ConfigHash != "0123456789ab" {
+		t.Errorf("Version = %q, ConfigHash = %q, want the registered values", info.Version, info.ConfigHash)
+	}
+	if !info.StartedAt.Equal(s.startedAt) {
+		t.Errorf("StartedAt = %v, want %v", info.StartedAt, s.startedAt)
+	}
+	if len(info.Jobs) != 1 || info.Jobs[0] != "slow" || info.RunningJobs != 1 {
+		t.Errorf("Jobs = %v, RunningJobs = %d, want [slow], 1", info.Jobs, info.RunningJobs)
+	}
+}
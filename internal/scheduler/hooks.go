@@ -0,0 +1,335 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/events"
+	"cronlock/internal/executor"
+)
+
+// Hook names, as passed to hooks in CRONLOCK_HOOK.
+const (
+	hookStart    = "on_start"
+	hookSuccess  = "on_success"
+	hookFailure  = "on_failure"
+	hookTimeout  = "on_timeout"
+	hookComplete = "on_complete"
+	hookSlow     = "on_slow"
+	hookOverdue  = "on_overdue"
+)
+
+// Environment variables describing the run, passed to each hook.
+const (
+	envJob         = "CRONLOCK_JOB"
+	envNodeID      = "CRONLOCK_NODE_ID"
+	envRunID       = "CRONLOCK_RUN_ID"
+	envScheduledAt = "CRONLOCK_SCHEDULED_AT"
+	envHook        = "CRONLOCK_HOOK"
+	envOutcome     = "CRONLOCK_OUTCOME"
+	envExitCode    = "CRONLOCK_EXIT_CODE"
+	envDuration    = "CRONLOCK_DURATION"
+	envStdoutFile  = "CRONLOCK_STDOUT_FILE"
+	envStderrFile  = "CRONLOCK_STDERR_FILE"
+	envLastSuccess = "CRONLOCK_LAST_SUCCESS"
+)
+
+// Outcomes passed to hooks in CRONLOCK_OUTCOME.
+const (
+	outcomeSuccess = "success"
+	outcomeFailure = "failure"
+	outcomeTimeout = "timeout"
+)
+
+// hookRun describes the run a hook reports on.
+type hookRun struct {
+	runID       string
+	scheduledAt time.Time
+
+	// finished is set once the run is over, for every hook but on_start.
+	finished bool
+	outcome  string
+	exitCode int
+	duration time.Duration
+	// result, if set, is the command's result, whose captured output is
+	// passed to hooks as files. A sharded run as a whole has none.
+	result *executor.Result
+	// env holds extra variables, such as the shard a shard's hook is about.
+	env map[string]string
+	// err describes the failure of a run without a result, e.g. a sharded
+	// run whose shards didn't all report.
+	err string
+}
+
+// hookCall is a hook to run for a run.
+type hookCall struct {
+	hook string
+	run  hookRun
+}
+
+// finishedRun describes a run whose command has exited.
+func finishedRun(runID string, scheduledAt time.Time, result *executor.Result, timedOut bool) hookRun {
+	return hookRun{
+		runID:       runID,
+		scheduledAt: scheduledAt,
+		finished:    true,
+		outcome:     runOutcome(result, timedOut),
+		exitCode:    result.ExitCode,
+		duration:    result.Duration,
+		result:      result,
+	}
+}
+
+// runOutcome returns the outcome of a command's result.
+func runOutcome(result *executor.Result, timedOut bool) string {
+	switch {
+	case timedOut:
+		return outcomeTimeout
+	case !result.Success():
+		return outcomeFailure
+	}
+	return outcomeSuccess
+}
+
+// timedOut reports whether a run's command was killed by the job's timeout.
+func timedOut(execCtx context.Context, result *executor.Result) bool {
+	return !result.Success() && errors.Is(execCtx.Err(), context.DeadlineExceeded)
+}
+
+// finishHooks returns the configured hooks for a finished run, in order:
+// on_timeout, then on_success or on_failure, then on_complete. A timed out
+// run is a failure, so on_failure runs after on_timeout.
+func (j *Job) finishHooks(run hookRun) []hookCall {
+	var hooks []string
+	if run.outcome == outcomeTimeout {
+		hooks = append(hooks, hookTimeout)
+	}
+	if run.outcome == outcomeSuccess {
+		hooks = append(hooks, hookSuccess)
+	} else {
+		hooks = append(hooks, hookFailure)
+	}
+	hooks = append(hooks, hookComplete)
+
+	var calls []hookCall
+	for _, hook := range hooks {
+		if j.hookCommand(hook) != "" {
+			calls = append(calls, hookCall{hook: hook, run: run})
+		}
+	}
+	return calls
+}
+
+// finish runs a finished run's hooks and releases its locks, running the
+// hooks first unless hooks_after_release is set.
+func (j *Job) finish(ctx context.Context, hooks []hookCall, lockNames ...string) {
+	if !j.config.HooksAfterRelease {
+		j.runHooks(ctx, hooks)
+	}
+	j.waitGracePeriod(ctx)
+	for _, name := range lockNames {
+		j.release(ctx, name)
+	}
+	if j.config.HooksAfterRelease {
+		j.runHooks(ctx, hooks)
+	}
+}
+
+// runHooks runs hooks one after another.
+func (j *Job) runHooks(ctx context.Context, hooks []hookCall) {
+	for _, call := range hooks {
+		j.runHook(ctx, call.hook, call.run)
+	}
+}
+
+// hookOrder ranks on_timeout before the other hooks of a finished run.
+func hookOrder(hook string) int {
+	if hook == hookTimeout {
+		return 0
+	}
+	return 1
+}
+
+// hookCommand returns the command configured for a hook, or "" if none is.
+func (j *Job) hookCommand(hook string) string {
+	switch hook {
+	case hookStart:
+		return j.config.OnStart
+	case hookSuccess:
+		return j.config.OnSuccess
+	case hookFailure:
+		return j.config.OnFailure
+	case hookTimeout:
+		return j.config.OnTimeout
+	case hookComplete:
+		return j.config.OnComplete
+	case hookSlow:
+		return j.config.OnSlow
+	case hookOverdue:
+		return j.config.OnOverdue
+	}
+	return ""
+}
+
+// hookTimeout returns how long a hook may run.
+func (j *Job) hookTimeout() time.Duration {
+	if j.config.HookTimeout > 0 {
+		return j.config.HookTimeout
+	}
+	return config.DefaultHookTimeout
+}
+
+// runHook runs a hook with the run's context in its environment, killing
+// it after hook_timeout. A failing hook is logged and otherwise ignored.
+// ctx carries the span the hook's span is a child of.
+func (j *Job) runHook(ctx context.Context, hook string, run hookRun) {
+	command := j.hookCommand(hook)
+	if command == "" {
+		return
+	}
+	logger := j.logger.With("hook", hook)
+	if run.runID != "" {
+		logger = logger.With("run_id", run.runID)
+	}
+	logger.Debug("running hook", "command", command)
+
+	ctx, span := j.startSpan(ctx, "hook "+hook, attrHook.String(hook))
+	defer span.End()
+
+	env := withTraceContext(ctx, j.hookEnv(hook, run))
+	if run.result != nil {
+		files, err := j.writeOutputFiles(run.result)
+		if err != nil {
+			logger.Warn("failed to write output files for hook", "error", err)
+		}
+		defer files.remove()
+		maps.Copy(env, files.env())
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), j.hookTimeout())
+	defer cancel()
+
+	result := j.executor.Execute(ctx, executor.Options{
+		Command:    command,
+		Shell:      j.config.Shell,
+		WorkDir:    j.config.WorkDir,
+		Env:        env,
+		Credential: j.credential(),
+	})
+
+	span.SetAttributes(attrExitCode.Int(result.ExitCode))
+	var err error
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		logger.Warn("hook timed out", "timeout", formatDuration(j.hookTimeout()))
+		err = fmt.Errorf("hook timed out after %v", j.hookTimeout())
+	case !result.Success():
+		logger.Warn("hook failed",
+			"exit_code", result.ExitCode,
+			"error", result.Err,
+		)
+		err = fmt.Errorf("hook failed with exit code %d", result.ExitCode)
+	}
+	if err != nil {
+		spanError(span, err)
+		j.emit(ctx, events.Event{
+			Type:     events.TypeHookFailed,
+			Hook:     hook,
+			Outcome:  runOutcome(result, errors.Is(ctx.Err(), context.DeadlineExceeded)),
+			ExitCode: &result.ExitCode,
+			Duration: result.Duration.Seconds(),
+			Error:    err.Error(),
+		})
+	}
+}
+
+// hookEnv returns a hook's environment: the job's environment plus the
+// variables describing the run.
+func (j *Job) hookEnv(hook string, run hookRun) map[string]string {
+	env := make(map[string]string, len(j.config.Env)+len(run.env)+8)
+	maps.Copy(env, j.env())
+	maps.Copy(env, run.env)
+
+	env[envJob] = j.config.Name
+	env[envNodeID] = j.nodeID
+	if run.runID != "" {
+		env[envRunID] = run.runID
+		env[envScheduledAt] = run.scheduledAt.Format(time.RFC3339)
+	}
+	env[envHook] = hook
+	if run.finished {
+		env[envOutcome] = run.outcome
+		env[envExitCode] = strconv.Itoa(run.exitCode)
+		env[envDuration] = fmt.Sprintf("%.3f", run.duration.Seconds())
+	}
+	return env
+}
+
+// outputFiles are temporary files holding a run's captured output for its
+// hooks.
+type outputFiles struct {
+	stdout string
+	stderr string
+}
+
+// writeOutputFiles writes the captured stdout and stderr to temporary files
+// readable by the job's user. The files must be removed once the hook exits.
+func (j *Job) writeOutputFiles(result *executor.Result) (*outputFiles, error) {
+	files := &outputFiles{}
+	var err error
+	if files.stdout, err = j.writeOutputFile("stdout", result.Stdout); err != nil {
+		return files, err
+	}
+	if files.stderr, err = j.writeOutputFile("stderr", result.Stderr); err != nil {
+		return files, err
+	}
+	return files, nil
+}
+
+// writeOutputFile writes one stream to a temporary file and returns its path.
+func (j *Job) writeOutputFile(stream, data string) (string, error) {
+	f, err := os.CreateTemp("", "cronlock-*."+stream)
+	if err != nil {
+		return "", err
+	}
+	path := f.Name()
+	_, err = f.WriteString(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && j.runAs != nil {
+		err = os.Chown(path, int(j.runAs.UID), int(j.runAs.GID))
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", err
+	}
+	return path, nil
+}
+
+// env returns the variables pointing hooks at the files that were written.
+func (f *outputFiles) env() map[string]string {
+	env := make(map[string]string, 2)
+	if f.stdout != "" {
+		env[envStdoutFile] = f.stdout
+	}
+	if f.stderr != "" {
+		env[envStderrFile] = f.stderr
+	}
+	return env
+}
+
+// remove deletes the files.
+func (f *outputFiles) remove() {
+	for _, path := range []string{f.stdout, f.stderr} {
+		if path != "" {
+			_ = os.Remove(path)
+		}
+	}
+}
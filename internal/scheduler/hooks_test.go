@@ -0,0 +1,201 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+// readEnvFile parses the KEY=value lines a hook wrote with env.
+func readEnvFile(t *testing.T, path string) map[string]string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("hook did not run: %v", err)
+	}
+	env := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			env[k] = v
+		}
+	}
+	return env
+}
+
+func TestJob_Run_HookEnv(t *testing.T) {
+	tmpDir := t.TempDir()
+	envFile := filepath.Join(tmpDir, "env")
+	stdoutCopy := filepath.Join(tmpDir, "stdout")
+
+	cfg := config.JobConfig{
+		Name:       "report",
+		Command:    config.ShellCommand("echo hello; echo oops >&2; exit 3"),
+		OnComplete: "env > " + envFile + "; cat $CRONLOCK_STDOUT_FILE > " + stdoutCopy,
+	}
+
+	job := newTestJob(cfg, lock.NewMockLocker())
+	job.nodeID = "node-1"
+	job.Run()
+
+	env := readEnvFile(t, envFile)
+	want := map[string]string{
+		"CRONLOCK_JOB":       "report",
+		"CRONLOCK_NODE_ID":   "node-1",
+		"CRONLOCK_HOOK":      "on_complete",
+		"CRONLOCK_OUTCOME":   "failure",
+		"CRONLOCK_EXIT_CODE": "3",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+	for _, k := range []string{"CRONLOCK_RUN_ID", "CRONLOCK_DURATION", "CRONLOCK_STDERR_FILE"} {
+		if env[k] == "" {
+			t.Errorf("%s is not set", k)
+		}
+	}
+	if _, err := time.Parse(time.RFC3339, env["CRONLOCK_SCHEDULED_AT"]); err != nil {
+		t.Errorf("CRONLOCK_SCHEDULED_AT = %q, want RFC 3339: %v", env["CRONLOCK_SCHEDULED_AT"], err)
+	}
+
+	stdout, _ := os.ReadFile(stdoutCopy)
+	if string(stdout) != "hello\n" {
+		t.Errorf("CRONLOCK_STDOUT_FILE contents = %q, want %q", stdout, "hello\n")
+	}
+	// The output files are removed once the hook exits
+	for _, k := range []string{"CRONLOCK_STDOUT_FILE", "CRONLOCK_STDERR_FILE"} {
+		if _, err := os.Stat(env[k]); !os.IsNotExist(err) {
+			t.Errorf("%s %q was not removed", k, env[k])
+		}
+	}
+}
+
+func TestJob_Run_HookOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		timeout time.Duration
+		want    string
+	}{
+		{"success", "true", 0, "on_start:\non_success:success\non_complete:success\n"},
+		{"failure", "false", 0, "on_start:\non_failure:failure\non_complete:failure\n"},
+		{
+			"timeout",
+			"sleep 10",
+			200 * time.Millisecond,
+			"on_start:\non_timeout:timeout\non_failure:timeout\non_complete:timeout\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logFile := filepath.Join(t.TempDir(), "hooks")
+			hook := "echo $CRONLOCK_HOOK:$CRONLOCK_OUTCOME >> " + logFile
+
+			cfg := config.JobConfig{
+				Name:       "ordered",
+				Command:    config.ShellCommand(tt.command),
+				Timeout:    tt.timeout,
+				OnStart:    hook,
+				OnSuccess:  hook,
+				OnFailure:  hook,
+				OnTimeout:  hook,
+				OnComplete: hook,
+			}
+			newTestJob(cfg, lock.NewMockLocker()).Run()
+
+			got, _ := os.ReadFile(logFile)
+			if string(got) != tt.want {
+				t.Errorf("hooks ran as %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJob_Run_HookTimeout(t *testing.T) {
+	locker := lock.NewMockLocker()
+	cfg := config.JobConfig{
+		Name:        "hung-hook",
+		Command:     config.ShellCommand("true"),
+		OnSuccess:   "sleep 10; echo done",
+		HookTimeout: 200 * time.Millisecond,
+	}
+
+	start := time.Now()
+	newTestJob(cfg, locker).Run()
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Run() took %v, want the hook killed after hook_timeout", elapsed)
+	}
+	if len(locker.ReleaseCalls) != 1 {
+		t.Errorf("Release() called %d times, want 1", len(locker.ReleaseCalls))
+	}
+}
+
+// releaseSpy records whether a hook's marker file existed when the lock
+// was released.
+type releaseSpy struct {
+	*lock.MockLocker
+	marker        string
+	hookRanBefore bool
+}
+
+func (l *releaseSpy) Release(ctx context.Context, jobName string) error {
+	_, err := os.Stat(l.marker)
+	l.hookRanBefore = err == nil
+	return l.MockLocker.Release(ctx, jobName)
+}
+
+func TestJob_Run_HooksAfterRelease(t *testing.T) {
+	for _, afterRelease := range []bool{false, true} {
+		marker := filepath.Join(t.TempDir(), "hook")
+		locker := &releaseSpy{MockLocker: lock.NewMockLocker(), marker: marker}
+
+		cfg := config.JobConfig{
+			Name:              "slow-hook",
+			Command:           config.ShellCommand("true"),
+			OnComplete:        "touch " + marker,
+			HooksAfterRelease: afterRelease,
+		}
+		newTestJob(cfg, locker).Run()
+
+		if _, err := os.Stat(marker); err != nil {
+			t.Fatalf("hooks_after_release=%v: hook did not run", afterRelease)
+		}
+		if locker.hookRanBefore == afterRelease {
+			t.Errorf("hooks_after_release=%v: hook ran before release = %v", afterRelease, locker.hookRanBefore)
+		}
+	}
+}
+
+func TestJob_Run_Sharded_HookEnv(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+
+	cfg := config.JobConfig{
+		Name:       "reindex",
+		Command:    config.ShellCommand(`[ "$CRONLOCK_SHARD_INDEX" != 1 ]`),
+		Shards:     2,
+		OnComplete: "env > " + envFile,
+	}
+
+	job := newTestJob(cfg, lock.NewMockLocker())
+	job.store = state.NewMockStore()
+	job.Run()
+
+	env := readEnvFile(t, envFile)
+	if env["CRONLOCK_OUTCOME"] != "failure" || env["CRONLOCK_EXIT_CODE"] != "1" {
+		t.Errorf("CRONLOCK_OUTCOME = %q, CRONLOCK_EXIT_CODE = %q, want failure, 1", env["CRONLOCK_OUTCOME"], env["CRONLOCK_EXIT_CODE"])
+	}
+	// A sharded run as a whole has no single output
+	if _, ok := env["CRONLOCK_STDOUT_FILE"]; ok {
+		t.Errorf("CRONLOCK_STDOUT_FILE = %q, want unset", env["CRONLOCK_STDOUT_FILE"])
+	}
+}
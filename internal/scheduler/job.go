@@ -4,12 +4,21 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"maps"
+	"strings"
 	"sync"
 	"time"
 
 	"cronlock/internal/config"
+	"cronlock/internal/events"
 	"cronlock/internal/executor"
 	"cronlock/internal/lock"
+	"cronlock/internal/notify"
+	"cronlock/internal/state"
+
+	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // formatDuration formats a duration as seconds with 2 decimal places.
@@ -20,14 +29,40 @@ func formatDuration(d time.Duration) string {
 // Job represents a scheduled job with distributed locking.
 type Job struct {
 	config      config.JobConfig
+	nodeID      string
 	locker      lock.Locker
 	executor    *executor.Executor
 	gracePeriod time.Duration
 	logger      *slog.Logger
+	store       state.Store
+	output      config.OutputConfig
+	runAs       *config.RunAs
+	load        *nodeLoad
+	draining    func() bool
+	notifier    *notify.Dispatcher
+	slowRuns    *runCounter
+	tracer      trace.Tracer
+	events      *events.Publisher
+
+	// configHash identifies the job's configuration, for comparison with
+	// other nodes'. nodeDriftPolicy is the node's drift_policy.
+	configHash      string
+	nodeDriftPolicy string
+
+	// acquireDelay is waited before competing for the lock, giving nodes
+	// that match the job's preferences a head start.
+	acquireDelay time.Duration
+
+	// entryID identifies the job's cron entry once it is scheduled.
+	entryID cron.EntryID
 
 	mu        sync.Mutex
 	running   bool
 	cancelCtx context.CancelFunc
+	// configVersion and configModTime identify the configuration the job
+	// is current with; see WithConfigVersion.
+	configVersion int
+	configModTime time.Time
 }
 
 // NewJob creates a new Job instance.
@@ -38,6 +73,7 @@ func NewJob(cfg config.JobConfig, locker lock.Locker, exec *executor.Executor, g
 		executor:    exec,
 		gracePeriod: gracePeriod,
 		logger:      logger.With("job", cfg.Name),
+		tracer:      noopTracer,
 	}
 }
 
@@ -59,99 +95,408 @@ func (j *Job) Run() {
 		j.mu.Unlock()
 	}()
 
-	ctx := context.Background()
+	lockTTL := j.lockTTL()
+	scheduledAt := scheduledTime(time.Now())
+
+	ctx, span := j.startSpan(context.Background(), "job.run",
+		attrJob.String(j.config.Name),
+		attrNodeID.String(j.nodeID),
+		attrScheduledAt.String(scheduledAt.Format(time.RFC3339)),
+	)
+	defer span.End()
+	ctx = withRun(ctx, "", scheduledAt)
+	j.emit(ctx, events.Event{Type: events.TypeScheduled})
+
+	if j.isDraining() {
+		j.logger.Debug("node is draining, skipping")
+		j.skip(ctx, span, skipReasonDraining)
+		return
+	}
+
+	if j.isPaused(ctx, scheduledAt) {
+		j.skip(ctx, span, skipReasonPaused)
+		return
+	}
+
+	if j.skipForDrift() {
+		j.skip(ctx, span, skipReasonDrift)
+		return
+	}
+
+	if j.load != nil && j.load.full() {
+		j.logger.Debug("node is at max_concurrent_jobs, skipping")
+		j.skip(ctx, span, skipReasonMaxConcurrent)
+		return
+	}
+
+	if j.acquireDelay > 0 {
+		j.logger.Debug("node is not preferred, delaying lock acquisition", "delay", formatDuration(j.acquireDelay))
+		time.Sleep(j.acquireDelay)
+	}
 
-	// Determine lock TTL
-	lockTTL := j.config.LockTTL
-	if lockTTL == 0 {
-		// Default to timeout + 1 minute, or 5 minutes if no timeout
-		if j.config.Timeout > 0 {
-			lockTTL = j.config.Timeout + time.Minute
-		} else {
-			lockTTL = 5 * time.Minute
+	if j.config.Placement == config.PlacementLeastLoaded && j.load != nil {
+		if delay := j.load.delay(); delay > 0 {
+			j.logger.Debug("node is busier than its peers, delaying lock acquisition", "delay", formatDuration(delay))
+			time.Sleep(delay)
 		}
 	}
 
+	// The node may have started draining during the delays above
+	if j.isDraining() {
+		j.logger.Debug("node is draining, skipping")
+		j.skip(ctx, span, skipReasonDraining)
+		return
+	}
+
+	runID := newRunID()
+	ctx = withRun(ctx, runID, scheduledAt)
+
+	if j.config.IsSharded() {
+		j.runSharded(ctx, runID, scheduledAt, lockTTL)
+		return
+	}
+
 	// Try to acquire the lock
-	acquired, err := j.locker.Acquire(ctx, j.config.Name, lockTTL)
+	acquired, err := j.acquire(ctx, j.config.Name, lockTTL)
 	if err != nil {
 		j.logger.Error("failed to acquire lock", "error", err)
+		spanError(span, err)
 		return
 	}
 	if !acquired {
 		j.logger.Debug("lock not acquired, another node is executing")
+		j.skip(ctx, span, skipReasonLockHeld)
 		return
 	}
+	if !j.reserveSlot(ctx, span, j.config.Name) {
+		return
+	}
+	if j.load != nil {
+		defer j.load.done()
+	}
 
-	j.logger.Info("acquired lock, starting execution")
-
-	// Create cancellable context for execution
-	execCtx, cancel := context.WithCancel(ctx)
-	j.mu.Lock()
-	j.cancelCtx = cancel
-	j.mu.Unlock()
+	logger := j.logger.With("run_id", runID)
+	logger.Info("acquired lock, starting execution")
+	span.SetAttributes(attrRunID.String(runID))
 
-	// Apply timeout if configured
-	if j.config.Timeout > 0 {
-		var timeoutCancel context.CancelFunc
-		execCtx, timeoutCancel = context.WithTimeout(execCtx, j.config.Timeout)
-		defer timeoutCancel()
-	}
+	execCtx, cancel := j.execContext(ctx)
+	defer cancel()
 
-	// Start lock renewal goroutine
-	renewDone := make(chan struct{})
-	go j.renewLock(ctx, lockTTL, renewDone)
-
-	// Execute the command
-	result := j.executor.Execute(execCtx, executor.Options{
-		Command: j.config.Command,
-		WorkDir: j.config.WorkDir,
-		Env:     j.config.Env,
-		Timeout: j.config.Timeout,
-	})
+	j.runHook(ctx, hookStart, hookRun{runID: runID, scheduledAt: scheduledAt})
 
-	// Stop lock renewal
-	close(renewDone)
+	startedAt := time.Now()
+	lockLost := func() { j.notify(config.EventLockLost, hookRun{runID: runID, scheduledAt: scheduledAt}, 0) }
+	slow := func() { j.warnSlow(ctx, logger, hookRun{runID: runID, scheduledAt: scheduledAt}) }
+	result, wasSlow := j.execute(ctx, execCtx, logger, j.config.Name, lockTTL, j.env(), runID, lockLost, slow)
+	timedOut := timedOut(execCtx, result)
+	rec := j.runRecord(runID, scheduledAt, startedAt, result)
+	rec.Slow = wasSlow
+	j.recordRun(ctx, rec)
 
 	// Log result
 	if result.Success() {
-		j.logger.Info("job completed successfully",
+		logger.Info("job completed successfully",
 			"duration", formatDuration(result.Duration),
 			"exit_code", result.ExitCode,
 		)
-		// Run success hook if configured
-		if j.config.OnSuccess != "" {
-			j.runHook(ctx, j.config.OnSuccess, "success")
-		}
 	} else {
-		j.logger.Error("job failed",
+		logger.Error("job failed",
 			"duration", formatDuration(result.Duration),
 			"exit_code", result.ExitCode,
+			"timed_out", timedOut,
+			"oom_killed", result.OOMKilled,
 			"error", result.Err,
 			"stderr", result.Stderr,
 		)
-		// Run failure hook if configured
-		if j.config.OnFailure != "" {
-			j.runHook(ctx, j.config.OnFailure, "failure")
+	}
+
+	run := finishedRun(runID, scheduledAt, result, timedOut)
+	span.SetAttributes(attrOutcome.String(run.outcome), attrExitCode.Int(result.ExitCode))
+	if run.outcome != outcomeSuccess {
+		span.SetStatus(codes.Error, "job "+run.outcome)
+	}
+	j.notifyOutcome(ctx, run)
+	j.finish(ctx, j.finishHooks(run), j.config.Name)
+}
+
+// reserveSlot claims a max_concurrent_jobs slot for a run that acquired
+// the locks in lockNames. If other runs filled the node since Run checked,
+// it releases the locks, so the job isn't held up by a node that can't run
+// it, and skips the run.
+func (j *Job) reserveSlot(ctx context.Context, span trace.Span, lockNames ...string) bool {
+	if j.load == nil || j.load.reserve() {
+		return true
+	}
+	j.logger.Debug("node reached max_concurrent_jobs while acquiring the lock, releasing it and skipping")
+	for _, name := range lockNames {
+		j.release(ctx, name)
+	}
+	j.skip(ctx, span, skipReasonMaxConcurrent)
+	return false
+}
+
+// isPaused reports whether the job is paused cluster-wide, logging the
+// pause and recording a paused outcome if so. A pause that can't be read
+// doesn't block the run.
+func (j *Job) isPaused(ctx context.Context, scheduledAt time.Time) bool {
+	if j.store == nil {
+		return false
+	}
+
+	pause, err := j.store.GetPause(ctx, j.config.Name)
+	if err != nil {
+		j.logger.Warn("failed to check pause state", "error", err)
+		return false
+	}
+	if pause == nil {
+		return false
+	}
+
+	attrs := []any{"reason", pause.Reason, "paused_by", pause.PausedBy}
+	if !pause.Until.IsZero() {
+		attrs = append(attrs, "until", pause.Until.Format(time.RFC3339))
+	}
+	j.logger.Info("job is paused, skipping", attrs...)
+
+	j.recordRun(ctx, state.RunRecord{
+		Job:         j.config.Name,
+		NodeID:      j.nodeID,
+		Outcome:     state.OutcomePaused,
+		ScheduledAt: scheduledAt,
+		Reason:      pause.Reason,
+	})
+	return true
+}
+
+// errOOMKilled is the history error of a run killed by its memory limit.
+const errOOMKilled = "killed by the memory limit (limits.memory_max)"
+
+// runRecord builds the history record for a completed execution.
+func (j *Job) runRecord(runID string, scheduledAt, startedAt time.Time, result *executor.Result) state.RunRecord {
+	rec := state.RunRecord{
+		Job:         j.config.Name,
+		RunID:       runID,
+		NodeID:      j.nodeID,
+		Outcome:     state.OutcomeSuccess,
+		ScheduledAt: scheduledAt,
+		StartedAt:   startedAt,
+		Duration:    result.Duration,
+		ExitCode:    result.ExitCode,
+	}
+	if !result.Success() {
+		rec.Outcome = state.OutcomeFailure
+		if result.OOMKilled {
+			rec.Error = errOOMKilled
+		} else if result.Err != nil {
+			rec.Error = result.Err.Error()
 		}
 	}
+	return rec
+}
 
-	// Wait grace period before releasing lock
+// recordRun appends a record to the job's history in the state store.
+func (j *Job) recordRun(ctx context.Context, rec state.RunRecord) {
+	if j.store == nil {
+		return
+	}
+	if err := j.store.RecordRun(ctx, rec); err != nil {
+		j.logger.Warn("failed to record run history", "error", err)
+	}
+}
+
+// isDraining reports whether the node is draining and must not take new runs.
+func (j *Job) isDraining() bool {
+	return j.draining != nil && j.draining()
+}
+
+// lockTTL returns the lock TTL for a run.
+func (j *Job) lockTTL() time.Duration {
+	if j.config.LockTTL > 0 {
+		return j.config.LockTTL
+	}
+	// Default to timeout + 1 minute, or 5 minutes if no timeout
+	if j.config.Timeout > 0 {
+		return j.config.Timeout + time.Minute
+	}
+	return 5 * time.Minute
+}
+
+// env returns the environment for the job's command and hooks: the
+// configured env and, when running as another user, that user's USER,
+// LOGNAME and HOME unless the configured env sets them.
+func (j *Job) env() map[string]string {
+	if j.runAs == nil {
+		return j.config.Env
+	}
+	env := map[string]string{
+		"USER":    j.runAs.User,
+		"LOGNAME": j.runAs.User,
+		"HOME":    j.runAs.Home,
+	}
+	maps.Copy(env, j.config.Env)
+	return env
+}
+
+// credential returns the identity to run the job's command and hooks as,
+// or nil to run them as cronlock's own user.
+func (j *Job) credential() *executor.Credential {
+	if j.runAs == nil {
+		return nil
+	}
+	return &executor.Credential{
+		UID:    j.runAs.UID,
+		GID:    j.runAs.GID,
+		Groups: j.runAs.Groups,
+	}
+}
+
+// limits returns the resource limits for a run of the job's command, or nil
+// if it has none. name is the run's log name, which also names its cgroup.
+func (j *Job) limits(name string) *executor.Limits {
+	l := j.config.Limits
+	if !l.HasRlimits() && !l.HasCgroup() {
+		return nil
+	}
+	limits := &executor.Limits{
+		NoFile:    l.NoFile,
+		NProc:     l.NProc,
+		Cgroup:    strings.ReplaceAll(j.config.Name, "/", "_") + "-" + name,
+		MemoryMax: uint64(l.MemoryMax),
+		CPUWeight: l.CPUWeight,
+		CPUMax:    l.CPUMax,
+		PidsMax:   l.PidsMax,
+	}
+	if l.Core != nil {
+		core := uint64(*l.Core)
+		limits.Core = &core
+	}
+	if l.AS != nil {
+		as := uint64(*l.AS)
+		limits.AS = &as
+	}
+	return limits
+}
+
+// execContext creates the cancellable execution context for a run, applying
+// the configured timeout. The returned function must be called when the run ends.
+func (j *Job) execContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	execCtx, cancel := context.WithCancel(ctx)
+	j.mu.Lock()
+	j.cancelCtx = cancel
+	j.mu.Unlock()
+
+	if j.config.Timeout <= 0 {
+		return execCtx, cancel
+	}
+
+	execCtx, timeoutCancel := context.WithTimeout(execCtx, j.config.Timeout)
+	return execCtx, func() {
+		timeoutCancel()
+		cancel()
+	}
+}
+
+// execute runs the job command while keeping the named lock renewed.
+// logName names the run's log file, if output.dir is set. lockLost is
+// called once if the lock can't be extended, and slow once the command has
+// run for warn_after. It reports whether slow was called.
+func (j *Job) execute(ctx, execCtx context.Context, logger *slog.Logger, lockName string, lockTTL time.Duration, env map[string]string, logName string, lockLost, slow func()) (*executor.Result, bool) {
+	// Start lock renewal goroutine
+	renewDone := make(chan struct{})
+	go j.renewLock(ctx, logger, lockName, lockTTL, renewDone, lockLost)
+	stopSlow := j.watchSlow(slow)
+
+	// Execute the command, passing it the command span's trace context
+	cmdCtx, span := j.startSpan(execCtx, "command", attrLock.String(lockName))
+	j.emit(ctx, events.Event{Type: events.TypeStarted, Lock: lockName})
+	out := j.openOutput(logger, logName)
+	result := j.executor.Execute(cmdCtx, executor.Options{
+		Command:    j.config.Command.Line,
+		Args:       j.config.Command.Argv,
+		Shell:      j.config.Shell,
+		WorkDir:    j.config.WorkDir,
+		Env:        withTraceContext(cmdCtx, env),
+		Credential: j.credential(),
+		Limits:     j.limits(logName),
+		Timeout:    j.config.Timeout,
+		MaxCapture: j.output.MaxCapture,
+		Stdout:     out.stdout,
+		Stderr:     out.stderr,
+	})
+	j.closeOutput(logger, out)
+
+	// Stop lock renewal
+	close(renewDone)
+	wasSlow := stopSlow()
+
+	outcome := runOutcome(result, timedOut(execCtx, result))
+	span.SetAttributes(
+		attrOutcome.String(outcome),
+		attrExitCode.Int(result.ExitCode),
+		attrSlow.Bool(wasSlow),
+	)
+	if result.OOMKilled {
+		span.SetAttributes(attrOOMKilled.Bool(true))
+	}
+	finished := events.Event{
+		Type:     events.TypeFinished,
+		Lock:     lockName,
+		Outcome:  outcome,
+		ExitCode: &result.ExitCode,
+		Duration: result.Duration.Seconds(),
+	}
+	if !result.Success() {
+		err := result.Err
+		if err == nil {
+			err = fmt.Errorf("exit code %d", result.ExitCode)
+		}
+		spanError(span, err)
+		finished.Error = err.Error()
+	}
+	span.End()
+	j.emit(ctx, finished)
+
+	return result, wasSlow
+}
+
+// acquire tries to acquire the named lock.
+func (j *Job) acquire(ctx context.Context, lockName string, ttl time.Duration) (bool, error) {
+	ctx, span := j.startSpan(ctx, "lock.acquire", attrLock.String(lockName))
+	acquired, err := j.locker.Acquire(ctx, lockName, ttl)
+	span.SetAttributes(attrAcquired.Bool(acquired))
+	endSpan(span, err)
+	if acquired {
+		j.emit(ctx, events.Event{Type: events.TypeLockAcquired, Lock: lockName})
+	}
+	return acquired, err
+}
+
+// waitGracePeriod waits the node's grace period before locks are released.
+func (j *Job) waitGracePeriod(ctx context.Context) {
 	if j.gracePeriod > 0 {
+		_, span := j.startSpan(ctx, "grace_wait")
+		defer span.End()
 		j.logger.Debug("waiting grace period before releasing lock", "duration", formatDuration(j.gracePeriod))
 		time.Sleep(j.gracePeriod)
 	}
+}
 
-	// Release the lock
-	if err := j.locker.Release(ctx, j.config.Name); err != nil {
-		j.logger.Error("failed to release lock", "error", err)
+// release releases the named lock.
+func (j *Job) release(ctx context.Context, lockName string) {
+	ctx, span := j.startSpan(ctx, "lock.release", attrLock.String(lockName))
+	err := j.locker.Release(ctx, lockName)
+	endSpan(span, err)
+	if err != nil {
+		j.logger.Error("failed to release lock", "lock", lockName, "error", err)
 	} else {
-		j.logger.Debug("released lock")
+		j.logger.Debug("released lock", "lock", lockName)
+		j.emit(ctx, events.Event{Type: events.TypeReleased, Lock: lockName})
 	}
 }
 
-// renewLock periodically extends the lock TTL while the job is running.
-func (j *Job) renewLock(ctx context.Context, ttl time.Duration, done <-chan struct{}) {
+// renewLock periodically extends the lock TTL while the job is running,
+// calling lockLost the first time an extension finds the lock gone.
+func (j *Job) renewLock(ctx context.Context, logger *slog.Logger, lockName string, ttl time.Duration, done <-chan struct{}, lockLost func()) {
 	// Renew every TTL/3
 	interval := ttl / 3
 	if interval < time.Second {
@@ -161,42 +506,34 @@ func (j *Job) renewLock(ctx context.Context, ttl time.Duration, done <-chan stru
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	lost := false
 	for {
 		select {
 		case <-done:
 			return
 		case <-ticker.C:
-			extended, err := j.locker.Extend(ctx, j.config.Name, ttl)
+			extendCtx, span := j.startSpan(ctx, "lock.renew", attrLock.String(lockName))
+			extended, err := j.locker.Extend(extendCtx, lockName, ttl)
+			span.SetAttributes(attrExtended.Bool(extended))
+			endSpan(span, err)
 			if err != nil {
-				j.logger.Error("failed to extend lock", "error", err)
+				logger.Error("failed to extend lock", "error", err)
+				j.emit(ctx, events.Event{Type: events.TypeExtendFailed, Lock: lockName, Error: err.Error()})
 			} else if !extended {
-				j.logger.Warn("lock extension failed, lock may have been lost")
+				logger.Warn("lock extension failed, lock may have been lost")
+				j.emit(ctx, events.Event{Type: events.TypeExtendFailed, Lock: lockName, Error: "lock lost"})
+				if !lost && lockLost != nil {
+					lost = true
+					lockLost()
+				}
 			} else {
-				j.logger.Debug("extended lock", "ttl", ttl)
+				logger.Debug("extended lock", "ttl", ttl)
+				j.emit(ctx, events.Event{Type: events.TypeExtended, Lock: lockName})
 			}
 		}
 	}
 }
 
-// runHook executes a hook command (on_success or on_failure).
-func (j *Job) runHook(ctx context.Context, command, hookType string) {
-	j.logger.Debug("running hook", "type", hookType, "command", command)
-
-	result := j.executor.Execute(ctx, executor.Options{
-		Command: command,
-		WorkDir: j.config.WorkDir,
-		Env:     j.config.Env,
-	})
-
-	if !result.Success() {
-		j.logger.Warn("hook failed",
-			"type", hookType,
-			"exit_code", result.ExitCode,
-			"error", result.Err,
-		)
-	}
-}
-
 // Cancel requests cancellation of the running job.
 func (j *Job) Cancel() {
 	j.mu.Lock()
@@ -222,3 +559,8 @@ func (j *Job) Timeout() time.Duration {
 func (j *Job) Name() string {
 	return j.config.Name
 }
+
+// Config returns the job's configuration.
+func (j *Job) Config() config.JobConfig {
+	return j.config
+}
@@ -1,8 +1,15 @@
 package scheduler
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"log/slog"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -10,6 +17,7 @@ import (
 	"cronlock/internal/config"
 	"cronlock/internal/executor"
 	"cronlock/internal/lock"
+	"cronlock/internal/state"
 )
 
 func newTestJob(cfg config.JobConfig, locker lock.Locker) *Job {
@@ -25,7 +33,7 @@ func TestNewJob(t *testing.T) {
 	cfg := config.JobConfig{
 		Name:     "test-job",
 		Schedule: "* * * * *",
-		Command:  "echo hello",
+		Command:  config.ShellCommand("echo hello"),
 		Timeout:  30 * time.Second,
 		LockTTL:  60 * time.Second,
 	}
@@ -50,7 +58,7 @@ func TestJob_Run_AcquiresLock(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo hello",
+		Command: config.ShellCommand("echo hello"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -99,7 +107,7 @@ func TestJob_Run_LockTTL(t *testing.T) {
 			locker := lock.NewMockLocker()
 			cfg := config.JobConfig{
 				Name:    "test-job",
-				Command: "echo hello",
+				Command: config.ShellCommand("echo hello"),
 				Timeout: tt.timeout,
 				LockTTL: tt.lockTTL,
 			}
@@ -125,7 +133,7 @@ func TestJob_Run_SkipsIfLockHeld(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo hello",
+		Command: config.ShellCommand("echo hello"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -146,7 +154,7 @@ func TestJob_Run_ReleasesLock(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo hello",
+		Command: config.ShellCommand("echo hello"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -171,7 +179,7 @@ func TestJob_Run_ExecutesCommand(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "touch " + markerFile,
+		Command: config.ShellCommand("touch " + markerFile),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -189,7 +197,7 @@ func TestJob_Run_SkipsIfAlreadyRunning(t *testing.T) {
 	// Create a job that takes some time
 	cfg := config.JobConfig{
 		Name:    "long-job",
-		Command: "sleep 0.5",
+		Command: config.ShellCommand("sleep 0.5"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -220,7 +228,7 @@ func TestJob_IsRunning(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "sleep 0.2",
+		Command: config.ShellCommand("sleep 0.2"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -258,7 +266,7 @@ func TestJob_Cancel(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "long-job",
-		Command: "sleep 10",
+		Command: config.ShellCommand("sleep 10"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -291,7 +299,7 @@ func TestJob_Cancel_BeforeRun(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo hello",
+		Command: config.ShellCommand("echo hello"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -314,7 +322,7 @@ func TestJob_Run_WithWorkDir(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "touch " + markerFile,
+		Command: config.ShellCommand("touch " + markerFile),
 		WorkDir: tmpDir,
 	}
 
@@ -335,7 +343,7 @@ func TestJob_Run_WithEnv(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo $MY_VAR > " + outputFile,
+		Command: config.ShellCommand("echo $MY_VAR > " + outputFile),
 		Env: map[string]string{
 			"MY_VAR": "test-value",
 		},
@@ -364,7 +372,7 @@ func TestJob_Run_AcquireError(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "touch " + markerFile,
+		Command: config.ShellCommand("touch " + markerFile),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -385,7 +393,7 @@ func TestJob_Run_WithTimeout(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "timeout-job",
-		Command: "sleep 10",
+		Command: config.ShellCommand("sleep 10"),
 		Timeout: 100 * time.Millisecond,
 	}
 
@@ -410,7 +418,7 @@ func TestJob_Run_FailedCommand(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "failing-job",
-		Command: "exit 1",
+		Command: config.ShellCommand("exit 1"),
 	}
 
 	job := newTestJob(cfg, locker)
@@ -429,7 +437,7 @@ func TestJob_Run_OnSuccessHook(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:      "hook-job",
-		Command:   "echo success",
+		Command:   config.ShellCommand("echo success"),
 		OnSuccess: "touch " + hookMarker,
 	}
 
@@ -449,7 +457,7 @@ func TestJob_Run_OnFailureHook(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:      "hook-job",
-		Command:   "exit 1",
+		Command:   config.ShellCommand("exit 1"),
 		OnFailure: "touch " + hookMarker,
 	}
 
@@ -469,7 +477,7 @@ func TestJob_Run_OnSuccessHook_NotCalledOnFailure(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:      "failing-job",
-		Command:   "exit 1",
+		Command:   config.ShellCommand("exit 1"),
 		OnSuccess: "touch " + hookMarker,
 	}
 
@@ -489,7 +497,7 @@ func TestJob_Run_OnFailureHook_NotCalledOnSuccess(t *testing.T) {
 
 	cfg := config.JobConfig{
 		Name:      "success-job",
-		Command:   "echo success",
+		Command:   config.ShellCommand("echo success"),
 		OnFailure: "touch " + hookMarker,
 	}
 
@@ -506,7 +514,7 @@ func TestJob_Run_WithGracePeriod(t *testing.T) {
 	locker := lock.NewMockLocker()
 	cfg := config.JobConfig{
 		Name:    "test-job",
-		Command: "echo hello",
+		Command: config.ShellCommand("echo hello"),
 	}
 
 	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{
@@ -524,3 +532,191 @@ func TestJob_Run_WithGracePeriod(t *testing.T) {
 		t.Errorf("job completed in %v, expected at least 100ms grace period", elapsed)
 	}
 }
+
+func TestJob_Run_Paused(t *testing.T) {
+	locker := lock.NewMockLocker()
+	store := state.NewMockStore()
+	_ = store.SetPause(context.Background(), "test-job", state.Pause{Reason: "maintenance"})
+
+	cfg := config.JobConfig{
+		Name:    "test-job",
+		Command: config.ShellCommand("echo hello"),
+	}
+
+	job := newTestJob(cfg, locker)
+	job.store = store
+	job.Run()
+
+	if len(locker.AcquireCalls) != 0 {
+		t.Errorf("Acquire() called %d times for paused job, want 0", len(locker.AcquireCalls))
+	}
+
+	history, _ := store.History(context.Background(), "test-job", 10)
+	if len(history) != 1 || history[0].Outcome != state.OutcomePaused {
+		t.Fatalf("history = %+v, want one paused record", history)
+	}
+	if history[0].Reason != "maintenance" {
+		t.Errorf("history reason = %q, want %q", history[0].Reason, "maintenance")
+	}
+
+	// Resuming lets the next run through
+	_ = store.ClearPause(context.Background(), "test-job")
+	job.Run()
+	if len(locker.AcquireCalls) != 1 {
+		t.Errorf("Acquire() called %d times after resume, want 1", len(locker.AcquireCalls))
+	}
+}
+
+func TestJob_Run_PauseExpired(t *testing.T) {
+	locker := lock.NewMockLocker()
+	store := state.NewMockStore()
+	_ = store.SetPause(context.Background(), "test-job", state.Pause{Until: time.Now().Add(-time.Second)})
+
+	job := newTestJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("true")}, locker)
+	job.store = store
+	job.Run()
+
+	if len(locker.AcquireCalls) != 1 {
+		t.Errorf("Acquire() called %d times after pause expired, want 1", len(locker.AcquireCalls))
+	}
+}
+
+func TestJob_Run_RecordsHistory(t *testing.T) {
+	store := state.NewMockStore()
+
+	job := newTestJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("exit 3")}, lock.NewMockLocker())
+	job.store = store
+	job.nodeID = "node-1"
+	job.Run()
+
+	history, _ := store.History(context.Background(), "test-job", 10)
+	if len(history) != 1 {
+		t.Fatalf("history has %d records, want 1", len(history))
+	}
+	rec := history[0]
+	if rec.Outcome != state.OutcomeFailure || rec.ExitCode != 3 || rec.NodeID != "node-1" {
+		t.Errorf("record = %+v, want failure with exit code 3 on node-1", rec)
+	}
+	if rec.StartedAt.IsZero() || rec.ScheduledAt.IsZero() {
+		t.Errorf("record times not set: %+v", rec)
+	}
+}
+
+func TestJob_Run_WritesLogFile(t *testing.T) {
+	store := state.NewMockStore()
+	dir := t.TempDir()
+
+	job := newTestJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("echo out; echo err >&2")}, lock.NewMockLocker())
+	job.store = store
+	job.output = config.OutputConfig{MaxCapture: 1024, Dir: dir, Keep: 2}
+
+	for range 3 {
+		job.Run()
+	}
+
+	history, _ := store.History(context.Background(), "test-job", 10)
+	if len(history) != 3 {
+		t.Fatalf("history has %d records, want 3", len(history))
+	}
+
+	// Only the newest two runs' files are kept
+	entries, err := os.ReadDir(filepath.Join(dir, "test-job"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("log dir has %d files, want 2", len(entries))
+	}
+
+	latest := history[0]
+	if latest.RunID == "" {
+		t.Fatal("RunID not recorded")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "test-job", latest.RunID+".log"))
+	if err != nil {
+		t.Fatalf("failed to read log file of the latest run: %v", err)
+	}
+	if got := string(data); got != "out\nerr\n" && got != "err\nout\n" {
+		t.Errorf("log file = %q, want stdout and stderr", got)
+	}
+}
+
+func TestJob_Run_LogLines(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	job := NewJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("echo one; echo two >&2")}, lock.NewMockLocker(), executor.New(), 0, logger)
+	job.output = config.OutputConfig{MaxCapture: 1024, LogLines: true}
+	job.Run()
+
+	logs := buf.String()
+	for _, want := range []string{
+		"msg=output job=test-job run_id=",
+		"stream=stdout line=one",
+		"stream=stderr line=two",
+	} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("logs missing %q:\n%s", want, logs)
+		}
+	}
+}
+
+func TestJob_EnvAndCredential(t *testing.T) {
+	job := newTestJob(config.JobConfig{Name: "test-job", Env: map[string]string{"HOME": "/srv/app", "KEY": "value"}}, lock.NewMockLocker())
+
+	// Without a user, the configured env is used as-is
+	if env := job.env(); len(env) != 2 {
+		t.Errorf("env() = %v, want the configured env", env)
+	}
+	if job.credential() != nil {
+		t.Errorf("credential() = %+v, want nil", job.credential())
+	}
+
+	job.runAs = &config.RunAs{UID: 1000, GID: 100, Groups: []uint32{100, 27}, User: "app", Home: "/home/app"}
+
+	env := job.env()
+	want := map[string]string{"USER": "app", "LOGNAME": "app", "HOME": "/srv/app", "KEY": "value"}
+	if !maps.Equal(env, want) {
+		t.Errorf("env() = %v, want %v", env, want)
+	}
+
+	cred := job.credential()
+	if cred == nil || cred.UID != 1000 || cred.GID != 100 || !slices.Equal(cred.Groups, []uint32{100, 27}) {
+		t.Errorf("credential() = %+v, want uid 1000, gid 100, groups [100 27]", cred)
+	}
+}
+
+func TestJob_Limits(t *testing.T) {
+	job := newTestJob(config.JobConfig{Name: "team/report"}, lock.NewMockLocker())
+	if limits := job.limits("run-1"); limits != nil {
+		t.Errorf("limits() = %+v, want nil", limits)
+	}
+
+	nofile := uint64(1024)
+	core := config.ByteSize(0)
+	job.config.Limits = config.LimitsConfig{NoFile: &nofile, Core: &core, MemoryMax: 512 << 20, PidsMax: 100}
+
+	limits := job.limits("run-1")
+	if limits == nil {
+		t.Fatal("limits() = nil, want limits")
+	}
+	if limits.Cgroup != "team_report-run-1" {
+		t.Errorf("Cgroup = %q, want %q", limits.Cgroup, "team_report-run-1")
+	}
+	if limits.NoFile == nil || *limits.NoFile != 1024 || limits.Core == nil || *limits.Core != 0 || limits.AS != nil {
+		t.Errorf("rlimits = nofile %v, core %v, as %v, want 1024, 0, nil", limits.NoFile, limits.Core, limits.AS)
+	}
+	if limits.MemoryMax != 512<<20 || limits.PidsMax != 100 {
+		t.Errorf("MemoryMax = %d, PidsMax = %d, want 512MiB, 100", limits.MemoryMax, limits.PidsMax)
+	}
+}
+
+func TestJob_RunRecord_OOMKilled(t *testing.T) {
+	job := newTestJob(config.JobConfig{Name: "report"}, lock.NewMockLocker())
+	now := time.Now()
+
+	rec := job.runRecord("run-1", now, now, &executor.Result{ExitCode: -1, Err: errors.New("signal: killed"), OOMKilled: true})
+	if rec.Outcome != state.OutcomeFailure || rec.Error != errOOMKilled {
+		t.Errorf("runRecord() outcome = %q, error = %q, want failure, %q", rec.Outcome, rec.Error, errOOMKilled)
+	}
+}
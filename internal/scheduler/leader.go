@@ -0,0 +1,235 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"cronlock/internal/lock"
+)
+
+// The leader retries renewing its lease this many times, leaderRetryDelay
+// apart, before giving up on an error such as a brief loss of the Redis
+// connection.
+const (
+	leaderExtendAttempts = 3
+	leaderRetryDelay     = 200 * time.Millisecond
+)
+
+// Node statuses reported through the status handler.
+const (
+	StatusRunning  = "running"
+	StatusLeader   = "leader"
+	StatusFollower = "follower"
+)
+
+// WithLeaderElection runs jobs only while this node holds the leader lease.
+// The leader renews the lease every ttl/3; followers retry at the same
+// interval, so they take over roughly one TTL after the leader dies.
+func WithLeaderElection(lease lock.Lease, ttl time.Duration) Option {
+	return func(s *Scheduler) {
+		s.lease = lease
+		s.leaderTTL = ttl
+	}
+}
+
+// WithStatusHandler registers a function called whenever the node's status
+// changes, e.g. to report it to systemd.
+func WithStatusHandler(fn func(status string)) Option {
+	return func(s *Scheduler) {
+		s.onStatus = fn
+	}
+}
+
+// Status returns the node's current status.
+func (s *Scheduler) Status() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.statusLocked()
+}
+
+// statusLocked returns the node's status. The caller must hold s.mu.
+func (s *Scheduler) statusLocked() string {
+	switch {
+	case s.draining:
+		return StatusDraining
+	case s.lease == nil:
+		return StatusRunning
+	case s.leader:
+		return StatusLeader
+	default:
+		return StatusFollower
+	}
+}
+
+// IsLeader reports whether this node runs jobs: always true without leader
+// election, otherwise only while it holds the leader lease.
+func (s *Scheduler) IsLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lease == nil || s.leader
+}
+
+// LeaderID returns the node ID of the current leader, or "" without leader election.
+func (s *Scheduler) LeaderID(ctx context.Context) (string, error) {
+	if s.lease == nil {
+		return "", nil
+	}
+	return s.lease.Holder(ctx)
+}
+
+// notifyStatus reports the current status to the status handler.
+func (s *Scheduler) notifyStatus() {
+	if s.onStatus != nil {
+		s.onStatus(s.Status())
+	}
+}
+
+// runElection campaigns for the leader lease until done is closed.
+func (s *Scheduler) runElection(done <-chan struct{}) {
+	interval := s.leaderTTL / 3
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	s.campaign(interval)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.campaign(interval)
+		case <-s.electionWake:
+			s.campaign(interval)
+		}
+	}
+}
+
+// wakeElection makes the election loop campaign immediately, e.g. after the
+// node's drain state changes.
+func (s *Scheduler) wakeElection() {
+	if s.lease == nil {
+		return
+	}
+	select {
+	case s.electionWake <- struct{}{}:
+	default:
+	}
+}
+
+// campaign renews the lease while leader, or tries to acquire it otherwise,
+// starting or stopping the cron scheduler when leadership changes.
+func (s *Scheduler) campaign(timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	s.mu.Lock()
+	leader, draining := s.leader, s.draining
+	s.mu.Unlock()
+
+	// A draining node neither holds nor seeks leadership
+	if draining {
+		if leader {
+			s.stepDown()
+			s.releaseLease()
+		}
+		return
+	}
+
+	if leader {
+		extended, err := s.renewLease(ctx)
+		if err != nil {
+			s.logger.Error("failed to renew leader lease", "error", err)
+		}
+		if err != nil || !extended {
+			s.stepDown()
+		}
+		if err != nil {
+			// The lease may still be held by this node; give it up so a
+			// node can take over now rather than once it expires
+			s.releaseLease()
+		}
+		return
+	}
+
+	acquired, err := s.lease.Acquire(ctx, s.leaderTTL)
+	if err != nil {
+		s.logger.Error("failed to acquire leader lease", "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	s.mu.Lock()
+	s.leader = true
+	s.mu.Unlock()
+
+	s.logger.Info("acquired leadership, starting jobs")
+	s.cron.Start()
+	s.notifyStatus()
+}
+
+// renewLease extends the leader lease, retrying on errors.
+func (s *Scheduler) renewLease(ctx context.Context) (bool, error) {
+	var err error
+	for attempt := range leaderExtendAttempts {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return false, err
+			case <-time.After(leaderRetryDelay):
+			}
+		}
+		var extended bool
+		if extended, err = s.lease.Extend(ctx, s.leaderTTL); err == nil {
+			return extended, nil
+		}
+		s.logger.Warn("failed to renew leader lease, retrying", "attempt", attempt+1, "error", err)
+	}
+	return false, err
+}
+
+// releaseLease gives up the leader lease if this node holds it.
+func (s *Scheduler) releaseLease() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.lease.Release(ctx); err != nil {
+		s.logger.Error("failed to release leader lease", "error", err)
+	}
+}
+
+// stepDown stops scheduling new runs after leadership is lost or given up.
+// Runs already in progress finish under their job locks.
+func (s *Scheduler) stepDown() {
+	s.mu.Lock()
+	s.leader = false
+	s.mu.Unlock()
+
+	s.logger.Warn("no longer leader, stopping jobs")
+	s.cron.Stop()
+	s.notifyStatus()
+}
+
+// resign stops campaigning and gives up the lease so a follower can take
+// over without waiting for it to expire.
+func (s *Scheduler) resign() {
+	close(s.electionDone)
+	<-s.electionExited
+
+	s.mu.Lock()
+	leader := s.leader
+	s.leader = false
+	s.mu.Unlock()
+
+	if !leader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.lease.Release(ctx); err != nil {
+		s.logger.Error("failed to release leader lease", "error", err)
+	} else {
+		s.logger.Info("released leadership")
+	}
+}
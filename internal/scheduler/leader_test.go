@@ -0,0 +1,181 @@
+package scheduler
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+)
+
+// statusRecorder collects statuses passed to the status handler.
+type statusRecorder struct {
+	mu       sync.Mutex
+	statuses []string
+}
+
+func (r *statusRecorder) record(status string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statuses = append(r.statuses, status)
+}
+
+func (r *statusRecorder) last() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.statuses) == 0 {
+		return ""
+	}
+	return r.statuses[len(r.statuses)-1]
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func TestScheduler_Status_NoElection(t *testing.T) {
+	recorder := &statusRecorder{}
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(), WithStatusHandler(recorder.record))
+
+	s.Start()
+	defer s.Stop()
+
+	if s.Status() != StatusRunning {
+		t.Errorf("Status() = %q, want %q", s.Status(), StatusRunning)
+	}
+	if !s.IsLeader() {
+		t.Error("IsLeader() = false without leader election, want true")
+	}
+	if recorder.last() != StatusRunning {
+		t.Errorf("last status = %q, want %q", recorder.last(), StatusRunning)
+	}
+}
+
+func TestScheduler_LeaderElection_BecomesLeader(t *testing.T) {
+	lease := lock.NewMockLease()
+	recorder := &statusRecorder{}
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+		WithStatusHandler(recorder.record),
+	)
+
+	s.Start()
+	waitFor(t, time.Second, s.IsLeader)
+
+	if s.Status() != StatusLeader {
+		t.Errorf("Status() = %q, want %q", s.Status(), StatusLeader)
+	}
+	if recorder.last() != StatusLeader {
+		t.Errorf("last status = %q, want %q", recorder.last(), StatusLeader)
+	}
+
+	s.Stop()
+
+	if lease.ReleaseCalls != 1 {
+		t.Errorf("Release() called %d times, want 1 on shutdown", lease.ReleaseCalls)
+	}
+}
+
+func TestScheduler_LeaderElection_Follower(t *testing.T) {
+	lease := lock.NewMockLease()
+	lease.AcquireResult = false
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+	)
+
+	s.Start()
+	time.Sleep(50 * time.Millisecond)
+
+	if s.IsLeader() {
+		t.Error("IsLeader() = true, want false while another node holds the lease")
+	}
+	if s.Status() != StatusFollower {
+		t.Errorf("Status() = %q, want %q", s.Status(), StatusFollower)
+	}
+
+	s.Stop()
+
+	if lease.ReleaseCalls != 0 {
+		t.Errorf("Release() called %d times, want 0 for a follower", lease.ReleaseCalls)
+	}
+}
+
+func TestScheduler_LeaderElection_StepsDownOnLostLease(t *testing.T) {
+	lease := lock.NewMockLease()
+	recorder := &statusRecorder{}
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+		WithStatusHandler(recorder.record),
+	)
+
+	s.Start()
+	defer s.Stop()
+	waitFor(t, time.Second, s.IsLeader)
+
+	// Another node takes over; the next renewal (every ttl/3) fails
+	lease.Set(false, false)
+	waitFor(t, 3*time.Second, func() bool { return !s.IsLeader() })
+
+	if recorder.last() != StatusFollower {
+		t.Errorf("last status = %q, want %q", recorder.last(), StatusFollower)
+	}
+}
+
+func TestScheduler_LeaderElection_RetriesRenewalOnError(t *testing.T) {
+	lease := lock.NewMockLease()
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+	)
+
+	s.Start()
+	defer s.Stop()
+	waitFor(t, time.Second, s.IsLeader)
+
+	// A brief Redis blip fails one renewal; the retry succeeds
+	lease.FailExtends(1, errors.New("connection reset"))
+	waitFor(t, 3*time.Second, func() bool {
+		extend, _ := lease.Calls()
+		return extend >= 2
+	})
+
+	if !s.IsLeader() {
+		t.Error("IsLeader() = false after a transient renewal error, want true")
+	}
+	if _, release := lease.Calls(); release != 0 {
+		t.Errorf("Release() called %d times, want 0", release)
+	}
+}
+
+func TestScheduler_LeaderElection_ReleasesLeaseOnRenewalError(t *testing.T) {
+	lease := lock.NewMockLease()
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithLeaderElection(lease, 3*time.Second),
+	)
+
+	s.Start()
+	defer s.Stop()
+	waitFor(t, time.Second, s.IsLeader)
+
+	// Renewal keeps failing: the node steps down and releases the lease
+	// rather than holding it until it expires
+	lease.SetExtendError(errors.New("connection refused"))
+	waitFor(t, 3*time.Second, func() bool {
+		_, release := lease.Calls()
+		return release >= 1
+	})
+
+	extend, _ := lease.Calls()
+	if extend < leaderExtendAttempts {
+		t.Errorf("Extend() called %d times, want at least %d", extend, leaderExtendAttempts)
+	}
+}
@@ -0,0 +1,131 @@
+package scheduler
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cronlock/internal/state"
+)
+
+// maxLoadDelay is the delay the most loaded node waits before competing for
+// a least_loaded job's lock.
+const maxLoadDelay = 2 * time.Second
+
+// nodeLoad tracks this node's running jobs and its peers' published load. A
+// run counts as running once it holds its lock, not while it contends.
+type nodeLoad struct {
+	nodeID        string
+	maxConcurrent int
+	active        atomic.Int64
+
+	mu    sync.Mutex
+	peers []state.NodeInfo
+}
+
+// newNodeLoad creates a load tracker. maxConcurrent of 0 means no cap.
+func newNodeLoad(nodeID string, maxConcurrent int) *nodeLoad {
+	return &nodeLoad{
+		nodeID:        nodeID,
+		maxConcurrent: maxConcurrent,
+	}
+}
+
+// full reports whether the node is at max_concurrent_jobs, so a run can
+// skip without contending for a lock it couldn't use.
+func (l *nodeLoad) full() bool {
+	return l.maxConcurrent > 0 && l.active.Load() >= int64(l.maxConcurrent)
+}
+
+// reserve claims a job slot for a run holding its lock, failing if the node
+// is at max_concurrent_jobs. Every successful reserve must be paired with a
+// call to done.
+func (l *nodeLoad) reserve() bool {
+	for {
+		n := l.active.Load()
+		if l.maxConcurrent > 0 && n >= int64(l.maxConcurrent) {
+			return false
+		}
+		if l.active.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// done releases a slot claimed by reserve.
+func (l *nodeLoad) done() {
+	l.active.Add(-1)
+}
+
+// running returns the number of jobs this node is running, holding their
+// locks.
+func (l *nodeLoad) running() int {
+	return int(l.active.Load())
+}
+
+// setPeers replaces the cached view of the cluster's published load.
+func (l *nodeLoad) setPeers(peers []state.NodeInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.peers = peers
+}
+
+// delay returns how long this node should wait before competing for a
+// least_loaded job, based on its live load and its peers' last heartbeat.
+func (l *nodeLoad) delay() time.Duration {
+	l.mu.Lock()
+	var peerScores []float64
+	for _, peer := range l.peers {
+		if peer.ID != l.nodeID {
+			peerScores = append(peerScores, loadScore(peer.RunningJobs, peer.LoadAvg, peer.CPUs))
+		}
+	}
+	l.mu.Unlock()
+
+	score := loadScore(l.running(), loadAverage(), runtime.NumCPU())
+	return loadDelay(score, peerScores, maxLoadDelay)
+}
+
+// loadScore combines running jobs and per-CPU load average into one number.
+func loadScore(runningJobs int, loadAvg float64, cpus int) float64 {
+	if cpus < 1 {
+		cpus = 1
+	}
+	return float64(max(runningJobs, 0)) + loadAvg/float64(cpus)
+}
+
+// loadDelay scales maxDelay by where score sits between the least and most
+// loaded nodes: the least loaded node doesn't wait, the most loaded waits maxDelay.
+func loadDelay(score float64, peers []float64, maxDelay time.Duration) time.Duration {
+	lo, hi := score, score
+	for _, p := range peers {
+		lo = min(lo, p)
+		hi = max(hi, p)
+	}
+	if hi == lo {
+		return 0
+	}
+	return time.Duration(float64(maxDelay) * (score - lo) / (hi - lo))
+}
+
+// loadAverage returns the 1-minute load average, or 0 where /proc/loadavg
+// is unavailable.
+func loadAverage() float64 {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return 0
+	}
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	load, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0
+	}
+	return load
+}
@@ -0,0 +1,176 @@
+package scheduler
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestLoadDelay(t *testing.T) {
+	tests := []struct {
+		name  string
+		score float64
+		peers []float64
+		want  time.Duration
+	}{
+		{"no peers", 3, nil, 0},
+		{"all equal", 1, []float64{1, 1}, 0},
+		{"least loaded", 0, []float64{2, 4}, 0},
+		{"most loaded", 4, []float64{0, 2}, 2 * time.Second},
+		{"halfway", 2, []float64{0, 4}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := loadDelay(tt.score, tt.peers, 2*time.Second); got != tt.want {
+				t.Errorf("loadDelay() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadScore(t *testing.T) {
+	if got := loadScore(2, 4, 4); got != 3 {
+		t.Errorf("loadScore(2, 4, 4) = %v, want 3", got)
+	}
+	if got := loadScore(-1, 0, 0); got != 0 {
+		t.Errorf("loadScore(-1, 0, 0) = %v, want 0", got)
+	}
+}
+
+func TestNodeLoad_Reserve(t *testing.T) {
+	load := newNodeLoad("node-1", 2)
+
+	if !load.reserve() || !load.reserve() {
+		t.Fatal("reserve() = false below max_concurrent_jobs")
+	}
+	if load.reserve() {
+		t.Error("reserve() = true at max_concurrent_jobs, want false")
+	}
+	if load.running() != 2 {
+		t.Errorf("running() = %d, want 2", load.running())
+	}
+
+	load.done()
+	if !load.reserve() {
+		t.Error("reserve() = false after a slot was released")
+	}
+}
+
+func TestNodeLoad_Reserve_NoCap(t *testing.T) {
+	load := newNodeLoad("node-1", 0)
+	for i := 0; i < 100; i++ {
+		if !load.reserve() {
+			t.Fatalf("reserve() = false with no cap after %d reservations", i)
+		}
+	}
+}
+
+func TestNodeLoad_DelayIgnoresSelf(t *testing.T) {
+	load := newNodeLoad("node-1", 0)
+	load.setPeers([]state.NodeInfo{
+		{ID: "node-1", RunningJobs: 50, CPUs: 1},
+	})
+	load.reserve()
+	defer load.done()
+
+	// This node's own stale heartbeat must not count as a peer
+	if got := load.delay(); got != 0 {
+		t.Errorf("delay() = %v, want 0 with no other peers", got)
+	}
+}
+
+func TestJob_Run_MaxConcurrentJobs(t *testing.T) {
+	locker := lock.NewMockLocker()
+	job := newTestJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("true")}, locker)
+	job.load = newNodeLoad("node-1", 1)
+
+	// Another job occupies the only slot
+	job.load.reserve()
+	job.Run()
+
+	if len(locker.AcquireCalls) != 0 {
+		t.Errorf("Acquire() called %d times, want 0 at max_concurrent_jobs", len(locker.AcquireCalls))
+	}
+
+	job.load.done()
+	job.Run()
+
+	if len(locker.AcquireCalls) != 1 {
+		t.Errorf("Acquire() called %d times, want 1 after slot freed", len(locker.AcquireCalls))
+	}
+	if job.load.running() != 0 {
+		t.Errorf("running() = %d after run, want 0", job.load.running())
+	}
+}
+
+func TestJob_Run_MaxConcurrentJobs_CountsOnlyLockHolders(t *testing.T) {
+	// Other nodes hold a's and b's locks, so this node loses them
+	locker := lock.NewMockLocker()
+	locker.SetLockHeld("a", true)
+	locker.SetLockHeld("b", true)
+	load := newNodeLoad("node-1", 2)
+
+	var losers []*Job
+	for _, name := range []string{"a", "b"} {
+		job := newTestJob(config.JobConfig{Name: name, Command: config.ShellCommand("true")}, locker)
+		job.load = load
+		job.acquireDelay = 200 * time.Millisecond
+		losers = append(losers, job)
+	}
+	c := newTestJob(config.JobConfig{Name: "c", Command: config.ShellCommand("true")}, locker)
+	c.load = load
+
+	// a and b fire on the same tick and wait before contending
+	var wg sync.WaitGroup
+	for _, job := range losers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			job.Run()
+		}()
+	}
+	waitFor(t, time.Second, func() bool { return losers[0].IsRunning() && losers[1].IsRunning() })
+
+	if load.running() != 0 {
+		t.Errorf("running() = %d while contending, want 0", load.running())
+	}
+	c.Run()
+	wg.Wait()
+
+	if !slices.Contains(locker.ReleaseCalls, "c") {
+		t.Errorf("Release() calls = %v, want c to have run", locker.ReleaseCalls)
+	}
+	if load.running() != 0 {
+		t.Errorf("running() = %d after the tick, want 0", load.running())
+	}
+}
+
+func TestJob_ReserveSlot_ReleasesLocksAtCap(t *testing.T) {
+	locker := lock.NewMockLocker()
+	job := newTestJob(config.JobConfig{Name: "test-job", Command: config.ShellCommand("true")}, locker)
+	job.load = newNodeLoad("node-1", 1)
+
+	// Another run took the only slot while this one acquired its locks
+	job.load.reserve()
+	span := trace.SpanFromContext(context.Background())
+	if job.reserveSlot(context.Background(), span, "test-job/0", "test-job/1") {
+		t.Fatal("reserveSlot() = true at max_concurrent_jobs, want false")
+	}
+	if !slices.Equal(locker.ReleaseCalls, []string{"test-job/0", "test-job/1"}) {
+		t.Errorf("Release() calls = %v, want both locks released", locker.ReleaseCalls)
+	}
+
+	job.load.done()
+	if !job.reserveSlot(context.Background(), span, "test-job") || job.load.running() != 1 {
+		t.Errorf("reserveSlot() with a free slot = false or running() = %d, want true and 1", job.load.running())
+	}
+}
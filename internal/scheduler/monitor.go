@@ -0,0 +1,262 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/notify"
+)
+
+// Cluster monitor settings. The node holding the monitor lease checks every
+// monitored job once per monitorInterval; another node takes over within
+// monitorLeaseTTL if it dies.
+const (
+	monitorInterval = time.Minute
+	monitorLeaseTTL = 3 * monitorInterval
+)
+
+// WithMonitor makes the node take part in the cluster monitor, which alerts
+// when a job with expect_success_within has not succeeded for that long.
+// Only the node holding lease checks jobs at a time. jobs is the full job
+// list from the config, including jobs this node doesn't schedule.
+func WithMonitor(lease lock.Lease, jobs []config.JobConfig) Option {
+	return func(s *Scheduler) {
+		s.monitorLease = lease
+		s.monitorJobs = monitoredJobs(jobs)
+	}
+}
+
+// monitoredJobs returns the jobs the cluster monitor checks.
+func monitoredJobs(jobs []config.JobConfig) []config.JobConfig {
+	var monitored []config.JobConfig
+	for _, job := range jobs {
+		if job.ExpectSuccessWithin > 0 && job.IsEnabled() {
+			monitored = append(monitored, job)
+		}
+	}
+	return monitored
+}
+
+// monitored returns the jobs the cluster monitor checks.
+func (s *Scheduler) monitored() []config.JobConfig {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.monitorJobs
+}
+
+// Deadline describes whether a job has succeeded within its
+// expect_success_within window.
+type Deadline struct {
+	Job    string
+	Window time.Duration
+	// LastSuccess is zero if the job never succeeded.
+	LastSuccess time.Time
+	// Overdue is set once Window has passed since the last success or, for
+	// a job that never succeeded, since the cluster first monitored it.
+	Overdue bool
+}
+
+// Deadlines returns the deadline of every monitored job, read from the state
+// store. It doesn't write to the store; a job that never succeeded and that
+// the monitor hasn't seen yet is not overdue.
+func (s *Scheduler) Deadlines(ctx context.Context) ([]Deadline, error) {
+	if s.store == nil {
+		return nil, nil
+	}
+	now := time.Now()
+	jobs := s.monitored()
+	deadlines := make([]Deadline, 0, len(jobs))
+	for _, job := range jobs {
+		last, err := s.store.LastSuccess(ctx, job.Name)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		since := last
+		if since.IsZero() {
+			if since, err = s.store.FirstSeenAt(ctx, job.Name); err != nil {
+				return nil, fmt.Errorf("job %s: %w", job.Name, err)
+			}
+		}
+		deadlines = append(deadlines, Deadline{
+			Job:         job.Name,
+			Window:      job.ExpectSuccessWithin,
+			LastSuccess: last,
+			Overdue:     !since.IsZero() && now.Sub(since) > job.ExpectSuccessWithin,
+		})
+	}
+	return deadlines, nil
+}
+
+// startMonitor starts the monitor loop if the node takes part and there is
+// anything to monitor.
+func (s *Scheduler) startMonitor() {
+	if s.monitorLease == nil || s.store == nil || len(s.monitored()) == 0 {
+		return
+	}
+	s.monitorDone = make(chan struct{})
+	s.monitorExited = make(chan struct{})
+	go func() {
+		defer close(s.monitorExited)
+		s.runMonitor(s.monitorDone)
+	}()
+}
+
+// stopMonitor stops the monitor loop and gives up the monitor lease so
+// another node can take over without waiting for it to expire.
+func (s *Scheduler) stopMonitor() {
+	if s.monitorDone == nil {
+		return
+	}
+	close(s.monitorDone)
+	<-s.monitorExited
+
+	if !s.monitorHeld {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.monitorLease.Release(ctx); err != nil {
+		s.logger.Error("failed to release monitor lease", "error", err)
+	}
+	s.monitorHeld = false
+}
+
+// runMonitor checks the monitored jobs every monitorInterval until done is
+// closed.
+func (s *Scheduler) runMonitor(done <-chan struct{}) {
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+
+	s.monitor()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			s.monitor()
+		}
+	}
+}
+
+// monitor checks the monitored jobs if this node holds the monitor lease,
+// alerting for each overdue job at most once per expect_success_within.
+func (s *Scheduler) monitor() {
+	ctx, cancel := context.WithTimeout(context.Background(), monitorInterval/2)
+	defer cancel()
+
+	if !s.holdMonitorLease(ctx) {
+		return
+	}
+
+	if err := s.recordFirstSeen(ctx); err != nil {
+		s.logger.Warn("failed to record first seen jobs", "error", err)
+		return
+	}
+	deadlines, err := s.Deadlines(ctx)
+	if err != nil {
+		s.logger.Warn("failed to check job deadlines", "error", err)
+		return
+	}
+	for _, d := range deadlines {
+		if !d.Overdue {
+			continue
+		}
+		claimed, err := s.store.ClaimOverdueAlert(ctx, d.Job, d.Window)
+		if err != nil {
+			s.logger.Warn("failed to claim overdue alert", "job", d.Job, "error", err)
+			continue
+		}
+		if claimed {
+			s.alertOverdue(d)
+		}
+	}
+}
+
+// recordFirstSeen records now as the first seen time of every monitored
+// job that never succeeded and has none yet, so Deadlines can measure it.
+func (s *Scheduler) recordFirstSeen(ctx context.Context) error {
+	now := time.Now()
+	for _, job := range s.monitored() {
+		last, err := s.store.LastSuccess(ctx, job.Name)
+		if err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+		if !last.IsZero() {
+			continue
+		}
+		if _, err := s.store.FirstSeen(ctx, job.Name, now); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// holdMonitorLease renews the monitor lease if held, or tries to acquire
+// it, and reports whether the node holds it.
+func (s *Scheduler) holdMonitorLease(ctx context.Context) bool {
+	if s.monitorHeld {
+		extended, err := s.monitorLease.Extend(ctx, monitorLeaseTTL)
+		if err != nil {
+			s.logger.Error("failed to renew monitor lease", "error", err)
+		}
+		if err == nil && extended {
+			return true
+		}
+		s.monitorHeld = false
+		s.logger.Info("no longer monitoring jobs")
+	}
+
+	acquired, err := s.monitorLease.Acquire(ctx, monitorLeaseTTL)
+	if err != nil {
+		s.logger.Error("failed to acquire monitor lease", "error", err)
+		return false
+	}
+	if acquired {
+		s.monitorHeld = true
+		s.logger.Info("acquired monitor lease, monitoring jobs", "job_count", len(s.monitored()))
+	}
+	return acquired
+}
+
+// alertOverdue sends the overdue event and runs the on_overdue hook of a
+// job that has not succeeded within its window. The event is sent even if
+// the job can't run on this node, e.g. because its user doesn't exist
+// here; only the hook needs the job.
+func (s *Scheduler) alertOverdue(d Deadline) {
+	attrs := []any{"job", d.Job, "expect_success_within", d.Window.String()}
+	if !d.LastSuccess.IsZero() {
+		attrs = append(attrs, "last_success", d.LastSuccess.Format(time.RFC3339))
+	}
+	s.logger.Warn("job has not succeeded within expect_success_within", attrs...)
+
+	var cfg config.JobConfig
+	for _, job := range s.monitored() {
+		if job.Name == d.Job {
+			cfg = job
+		}
+	}
+	send(s.notifier, cfg.Notify, config.EventOverdue, notify.Event{
+		Event:       config.EventOverdue,
+		Job:         d.Job,
+		NodeID:      s.node.ID,
+		Error:       fmt.Sprintf("no successful run within %v", d.Window),
+		LastSuccess: d.LastSuccess,
+	})
+
+	if cfg.OnOverdue == "" {
+		return
+	}
+	job, err := s.newJob(cfg)
+	if err != nil {
+		s.logger.Error("failed to run on_overdue hook", "job", d.Job, "error", err)
+		return
+	}
+	run := hookRun{env: make(map[string]string, 1)}
+	if !d.LastSuccess.IsZero() {
+		run.env[envLastSuccess] = d.LastSuccess.Format(time.RFC3339)
+	}
+	job.runHook(context.Background(), hookOverdue, run)
+}
@@ -0,0 +1,203 @@
+package scheduler
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/notify"
+	"cronlock/internal/state"
+)
+
+// recordSuccess records a successful run of job that finished at t.
+func recordSuccess(t *testing.T, store state.Store, job string, at time.Time) {
+	t.Helper()
+	rec := state.RunRecord{Job: job, Outcome: state.OutcomeSuccess, ScheduledAt: at, StartedAt: at}
+	if err := store.RecordRun(context.Background(), rec); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+}
+
+func TestScheduler_Deadlines(t *testing.T) {
+	store := state.NewMockStore()
+	disabled := false
+	jobs := []config.JobConfig{
+		{Name: "fresh", ExpectSuccessWithin: time.Hour},
+		{Name: "stale", ExpectSuccessWithin: time.Hour},
+		{Name: "never", ExpectSuccessWithin: time.Hour},
+		{Name: "never-old", ExpectSuccessWithin: time.Hour},
+		{Name: "unmonitored"},
+		{Name: "disabled", ExpectSuccessWithin: time.Hour, Enabled: &disabled},
+	}
+	recordSuccess(t, store, "fresh", time.Now().Add(-time.Minute))
+	recordSuccess(t, store, "stale", time.Now().Add(-2*time.Hour))
+	// Another node first monitored never-old 2h ago
+	if _, err := store.FirstSeen(context.Background(), "never-old", time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("FirstSeen() error = %v", err)
+	}
+
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithStore(store),
+		WithMonitor(lock.NewMockLease(), jobs),
+	)
+	s.startedAt = time.Now().Add(-2 * time.Hour)
+
+	deadlines, err := s.Deadlines(context.Background())
+	if err != nil {
+		t.Fatalf("Deadlines() error = %v", err)
+	}
+	got := make(map[string]Deadline)
+	for _, d := range deadlines {
+		got[d.Job] = d
+	}
+	if len(got) != 4 {
+		t.Fatalf("Deadlines() = %+v, want the 4 enabled monitored jobs", deadlines)
+	}
+	if got["fresh"].Overdue || !got["stale"].Overdue {
+		t.Errorf("Overdue = %v (fresh), %v (stale), want false, true", got["fresh"].Overdue, got["stale"].Overdue)
+	}
+	// A job that never succeeded is measured from when the cluster first
+	// monitored it, not from when this node started
+	if got["never"].Overdue || !got["never"].LastSuccess.IsZero() {
+		t.Errorf("never = %+v, want not overdue before it is first seen", got["never"])
+	}
+	if !got["never-old"].Overdue {
+		t.Errorf("never-old = %+v, want overdue 2h after first seen", got["never-old"])
+	}
+	// Reading deadlines, e.g. for /metrics, leaves first_seen to the monitor
+	if seen, _ := store.FirstSeenAt(context.Background(), "never"); !seen.IsZero() {
+		t.Errorf("FirstSeenAt(never) = %v after Deadlines(), want zero", seen)
+	}
+}
+
+func TestScheduler_Monitor_RecordsFirstSeen(t *testing.T) {
+	store := state.NewMockStore()
+	jobs := []config.JobConfig{
+		{Name: "done", ExpectSuccessWithin: time.Hour},
+		{Name: "never", ExpectSuccessWithin: time.Hour},
+	}
+	recordSuccess(t, store, "done", time.Now().Add(-time.Minute))
+
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithStore(store),
+		WithMonitor(lock.NewMockLease(), jobs),
+	)
+	before := time.Now()
+	s.monitor()
+
+	ctx := context.Background()
+	if seen, _ := store.FirstSeenAt(ctx, "never"); seen.Before(before) {
+		t.Errorf("FirstSeenAt(never) = %v, want recorded by the monitor after %v", seen, before)
+	}
+	if seen, _ := store.FirstSeenAt(ctx, "done"); !seen.IsZero() {
+		t.Errorf("FirstSeenAt(done) = %v, want zero for a job that succeeded", seen)
+	}
+}
+
+func TestScheduler_Monitor(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	store := state.NewMockStore()
+	lastSuccess := time.Now().Add(-2 * time.Hour).Truncate(time.Second)
+	recordSuccess(t, store, "backup", lastSuccess)
+
+	jobs := []config.JobConfig{{
+		Name:                "backup",
+		Schedule:            "@daily",
+		Command:             config.ShellCommand("true"),
+		ExpectSuccessWithin: time.Hour,
+		OnOverdue:           "env > " + envFile,
+		Notify:              []config.NotifyConfig{{Notifier: "rec"}},
+	}}
+
+	rec := &notifyRecorder{}
+	d, _ := notify.NewDispatcher(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		notify.WithNotifier("rec", rec, time.Second, 0),
+	)
+	lease := lock.NewMockLease()
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "monitor-1"}, newTestLogger(),
+		WithStore(store),
+		WithNotifier(d),
+		WithMonitor(lease, jobs),
+	)
+
+	// Checking again within the window doesn't alert twice
+	s.monitor()
+	s.monitor()
+	d.Close(context.Background())
+
+	if len(rec.events) != 1 {
+		t.Fatalf("got %d events, want 1", len(rec.events))
+	}
+	e := rec.events[0]
+	if e.Event != config.EventOverdue || e.NodeID != "monitor-1" || !e.LastSuccess.Equal(lastSuccess) {
+		t.Errorf("event = %+v, want overdue from monitor-1 with the last success", e)
+	}
+
+	env := readEnvFile(t, envFile)
+	if env[envHook] != hookOverdue || env[envLastSuccess] != lastSuccess.Format(time.RFC3339) {
+		t.Errorf("hook env %s = %q, %s = %q, want %q, %q", envHook, env[envHook], envLastSuccess, env[envLastSuccess], hookOverdue, lastSuccess.Format(time.RFC3339))
+	}
+	if _, ok := env[envRunID]; ok {
+		t.Errorf("%s = %q, want unset", envRunID, env[envRunID])
+	}
+	if lease.AcquireCalls != 1 || lease.ExtendCalls != 1 {
+		t.Errorf("lease acquired %d and extended %d times, want 1 and 1", lease.AcquireCalls, lease.ExtendCalls)
+	}
+}
+
+func TestScheduler_Monitor_JobNotRunnableHere(t *testing.T) {
+	store := state.NewMockStore()
+	recordSuccess(t, store, "backup", time.Now().Add(-2*time.Hour))
+
+	rec := &notifyRecorder{}
+	d, _ := notify.NewDispatcher(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		notify.WithNotifier("rec", rec, time.Second, 0),
+	)
+	// The job's user only exists on the nodes that run it
+	s := New(lock.NewMockLocker(), config.NodeConfig{ID: "monitor-1"}, newTestLogger(),
+		WithStore(store),
+		WithNotifier(d),
+		WithMonitor(lock.NewMockLease(), []config.JobConfig{{
+			Name:                "backup",
+			Schedule:            "@daily",
+			Command:             config.ShellCommand("true"),
+			User:                "cronlock-test-no-such-user",
+			ExpectSuccessWithin: time.Hour,
+			Notify:              []config.NotifyConfig{{Notifier: "rec"}},
+		}}),
+	)
+	s.monitor()
+	d.Close(context.Background())
+
+	if len(rec.events) != 1 || rec.events[0].Event != config.EventOverdue {
+		t.Errorf("events = %+v, want one overdue event", rec.events)
+	}
+}
+
+func TestScheduler_Monitor_LeaseHeldElsewhere(t *testing.T) {
+	envFile := filepath.Join(t.TempDir(), "env")
+	store := state.NewMockStore()
+	recordSuccess(t, store, "backup", time.Now().Add(-2*time.Hour))
+
+	lease := lock.NewMockLease()
+	lease.AcquireResult = false
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(),
+		WithStore(store),
+		WithMonitor(lease, []config.JobConfig{{
+			Name:                "backup",
+			ExpectSuccessWithin: time.Hour,
+			OnOverdue:           "touch " + envFile,
+		}}),
+	)
+	s.monitor()
+
+	if _, err := os.Stat(envFile); err == nil {
+		t.Error("on_overdue ran on a node without the monitor lease")
+	}
+}
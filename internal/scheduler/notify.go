@@ -0,0 +1,103 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/notify"
+	"cronlock/internal/state"
+)
+
+// notifyOutcome sends the events for a finished run. With
+// alert_after_failures set, failures are sent only when the job's
+// cluster-wide alert state fires or renotifies, and the first success after
+// an alert also sends recovered.
+func (j *Job) notifyOutcome(ctx context.Context, run hookRun) {
+	if j.notifier == nil || len(j.config.Notify) == 0 {
+		return
+	}
+	if j.config.AlertAfterFailures <= 0 || j.store == nil {
+		j.notify(run.outcome, run, 0)
+		return
+	}
+
+	success := run.outcome == outcomeSuccess
+	alert, err := j.store.RecordAlertOutcome(ctx, j.config.Name, success,
+		j.config.AlertAfterFailures, j.config.RenotifyInterval, time.Now())
+	if err != nil {
+		// Better a notification too many than a missed one
+		j.logger.Warn("failed to update alert state", "error", err)
+		j.notify(run.outcome, run, 0)
+		return
+	}
+
+	switch {
+	case success:
+		j.notify(run.outcome, run, 0)
+		if alert.Transition == state.AlertRecovered {
+			j.notify(config.EventRecovered, run, alert.Failures)
+		}
+	case alert.Transition == state.AlertFiring || alert.Transition == state.AlertRenotify:
+		j.notify(run.outcome, run, alert.Failures)
+	default:
+		j.logger.Debug("failure not notified, alert has not fired or already fired",
+			"run_id", run.runID,
+			"consecutive_failures", alert.Failures,
+		)
+	}
+}
+
+// notify sends an event about a run to the notifiers the job subscribes
+// to it. Delivery happens in the background and never delays the run.
+// failures is the number of consecutive failed runs, if tracked.
+func (j *Job) notify(event string, run hookRun, failures int) {
+	e := notify.Event{
+		Event:               event,
+		Job:                 j.config.Name,
+		NodeID:              j.nodeID,
+		RunID:               run.runID,
+		ScheduledAt:         run.scheduledAt,
+		ExitCode:            run.exitCode,
+		Duration:            run.duration.Seconds(),
+		ConsecutiveFailures: failures,
+	}
+	if run.finished && run.outcome != outcomeSuccess {
+		switch {
+		case run.result == nil:
+			// A sharded run as a whole has no single result
+			e.Error = cmp.Or(run.err, errShardsFailed)
+		case run.result.OOMKilled:
+			e.Error = errOOMKilled
+			e.Stderr = run.result.Stderr
+		default:
+			if run.result.Err != nil {
+				e.Error = run.result.Err.Error()
+			}
+			e.Stderr = run.result.Stderr
+		}
+	}
+	j.send(event, e)
+}
+
+// send queues an event for the notifiers the job subscribes to it.
+func (j *Job) send(event string, e notify.Event) {
+	send(j.notifier, j.config.Notify, event, e)
+}
+
+// send queues an event on d for the notifiers subscribed to it in subs.
+func send(d *notify.Dispatcher, subs []config.NotifyConfig, event string, e notify.Event) {
+	if d == nil {
+		return
+	}
+	var names []string
+	for _, n := range subs {
+		if n.Matches(event) {
+			names = append(names, n.Notifier)
+		}
+	}
+	if len(names) > 0 {
+		d.Send(names, e)
+	}
+}
@@ -0,0 +1,186 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/notify"
+	"cronlock/internal/state"
+)
+
+// notifyRecorder records the events sent to it.
+type notifyRecorder struct {
+	mu     sync.Mutex
+	events []notify.Event
+}
+
+func (r *notifyRecorder) Notify(ctx context.Context, e notify.Event) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, e)
+	return nil
+}
+
+// runNotified runs the job with a dispatcher sending to a recorder named
+// "rec", and returns the events it received.
+func runNotified(t *testing.T, job *Job) []notify.Event {
+	t.Helper()
+	rec := &notifyRecorder{}
+	d, err := notify.NewDispatcher(nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		notify.WithNotifier("rec", rec, time.Second, 0),
+	)
+	if err != nil {
+		t.Fatalf("NewDispatcher() error = %v", err)
+	}
+	job.notifier = d
+	job.Run()
+	d.Close(context.Background())
+	return rec.events
+}
+
+func TestJob_Run_Notify(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		timeout time.Duration
+		events  []string
+		want    []string
+	}{
+		{"failure by default", "echo boom >&2; exit 2", 0, nil, []string{"failure"}},
+		{"success not subscribed", "true", 0, nil, nil},
+		{"success", "true", 0, []string{"success", "failure"}, []string{"success"}},
+		{"timeout is a failure", "sleep 10", 200 * time.Millisecond, []string{"failure"}, []string{"timeout"}},
+		{"timeout only", "false", 0, []string{"timeout"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.JobConfig{
+				Name:    "notified",
+				Command: config.ShellCommand(tt.command),
+				Timeout: tt.timeout,
+				Notify:  []config.NotifyConfig{{Notifier: "rec", Events: tt.events}},
+			}
+			job := newTestJob(cfg, lock.NewMockLocker())
+			job.nodeID = "node-1"
+			events := runNotified(t, job)
+
+			var got []string
+			for _, e := range events {
+				got = append(got, e.Event)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("events = %v, want %v", got, tt.want)
+			}
+			if len(events) == 0 {
+				return
+			}
+			e := events[0]
+			if e.Job != "notified" || e.NodeID != "node-1" || e.RunID == "" {
+				t.Errorf("event = %+v, want the job, node and run ID", e)
+			}
+			if tt.name == "failure by default" && (e.ExitCode != 2 || e.Stderr != "boom\n") {
+				t.Errorf("exit_code = %d, stderr = %q, want 2, %q", e.ExitCode, e.Stderr, "boom\n")
+			}
+		})
+	}
+}
+
+func TestJob_Run_NotifyLockLost(t *testing.T) {
+	locker := lock.NewMockLocker()
+	locker.ExtendResult = false
+
+	cfg := config.JobConfig{
+		Name:    "lost",
+		Command: config.ShellCommand("sleep 2.5"),
+		LockTTL: time.Second,
+		Notify:  []config.NotifyConfig{{Notifier: "rec", Events: []string{"lock_lost"}}},
+	}
+	events := runNotified(t, newTestJob(cfg, locker))
+
+	// Renewal fails twice, but the loss is reported once
+	if len(events) != 1 || events[0].Event != config.EventLockLost {
+		t.Errorf("events = %+v, want one lock_lost", events)
+	}
+}
+
+func TestJob_Run_Sharded_Notify(t *testing.T) {
+	cfg := config.JobConfig{
+		Name:    "reindex",
+		Command: config.ShellCommand(`[ "$CRONLOCK_SHARD_INDEX" != 1 ]`),
+		Shards:  3,
+		Notify:  []config.NotifyConfig{{Notifier: "rec"}},
+	}
+	job := newTestJob(cfg, lock.NewMockLocker())
+	job.store = state.NewMockStore()
+	events := runNotified(t, job)
+
+	// Only the run as a whole is reported
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if events[0].Event != config.EventFailure || events[0].Error != errShardsFailed {
+		t.Errorf("event = %+v, want a failure with error %q", events[0], errShardsFailed)
+	}
+}
+
+func TestJob_Run_AlertAfterFailures(t *testing.T) {
+	cfg := config.JobConfig{
+		Name:               "health",
+		Command:            config.ShellCommand("false"),
+		Notify:             []config.NotifyConfig{{Notifier: "rec"}},
+		AlertAfterFailures: 3,
+	}
+	store := state.NewMockStore()
+
+	// Runs may land on different nodes; the streak is shared through the store
+	var got []string
+	run := func(command, nodeID string) {
+		job := newTestJob(cfg, lock.NewMockLocker())
+		job.config.Command = config.ShellCommand(command)
+		job.nodeID = nodeID
+		job.store = store
+		for _, e := range runNotified(t, job) {
+			got = append(got, fmt.Sprintf("%s:%d", e.Event, e.ConsecutiveFailures))
+		}
+	}
+	run("false", "node-1")
+	run("false", "node-2")
+	run("false", "node-1")
+	run("false", "node-2")
+	run("true", "node-1")
+	run("true", "node-2")
+	run("false", "node-1")
+
+	want := []string{"failure:3", "recovered:4"}
+	if !slices.Equal(got, want) {
+		t.Errorf("events = %v, want %v", got, want)
+	}
+}
+
+func TestAddJob_AlertRequiresStore(t *testing.T) {
+	cfg := config.JobConfig{
+		Name:               "health",
+		Schedule:           "* * * * *",
+		Command:            config.ShellCommand("true"),
+		AlertAfterFailures: 3,
+	}
+
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger())
+	if err := s.AddJob(cfg); err == nil {
+		t.Fatal("AddJob() error = nil, want error for alert_after_failures without store")
+	}
+
+	s = New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(), WithStore(state.NewMockStore()))
+	if err := s.AddJob(cfg); err != nil {
+		t.Errorf("AddJob() error = %v", err)
+	}
+}
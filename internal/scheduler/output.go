@@ -0,0 +1,106 @@
+package scheduler
+
+import (
+	"io"
+	"log/slog"
+	"time"
+
+	"cronlock/internal/runlog"
+
+	"github.com/google/uuid"
+)
+
+// newRunID returns a unique ID for one run of a job on this node. It is a
+// version 7 UUID, so IDs (and the log files named after them) sort by
+// start time.
+func newRunID() string {
+	id, err := uuid.NewV7()
+	if err != nil {
+		id = uuid.New()
+	}
+	return id.String()
+}
+
+// runOutput is where one execution's output goes besides the result
+// captured in memory: the run's log file and the job's logger.
+type runOutput struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	file  *runlog.File
+	lines []*runlog.LineWriter
+}
+
+// openOutput sets up the log file and line logging for one execution, as
+// configured. Failing to create the log file is logged and doesn't stop the
+// run. The returned runOutput must be closed once the command exits.
+func (j *Job) openOutput(logger *slog.Logger, logName string) *runOutput {
+	out := &runOutput{}
+	var stdout, stderr []io.Writer
+
+	if j.output.Dir != "" {
+		file, err := runlog.Create(j.output.Dir, j.config.Name, logName)
+		if err != nil {
+			logger.Error("failed to create run log file", "error", err)
+		} else {
+			out.file = file
+			stdout = append(stdout, file)
+			stderr = append(stderr, file)
+		}
+	}
+
+	if j.output.LogLines {
+		for _, stream := range []string{"stdout", "stderr"} {
+			w := runlog.NewLineWriter(func(line string) {
+				logger.Info("output", "stream", stream, "line", line)
+			})
+			out.lines = append(out.lines, w)
+			if stream == "stdout" {
+				stdout = append(stdout, w)
+			} else {
+				stderr = append(stderr, w)
+			}
+		}
+	}
+
+	out.stdout = multiWriter(stdout)
+	out.stderr = multiWriter(stderr)
+	return out
+}
+
+// closeOutput flushes partial lines and closes the log file, then prunes the
+// job's old log files.
+func (j *Job) closeOutput(logger *slog.Logger, out *runOutput) {
+	for _, w := range out.lines {
+		w.Flush()
+	}
+	if out.file == nil {
+		return
+	}
+
+	if err := out.file.Close(); err != nil {
+		logger.Error("failed to write run log file", "file", out.file.Name(), "error", err)
+	} else {
+		logger.Debug("wrote run log file", "file", out.file.Name())
+	}
+
+	removed, err := runlog.Prune(j.output.Dir, j.config.Name, j.output.Keep, j.output.MaxAge, time.Now())
+	if err != nil {
+		logger.Warn("failed to prune run log files", "error", err)
+	}
+	if removed > 0 {
+		logger.Debug("pruned run log files", "removed", removed)
+	}
+}
+
+// multiWriter combines writers, returning nil if there are none.
+func multiWriter(writers []io.Writer) io.Writer {
+	switch len(writers) {
+	case 0:
+		return nil
+	case 1:
+		return writers[0]
+	default:
+		return io.MultiWriter(writers...)
+	}
+}
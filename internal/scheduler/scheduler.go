@@ -1,80 +1,275 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"sync"
 	"time"
 
 	"cronlock/internal/config"
+	"cronlock/internal/events"
 	"cronlock/internal/executor"
 	"cronlock/internal/lock"
+	"cronlock/internal/logging"
+	"cronlock/internal/notify"
+	"cronlock/internal/state"
 
 	"github.com/robfig/cron/v3"
+	"go.opentelemetry.io/otel/trace"
 )
 
 const defaultShutdownTimeout = 30 * time.Second
 
+// Errors returned by Trigger.
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobRunning  = errors.New("job is already running")
+)
+
+// defaultPreferDelay is how long a node that doesn't match a job's
+// preferences waits before competing for the lock.
+const defaultPreferDelay = 2 * time.Second
+
 // Scheduler manages cron job scheduling with distributed locking.
 type Scheduler struct {
-	cron        *cron.Cron
-	locker      lock.Locker
-	executor    *executor.Executor
-	gracePeriod config.NodeConfig
-	logger      *slog.Logger
+	cron     *cron.Cron
+	locker   lock.Locker
+	executor *executor.Executor
+	node     config.NodeConfig
+	logger   *slog.Logger
+	store    state.Store
+	output   config.OutputConfig
+	onStatus func(status string)
+	load     *nodeLoad
+	notifier *notify.Dispatcher
+	slowRuns *runCounter
+	tracer   trace.Tracer
+	events   *events.Publisher
+
+	heartbeatDone chan struct{}
+	version       string
+	configHash    string    // guarded by mu
+	configVersion int       // guarded by mu
+	configModTime time.Time // guarded by mu
+
+	// Leader election; lease is nil when every node competes for every job.
+	lease          lock.Lease
+	leaderTTL      time.Duration
+	electionDone   chan struct{}
+	electionExited chan struct{}
+	electionWake   chan struct{}
+
+	// Cluster monitor; monitorLease is nil when the node doesn't take part.
+	monitorLease  lock.Lease
+	monitorJobs   []config.JobConfig // guarded by mu
+	monitorHeld   bool               // only used by the monitor goroutine
+	monitorDone   chan struct{}
+	monitorExited chan struct{}
+	startedAt     time.Time
+
+	mu     sync.Mutex
+	jobs   map[string]*Job
+	leader bool
+	// retired are jobs removed or replaced by ApplyJobs while running, so
+	// Stop still waits for them.
+	retired []*Job
+
+	// Drain state; draining nodes finish running jobs but start no new ones
+	draining         bool
+	drainExit        bool
+	drainRequested   bool // last drain request seen in the state store
+	drainRequestExit bool
+	drained          chan struct{}
+	drainedOnce      sync.Once
+}
 
-	mu   sync.Mutex
-	jobs map[string]*Job
+// Option configures optional Scheduler dependencies.
+type Option func(*Scheduler)
+
+// WithStore sets the cluster state store used by jobs that share state
+// between nodes, such as sharded jobs.
+func WithStore(store state.Store) Option {
+	return func(s *Scheduler) {
+		s.store = store
+	}
+}
+
+// WithOutput sets how jobs capture, store and log their output.
+func WithOutput(cfg config.OutputConfig) Option {
+	return func(s *Scheduler) {
+		s.output = cfg
+	}
+}
+
+// WithNotifier sets the dispatcher jobs send their notify events to.
+func WithNotifier(d *notify.Dispatcher) Option {
+	return func(s *Scheduler) {
+		s.notifier = d
+	}
 }
 
 // New creates a new Scheduler.
-func New(locker lock.Locker, nodeCfg config.NodeConfig, logger *slog.Logger) *Scheduler {
+func New(locker lock.Locker, nodeCfg config.NodeConfig, logger *slog.Logger, opts ...Option) *Scheduler {
 	// Create cron with seconds field support (optional) and standard parser
 	c := cron.New(cron.WithParser(cron.NewParser(
 		cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor,
 	)))
 
-	return &Scheduler{
-		cron:        c,
-		locker:      locker,
-		executor:    executor.New(),
-		gracePeriod: nodeCfg,
-		logger:      logger,
-		jobs:        make(map[string]*Job),
+	s := &Scheduler{
+		cron:     c,
+		locker:   locker,
+		executor: executor.New(executor.WithShell(nodeCfg.Shell), executor.WithCgroupParent(nodeCfg.CgroupParent)),
+		node:     nodeCfg,
+		output:   config.Defaults().Output,
+		logger:   logger,
+		load:     newNodeLoad(nodeCfg.ID, nodeCfg.MaxConcurrentJobs),
+		slowRuns: newRunCounter(),
+		tracer:   noopTracer,
+		jobs:     make(map[string]*Job),
+		drained:  make(chan struct{}),
+
+		electionWake: make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
 // AddJob adds a job to the scheduler.
 func (s *Scheduler) AddJob(cfg config.JobConfig) error {
+	job, err := s.buildJob(cfg)
+	if err != nil || job == nil {
+		return err
+	}
+	return s.scheduleJob(job)
+}
+
+// buildJob creates the Job for a job's configuration, or returns nil if the
+// job doesn't run on this node.
+func (s *Scheduler) buildJob(cfg config.JobConfig) (*Job, error) {
 	if !cfg.IsEnabled() {
 		s.logger.Info("job is disabled, skipping", "job", cfg.Name)
-		return nil
+		return nil, nil
 	}
 
-	job := NewJob(cfg, s.locker, s.executor, s.gracePeriod.GracePeriod, s.logger)
+	if !cfg.CanRunOn(s.node.Labels) {
+		s.logger.Info("job constraints not satisfied by node labels, skipping",
+			"job", cfg.Name,
+			"constraints", cfg.Constraints,
+		)
+		return nil, nil
+	}
+
+	if cfg.IsSharded() && s.store == nil {
+		return nil, fmt.Errorf("job %s is sharded but no state store is configured", cfg.Name)
+	}
+	if cfg.AlertAfterFailures > 0 && s.store == nil {
+		return nil, fmt.Errorf("job %s sets alert_after_failures but no state store is configured", cfg.Name)
+	}
 
-	entryID, err := s.cron.AddJob(cfg.Schedule, job)
+	job, err := s.newJob(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to add job %s: %w", cfg.Name, err)
+		return nil, err
 	}
+	if !cfg.Prefers(s.node.Labels) {
+		job.acquireDelay = cfg.PreferDelay
+		if job.acquireDelay == 0 {
+			job.acquireDelay = defaultPreferDelay
+		}
+	}
+	return job, nil
+}
+
+// scheduleJob adds a job to the cron schedule and the job list.
+func (s *Scheduler) scheduleJob(job *Job) error {
+	entryID, err := s.cron.AddJob(job.config.Schedule, job)
+	if err != nil {
+		return fmt.Errorf("failed to add job %s: %w", job.Name(), err)
+	}
+	job.entryID = entryID
 
 	s.mu.Lock()
-	s.jobs[cfg.Name] = job
+	s.jobs[job.Name()] = job
 	s.mu.Unlock()
 
 	s.logger.Info("added job",
-		"job", cfg.Name,
-		"schedule", cfg.Schedule,
+		"job", job.Name(),
+		"schedule", job.config.Schedule,
 		"entry_id", entryID,
 	)
 
 	return nil
 }
 
-// Start starts the scheduler.
+// newJob creates a Job with the node's settings and dependencies.
+func (s *Scheduler) newJob(cfg config.JobConfig) (*Job, error) {
+	logger := s.logger
+	if cfg.LogLevel != "" {
+		level, err := config.ParseLogLevel(cfg.LogLevel)
+		if err != nil {
+			return nil, fmt.Errorf("job %s: %w", cfg.Name, err)
+		}
+		logger = logging.WithLevel(logger, level)
+	}
+
+	runAs, err := cfg.RunAs()
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", cfg.Name, err)
+	}
+	configHash, err := cfg.Hash()
+	if err != nil {
+		return nil, fmt.Errorf("job %s: %w", cfg.Name, err)
+	}
+
+	job := NewJob(cfg, s.locker, s.executor, s.node.GracePeriod, logger)
+	job.nodeID = s.node.ID
+	job.store = s.store
+	job.output = s.output
+	job.runAs = runAs
+	job.load = s.load
+	job.draining = s.IsDraining
+	job.notifier = s.notifier
+	job.slowRuns = s.slowRuns
+	job.tracer = s.tracer
+	job.events = s.events
+	job.configHash = configHash
+	s.mu.Lock()
+	job.setConfigVersion(s.configVersion, s.configModTime)
+	s.mu.Unlock()
+	job.nodeDriftPolicy = s.node.DriftPolicy
+	return job, nil
+}
+
+// Start starts the scheduler. With leader election, jobs only start once
+// this node becomes the leader.
 func (s *Scheduler) Start() {
 	s.logger.Info("starting scheduler", "job_count", len(s.jobs))
-	s.cron.Start()
+	s.startedAt = time.Now()
+	s.emitNode(events.TypeNodeStarted)
+
+	if s.store != nil {
+		s.heartbeatDone = make(chan struct{})
+		go s.runHeartbeat(s.heartbeatDone)
+	}
+	s.startMonitor()
+
+	if s.lease == nil {
+		s.cron.Start()
+		s.notifyStatus()
+		return
+	}
+
+	s.logger.Info("leader election enabled, waiting for leadership", "lease_ttl", s.leaderTTL)
+	s.notifyStatus()
+
+	s.electionDone = make(chan struct{})
+	s.electionExited = make(chan struct{})
+	go func() {
+		defer close(s.electionExited)
+		s.runElection(s.electionDone)
+	}()
 }
 
 // Stop stops the scheduler and waits for running jobs to complete.
@@ -82,11 +277,19 @@ func (s *Scheduler) Start() {
 // Jobs without a timeout default to 30 seconds.
 func (s *Scheduler) Stop() {
 	s.logger.Info("stopping scheduler")
+	s.emitNode(events.TypeNodeStopping)
 
 	// Stop accepting new jobs
+	if s.electionDone != nil {
+		s.resign()
+	}
+	if s.heartbeatDone != nil {
+		close(s.heartbeatDone)
+	}
+	s.stopMonitor()
 	s.cron.Stop()
 
-	// Get currently running jobs
+	// Get currently running jobs, including those removed by ApplyJobs
 	s.mu.Lock()
 	var runningJobs []*Job
 	for _, job := range s.jobs {
@@ -94,6 +297,11 @@ func (s *Scheduler) Stop() {
 			runningJobs = append(runningJobs, job)
 		}
 	}
+	for _, job := range s.retired {
+		if job.IsRunning() {
+			runningJobs = append(runningJobs, job)
+		}
+	}
 	s.mu.Unlock()
 
 	if len(runningJobs) == 0 {
@@ -150,6 +358,11 @@ func (s *Scheduler) waitForJobWithTimeout(job *Job) {
 	}
 }
 
+// NodeID returns the ID of the node the scheduler runs on.
+func (s *Scheduler) NodeID() string {
+	return s.node.ID
+}
+
 // GetJob returns a job by name.
 func (s *Scheduler) GetJob(name string) (*Job, bool) {
 	s.mu.Lock()
@@ -169,6 +382,33 @@ func (s *Scheduler) Jobs() map[string]*Job {
 	return result
 }
 
+// NextRun returns the next and previous scheduled times of a job. Both are
+// zero while the cron scheduler isn't running, e.g. on a follower.
+func (s *Scheduler) NextRun(name string) (next, prev time.Time, ok bool) {
+	job, ok := s.GetJob(name)
+	if !ok {
+		return time.Time{}, time.Time{}, false
+	}
+	entry := s.cron.Entry(job.entryID)
+	return entry.Next, entry.Prev, true
+}
+
+// Trigger starts a run of the job immediately, outside its schedule. The run
+// still competes for the job's lock, so it is skipped if another node holds it.
+func (s *Scheduler) Trigger(name string) error {
+	job, ok := s.GetJob(name)
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.IsRunning() {
+		return ErrJobRunning
+	}
+
+	s.logger.Info("triggering job manually", "job", name)
+	go job.Run()
+	return nil
+}
+
 // Entries returns the cron entries for inspection.
 func (s *Scheduler) Entries() []cron.Entry {
 	return s.cron.Entries()
@@ -1,13 +1,17 @@
 package scheduler
 
 import (
+	"bytes"
+	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"cronlock/internal/config"
 	"cronlock/internal/lock"
+	"cronlock/internal/logging"
 )
 
 func newTestLogger() *slog.Logger {
@@ -54,7 +58,7 @@ func TestAddJob(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "test-job",
 		Schedule: "* * * * *",
-		Command:  "echo test",
+		Command:  config.ShellCommand("echo test"),
 	}
 
 	err := s.AddJob(jobCfg)
@@ -87,9 +91,9 @@ func TestAddJob_MultipleJobs(t *testing.T) {
 	s := New(locker, nodeCfg, logger)
 
 	jobs := []config.JobConfig{
-		{Name: "job1", Schedule: "* * * * *", Command: "echo 1"},
-		{Name: "job2", Schedule: "*/5 * * * *", Command: "echo 2"},
-		{Name: "job3", Schedule: "0 * * * *", Command: "echo 3"},
+		{Name: "job1", Schedule: "* * * * *", Command: config.ShellCommand("echo 1")},
+		{Name: "job2", Schedule: "*/5 * * * *", Command: config.ShellCommand("echo 2")},
+		{Name: "job3", Schedule: "0 * * * *", Command: config.ShellCommand("echo 3")},
 	}
 
 	for _, jobCfg := range jobs {
@@ -129,7 +133,7 @@ func TestAddJob_Disabled(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "disabled-job",
 		Schedule: "* * * * *",
-		Command:  "echo disabled",
+		Command:  config.ShellCommand("echo disabled"),
 		Enabled:  &enabled,
 	}
 
@@ -162,7 +166,7 @@ func TestAddJob_EnabledNil(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "default-enabled-job",
 		Schedule: "* * * * *",
-		Command:  "echo enabled",
+		Command:  config.ShellCommand("echo enabled"),
 		Enabled:  nil,
 	}
 
@@ -189,7 +193,7 @@ func TestAddJob_EnabledTrue(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "enabled-job",
 		Schedule: "* * * * *",
-		Command:  "echo enabled",
+		Command:  config.ShellCommand("echo enabled"),
 		Enabled:  &enabled,
 	}
 
@@ -215,7 +219,7 @@ func TestAddJob_InvalidSchedule(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "invalid-schedule-job",
 		Schedule: "not a valid cron",
-		Command:  "echo test",
+		Command:  config.ShellCommand("echo test"),
 	}
 
 	err := s.AddJob(jobCfg)
@@ -246,7 +250,7 @@ func TestAddJob_InvalidScheduleVariants(t *testing.T) {
 			jobCfg := config.JobConfig{
 				Name:     "test-job",
 				Schedule: schedule,
-				Command:  "echo test",
+				Command:  config.ShellCommand("echo test"),
 			}
 
 			err := s.AddJob(jobCfg)
@@ -265,16 +269,16 @@ func TestAddJob_ValidScheduleVariants(t *testing.T) {
 	logger := newTestLogger()
 
 	validSchedules := []string{
-		"* * * * *",          // every minute
-		"*/5 * * * *",        // every 5 minutes
-		"0 * * * *",          // every hour
-		"0 0 * * *",          // every day at midnight
-		"0 0 * * 0",          // every Sunday at midnight
-		"@hourly",            // cron descriptor
-		"@daily",             // cron descriptor
-		"@weekly",            // cron descriptor
-		"30 * * * * *",       // with optional seconds
-		"0 30 * * * *",       // with optional seconds
+		"* * * * *",    // every minute
+		"*/5 * * * *",  // every 5 minutes
+		"0 * * * *",    // every hour
+		"0 0 * * *",    // every day at midnight
+		"0 0 * * 0",    // every Sunday at midnight
+		"@hourly",      // cron descriptor
+		"@daily",       // cron descriptor
+		"@weekly",      // cron descriptor
+		"30 * * * * *", // with optional seconds
+		"0 30 * * * *", // with optional seconds
 	}
 
 	for _, schedule := range validSchedules {
@@ -284,7 +288,7 @@ func TestAddJob_ValidScheduleVariants(t *testing.T) {
 			jobCfg := config.JobConfig{
 				Name:     "test-job",
 				Schedule: schedule,
-				Command:  "echo test",
+				Command:  config.ShellCommand("echo test"),
 			}
 
 			err := s.AddJob(jobCfg)
@@ -307,7 +311,7 @@ func TestGetJob(t *testing.T) {
 	jobCfg := config.JobConfig{
 		Name:     "my-job",
 		Schedule: "* * * * *",
-		Command:  "echo test",
+		Command:  config.ShellCommand("echo test"),
 	}
 	_ = s.AddJob(jobCfg)
 
@@ -349,7 +353,7 @@ func TestJobs(t *testing.T) {
 		_ = s.AddJob(config.JobConfig{
 			Name:     name,
 			Schedule: "* * * * *",
-			Command:  "echo " + name,
+			Command:  config.ShellCommand("echo " + name),
 		})
 	}
 
@@ -378,7 +382,7 @@ func TestScheduler_StartStop(t *testing.T) {
 	_ = s.AddJob(config.JobConfig{
 		Name:     "test",
 		Schedule: "* * * * *",
-		Command:  "echo test",
+		Command:  config.ShellCommand("echo test"),
 	})
 
 	// Start and stop should not panic
@@ -401,11 +405,153 @@ func TestScheduler_Entries(t *testing.T) {
 	}
 
 	// Add jobs and check entries
-	_ = s.AddJob(config.JobConfig{Name: "job1", Schedule: "* * * * *", Command: "echo 1"})
-	_ = s.AddJob(config.JobConfig{Name: "job2", Schedule: "*/5 * * * *", Command: "echo 2"})
+	_ = s.AddJob(config.JobConfig{Name: "job1", Schedule: "* * * * *", Command: config.ShellCommand("echo 1")})
+	_ = s.AddJob(config.JobConfig{Name: "job2", Schedule: "*/5 * * * *", Command: config.ShellCommand("echo 2")})
 
 	entries = s.Entries()
 	if len(entries) != 2 {
 		t.Errorf("Entries() = %d, want 2", len(entries))
 	}
 }
+
+func TestAddJob_ConstraintsNotSatisfied(t *testing.T) {
+	locker := lock.NewMockLocker()
+	nodeCfg := config.NodeConfig{
+		Labels: map[string]string{"role": "web"},
+	}
+	s := New(locker, nodeCfg, newTestLogger())
+
+	err := s.AddJob(config.JobConfig{
+		Name:        "backup",
+		Schedule:    "* * * * *",
+		Command:     config.ShellCommand("echo test"),
+		Constraints: []string{"role=batch"},
+	})
+	if err != nil {
+		t.Fatalf("AddJob() error = %v", err)
+	}
+
+	if _, ok := s.GetJob("backup"); ok {
+		t.Error("job with unsatisfied constraints should not be registered")
+	}
+	if len(s.Entries()) != 0 {
+		t.Errorf("Entries() = %d, want 0", len(s.Entries()))
+	}
+}
+
+func TestAddJob_PreferDelay(t *testing.T) {
+	locker := lock.NewMockLocker()
+	nodeCfg := config.NodeConfig{
+		Labels: map[string]string{"zone": "b"},
+	}
+	s := New(locker, nodeCfg, newTestLogger())
+
+	jobs := []config.JobConfig{
+		{Name: "no-prefs", Schedule: "* * * * *", Command: config.ShellCommand("true")},
+		{Name: "prefers-a", Schedule: "* * * * *", Command: config.ShellCommand("true"), Prefer: []string{"zone=a"}},
+		{Name: "prefers-a-custom", Schedule: "* * * * *", Command: config.ShellCommand("true"), Prefer: []string{"zone=a"}, PreferDelay: 10 * time.Second},
+		{Name: "prefers-b", Schedule: "* * * * *", Command: config.ShellCommand("true"), Prefer: []string{"zone=b"}},
+	}
+	for _, cfg := range jobs {
+		if err := s.AddJob(cfg); err != nil {
+			t.Fatalf("AddJob(%s) error = %v", cfg.Name, err)
+		}
+	}
+
+	want := map[string]time.Duration{
+		"no-prefs":         0,
+		"prefers-a":        defaultPreferDelay,
+		"prefers-a-custom": 10 * time.Second,
+		"prefers-b":        0,
+	}
+	for name, delay := range want {
+		job, _ := s.GetJob(name)
+		if job.acquireDelay != delay {
+			t.Errorf("%s acquireDelay = %v, want %v", name, job.acquireDelay, delay)
+		}
+	}
+}
+
+func TestScheduler_NextRun(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger())
+	_ = s.AddJob(config.JobConfig{Name: "job1", Schedule: "@every 1h", Command: config.ShellCommand("true")})
+
+	if _, _, ok := s.NextRun("missing"); ok {
+		t.Error("NextRun(missing) ok = true, want false")
+	}
+
+	// Not scheduled until the cron scheduler starts
+	next, _, ok := s.NextRun("job1")
+	if !ok || !next.IsZero() {
+		t.Errorf("NextRun() before Start = %v, %v, want zero time", next, ok)
+	}
+
+	s.Start()
+	defer s.Stop()
+
+	waitFor(t, time.Second, func() bool {
+		next, _, _ = s.NextRun("job1")
+		return !next.IsZero()
+	})
+	if until := time.Until(next); until <= 0 || until > time.Hour {
+		t.Errorf("NextRun() = %v, want within the next hour", next)
+	}
+}
+
+func TestScheduler_Trigger(t *testing.T) {
+	locker := lock.NewMockLocker()
+	s := New(locker, config.NodeConfig{}, newTestLogger())
+	_ = s.AddJob(config.JobConfig{Name: "job1", Schedule: "@every 1h", Command: config.ShellCommand("sleep 0.2")})
+
+	if err := s.Trigger("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Trigger(missing) error = %v, want %v", err, ErrJobNotFound)
+	}
+
+	if err := s.Trigger("job1"); err != nil {
+		t.Fatalf("Trigger() error = %v", err)
+	}
+
+	job, _ := s.GetJob("job1")
+	waitFor(t, time.Second, job.IsRunning)
+
+	if err := s.Trigger("job1"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("Trigger() while running error = %v, want %v", err, ErrJobRunning)
+	}
+
+	waitFor(t, 2*time.Second, func() bool { return !job.IsRunning() })
+	if len(locker.AcquireCalls) != 1 {
+		t.Errorf("Acquire() called %d times, want 1", len(locker.AcquireCalls))
+	}
+}
+
+func TestScheduler_AddJob_LogLevel(t *testing.T) {
+	var buf bytes.Buffer
+	handler := slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})
+	logger := logging.WithLevel(slog.New(handler), slog.LevelInfo)
+
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, logger)
+	for _, cfg := range []config.JobConfig{
+		{Name: "noisy", Schedule: "@every 1h", Command: config.ShellCommand("true"), LogLevel: "debug"},
+		{Name: "normal", Schedule: "@every 1h", Command: config.ShellCommand("true")},
+	} {
+		if err := s.AddJob(cfg); err != nil {
+			t.Fatalf("AddJob() error = %v", err)
+		}
+	}
+
+	for _, name := range []string{"noisy", "normal"} {
+		job, _ := s.GetJob(name)
+		job.Run()
+	}
+
+	logs := buf.String()
+	if !strings.Contains(logs, `level=DEBUG msg="released lock" job=noisy`) {
+		t.Errorf("logs missing debug lines of the noisy job:\n%s", logs)
+	}
+	if strings.Contains(logs, "level=DEBUG msg=\"released lock\" job=normal") {
+		t.Errorf("logs contain debug lines of the normal job:\n%s", logs)
+	}
+	if !strings.Contains(logs, `msg="job completed successfully" job=normal`) {
+		t.Errorf("logs missing info lines of the normal job:\n%s", logs)
+	}
+}
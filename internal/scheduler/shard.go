@@ -0,0 +1,298 @@
+package scheduler
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/state"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Environment variables passed to each shard's command.
+const (
+	envShardIndex = "CRONLOCK_SHARD_INDEX"
+	envShardCount = "CRONLOCK_SHARD_COUNT"
+)
+
+// errShardsFailed is the history error of a sharded run with failed shards.
+const errShardsFailed = "one or more shards failed"
+
+// shardDeadlineSlack is how long after its next fire, or its timeout, a
+// sharded run may take to complete before it counts as stalled.
+const shardDeadlineSlack = time.Minute
+
+// shardLockName returns the lock name for one shard of a job.
+// With the locker's key format this becomes {prefix}job:{name}:shard:{index}.
+func shardLockName(jobName string, index int) string {
+	return fmt.Sprintf("%s:shard:%d", jobName, index)
+}
+
+// scheduledTime returns the cron tick a run belongs to. Nodes fire within
+// milliseconds of the scheduled second, so rounding gives every node the
+// same value as long as their clocks agree to within half a second.
+func scheduledTime(now time.Time) time.Time {
+	return now.Round(time.Second)
+}
+
+// runSharded competes for the job's shard locks and runs the command once per
+// acquired shard. The run as a whole succeeds only when every shard, on
+// whichever node ran it, reports success.
+func (j *Job) runSharded(ctx context.Context, runID string, scheduledAt time.Time, lockTTL time.Duration) {
+	span := trace.SpanFromContext(ctx)
+	shards := j.acquireShards(ctx, lockTTL)
+	if len(shards) == 0 {
+		j.logger.Debug("no shard locks acquired, other nodes are executing")
+		j.skip(ctx, span, skipReasonLockHeld)
+		return
+	}
+
+	lockNames := make([]string, len(shards))
+	for i, index := range shards {
+		lockNames[i] = shardLockName(j.config.Name, index)
+	}
+	if !j.reserveSlot(ctx, span, lockNames...) {
+		return
+	}
+	if j.load != nil {
+		defer j.load.done()
+	}
+
+	j.logger.Info("acquired shard locks, starting execution",
+		"shards", shards,
+		"shard_count", j.config.Shards,
+	)
+
+	execCtx, cancel := j.execContext(ctx)
+	defer cancel()
+
+	span.SetAttributes(attrRunID.String(runID), attrShards.IntSlice(shards))
+	j.runHook(ctx, hookStart, hookRun{runID: runID, scheduledAt: scheduledAt})
+
+	var (
+		wg    sync.WaitGroup
+		mu    sync.Mutex
+		hooks []hookCall
+	)
+	for _, index := range shards {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			calls := j.runShard(ctx, execCtx, runID, scheduledAt, index, lockTTL)
+			mu.Lock()
+			hooks = append(hooks, calls...)
+			mu.Unlock()
+		}(index)
+	}
+	wg.Wait()
+
+	// Shards' on_timeout hooks go before the whole run's hooks
+	slices.SortStableFunc(hooks, func(a, b hookCall) int {
+		return cmp.Compare(hookOrder(a.hook), hookOrder(b.hook))
+	})
+
+	j.finish(ctx, hooks, lockNames...)
+}
+
+// acquireShards tries each shard lock in turn until the node holds
+// max_shards_per_node shards or every shard has been tried.
+func (j *Job) acquireShards(ctx context.Context, lockTTL time.Duration) []int {
+	count := j.config.Shards
+	limit := j.config.MaxShardsPerNode
+	if limit == 0 || limit > count {
+		limit = count
+	}
+
+	// Start at a random shard so nodes firing at the same moment spread
+	// out instead of all contending for shard 0 first.
+	offset := rand.IntN(count)
+
+	var acquired []int
+	for i := 0; i < count && len(acquired) < limit; i++ {
+		index := (offset + i) % count
+		ok, err := j.acquire(ctx, shardLockName(j.config.Name, index), lockTTL)
+		if err != nil {
+			j.logger.Error("failed to acquire shard lock", "shard", index, "error", err)
+			continue
+		}
+		if ok {
+			acquired = append(acquired, index)
+		}
+	}
+
+	slices.Sort(acquired)
+	return acquired
+}
+
+// runShard executes one shard and reports its result to the state store.
+// It returns the hooks to run: on_timeout if the shard timed out and, on
+// the node whose report completes the run, the hooks for the whole run.
+func (j *Job) runShard(ctx, execCtx context.Context, runID string, scheduledAt time.Time, index int, lockTTL time.Duration) []hookCall {
+	logger := j.logger.With("run_id", runID, "shard", index)
+
+	ctx, span := j.startSpan(ctx, "shard", attrShard.Int(index))
+	defer span.End()
+	execCtx = trace.ContextWithSpan(execCtx, span)
+
+	env := make(map[string]string, len(j.config.Env)+2)
+	maps.Copy(env, j.env())
+	env[envShardIndex] = strconv.Itoa(index)
+	env[envShardCount] = strconv.Itoa(j.config.Shards)
+
+	logName := fmt.Sprintf("%s-shard-%d", runID, index)
+	shardEnv := map[string]string{envShardIndex: env[envShardIndex], envShardCount: env[envShardCount]}
+	lockLost := func() { j.notify(config.EventLockLost, hookRun{runID: runID, scheduledAt: scheduledAt}, 0) }
+	slow := func() { j.warnSlow(ctx, logger, hookRun{runID: runID, scheduledAt: scheduledAt, env: shardEnv}) }
+	result, _ := j.execute(ctx, execCtx, logger, shardLockName(j.config.Name, index), lockTTL, env, logName, lockLost, slow)
+
+	// A shard's timeout is reported by the node that ran it
+	var hooks []hookCall
+	shardTimedOut := timedOut(execCtx, result)
+	span.SetAttributes(attrOutcome.String(runOutcome(result, shardTimedOut)), attrExitCode.Int(result.ExitCode))
+	if !result.Success() {
+		span.SetStatus(codes.Error, "shard "+runOutcome(result, shardTimedOut))
+	}
+	if shardTimedOut && j.config.OnTimeout != "" {
+		run := finishedRun(runID, scheduledAt, result, true)
+		run.env = shardEnv
+		hooks = append(hooks, hookCall{hook: hookTimeout, run: run})
+	}
+
+	if result.Success() {
+		logger.Info("shard completed successfully",
+			"duration", formatDuration(result.Duration),
+			"exit_code", result.ExitCode,
+		)
+	} else {
+		logger.Error("shard failed",
+			"duration", formatDuration(result.Duration),
+			"exit_code", result.ExitCode,
+			"timed_out", shardTimedOut,
+			"oom_killed", result.OOMKilled,
+			"error", result.Err,
+			"stderr", result.Stderr,
+		)
+	}
+
+	if j.store == nil {
+		return hooks
+	}
+
+	outcome, err := j.store.RecordShardResult(ctx, j.config.Name, scheduledAt, index, j.config.Shards, result.Success(), j.shardDeadline(scheduledAt))
+	if err != nil {
+		logger.Error("failed to record shard result", "error", err)
+		return hooks
+	}
+
+	if outcome == state.ShardsPending {
+		return hooks
+	}
+
+	// Record the run as a whole, spanning from its scheduled time
+	rec := state.RunRecord{
+		Job:         j.config.Name,
+		RunID:       runID,
+		NodeID:      j.nodeID,
+		Outcome:     state.OutcomeSuccess,
+		ScheduledAt: scheduledAt,
+		StartedAt:   scheduledAt,
+		Duration:    time.Since(scheduledAt),
+	}
+	if outcome == state.ShardsFailed {
+		rec.Outcome = state.OutcomeFailure
+		rec.Error = errShardsFailed
+	}
+	// Shards run on other nodes too, so the run as a whole is slow if it
+	// took longer than warn_after from its scheduled time
+	rec.Slow = j.config.WarnAfter > 0 && rec.Duration > j.config.WarnAfter
+	j.recordRun(ctx, rec)
+
+	// The whole run's hooks get its outcome, with exit code 1 if any shard
+	// failed, but no output
+	run := hookRun{
+		runID:       runID,
+		scheduledAt: scheduledAt,
+		finished:    true,
+		outcome:     outcomeSuccess,
+		duration:    rec.Duration,
+	}
+	switch outcome {
+	case state.ShardsSucceeded:
+		j.logger.Info("all shards completed successfully", "shard_count", j.config.Shards)
+	case state.ShardsFailed:
+		j.logger.Error("sharded run failed", "shard_count", j.config.Shards)
+		run.outcome = outcomeFailure
+		run.exitCode = 1
+	}
+	j.notifyOutcome(ctx, run)
+	return append(hooks, j.finishHooks(run)...)
+}
+
+// shardDeadline returns when a sharded run that not every shard reported
+// counts as stalled: shardDeadlineSlack after the job's next fire or, if
+// later, its timeout. By then every node has had its chance at the shards.
+func (j *Job) shardDeadline(scheduledAt time.Time) time.Time {
+	deadline := scheduledAt.Add(j.config.Timeout)
+	if schedule, err := config.ParseSchedule(j.config.Schedule); err == nil {
+		if next := schedule.Next(scheduledAt); next.After(deadline) {
+			deadline = next
+		}
+	}
+	return deadline.Add(shardDeadlineSlack)
+}
+
+// claimStalledRuns claims the job's sharded runs that didn't complete by
+// their deadline, e.g. because fewer nodes than shards were available, and
+// records each as failed in the background.
+func (j *Job) claimStalledRuns(ctx context.Context) {
+	runs, err := j.store.ClaimStalledShardRuns(ctx, j.config.Name, time.Now())
+	if err != nil {
+		j.logger.Warn("failed to check for stalled sharded runs", "error", err)
+		return
+	}
+	for _, run := range runs {
+		go j.failStalledRun(context.Background(), run)
+	}
+}
+
+// failStalledRun records a stalled sharded run as failed, sending its
+// failure and running its hooks as for a failed shard.
+func (j *Job) failStalledRun(ctx context.Context, stalled state.StalledShardRun) {
+	missing := j.config.Shards - stalled.Reported
+	j.logger.Error("sharded run stalled, not every shard reported",
+		"scheduled_at", stalled.ScheduledAt.Format(time.RFC3339),
+		"missing_shards", missing,
+		"shard_count", j.config.Shards,
+	)
+
+	rec := state.RunRecord{
+		Job:         j.config.Name,
+		NodeID:      j.nodeID,
+		Outcome:     state.OutcomeFailure,
+		ScheduledAt: stalled.ScheduledAt,
+		StartedAt:   stalled.ScheduledAt,
+		Duration:    time.Since(stalled.ScheduledAt),
+		Error:       fmt.Sprintf("%d of %d shards did not report", missing, j.config.Shards),
+	}
+	j.recordRun(ctx, rec)
+
+	run := hookRun{
+		scheduledAt: stalled.ScheduledAt,
+		finished:    true,
+		outcome:     outcomeFailure,
+		exitCode:    1,
+		duration:    rec.Duration,
+		err:         rec.Error,
+	}
+	j.notifyOutcome(ctx, run)
+	j.runHooks(ctx, j.finishHooks(run))
+}
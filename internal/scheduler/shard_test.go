@@ -0,0 +1,219 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestShardLockName(t *testing.T) {
+	if got := shardLockName("reindex", 3); got != "reindex:shard:3" {
+		t.Errorf("shardLockName() = %q, want %q", got, "reindex:shard:3")
+	}
+}
+
+func TestScheduledTime(t *testing.T) {
+	base := time.Date(2026, 1, 1, 2, 0, 0, 0, time.UTC)
+
+	// Nodes firing a few milliseconds apart agree on the tick
+	for _, offset := range []time.Duration{0, 3 * time.Millisecond, 250 * time.Millisecond} {
+		if got := scheduledTime(base.Add(offset)); !got.Equal(base) {
+			t.Errorf("scheduledTime(+%v) = %v, want %v", offset, got, base)
+		}
+	}
+}
+
+func TestJob_Run_Sharded_AcquiresAllShards(t *testing.T) {
+	locker := lock.NewMockLocker()
+	store := state.NewMockStore()
+	tmpDir := t.TempDir()
+
+	cfg := config.JobConfig{
+		Name:    "reindex",
+		Command: config.ShellCommand("echo $CRONLOCK_SHARD_COUNT > " + tmpDir + "/shard-$CRONLOCK_SHARD_INDEX"),
+		Shards:  3,
+	}
+
+	job := newTestJob(cfg, locker)
+	job.store = store
+	job.Run()
+
+	if len(locker.AcquireCalls) != 3 {
+		t.Fatalf("Acquire() called %d times, want 3", len(locker.AcquireCalls))
+	}
+	if len(locker.ReleaseCalls) != 3 {
+		t.Errorf("Release() called %d times, want 3", len(locker.ReleaseCalls))
+	}
+
+	for i := 0; i < 3; i++ {
+		content, err := os.ReadFile(filepath.Join(tmpDir, "shard-"+strconv.Itoa(i)))
+		if err != nil {
+			t.Fatalf("shard %d did not run: %v", i, err)
+		}
+		if strings.TrimSpace(string(content)) != "3" {
+			t.Errorf("shard %d CRONLOCK_SHARD_COUNT = %q, want %q", i, content, "3")
+		}
+	}
+
+	if len(store.ShardCalls) != 3 {
+		t.Errorf("RecordShardResult() called %d times, want 3", len(store.ShardCalls))
+	}
+}
+
+func TestJob_Run_Sharded_MaxShardsPerNode(t *testing.T) {
+	locker := lock.NewMockLocker()
+
+	cfg := config.JobConfig{
+		Name:             "reindex",
+		Command:          config.ShellCommand("true"),
+		Shards:           4,
+		MaxShardsPerNode: 2,
+	}
+
+	job := newTestJob(cfg, locker)
+	job.store = state.NewMockStore()
+	job.Run()
+
+	if len(locker.AcquireCalls) != 2 {
+		t.Errorf("Acquire() called %d times, want 2", len(locker.AcquireCalls))
+	}
+	for _, call := range locker.AcquireCalls {
+		if !strings.HasPrefix(call.JobName, "reindex:shard:") {
+			t.Errorf("Acquire() jobName = %q, want shard lock", call.JobName)
+		}
+	}
+}
+
+func TestJob_Run_Sharded_SkipsHeldShards(t *testing.T) {
+	locker := lock.NewMockLocker()
+	locker.SetLockHeld("reindex:shard:0", true)
+	locker.SetLockHeld("reindex:shard:1", true)
+
+	cfg := config.JobConfig{
+		Name:    "reindex",
+		Command: config.ShellCommand("true"),
+		Shards:  3,
+	}
+
+	job := newTestJob(cfg, locker)
+	job.store = state.NewMockStore()
+	job.Run()
+
+	if len(locker.ReleaseCalls) != 1 || locker.ReleaseCalls[0] != "reindex:shard:2" {
+		t.Errorf("Release() calls = %v, want [reindex:shard:2]", locker.ReleaseCalls)
+	}
+}
+
+func TestJob_Run_Sharded_HooksRunOnceAllShardsReport(t *testing.T) {
+	locker := lock.NewMockLocker()
+	tmpDir := t.TempDir()
+	successMarker := tmpDir + "/hook-success"
+	failureMarker := tmpDir + "/hook-failure"
+
+	cfg := config.JobConfig{
+		Name:      "reindex",
+		Command:   config.ShellCommand("test $CRONLOCK_SHARD_INDEX != 1"),
+		Shards:    2,
+		OnSuccess: "touch " + successMarker,
+		OnFailure: "touch " + failureMarker,
+	}
+
+	job := newTestJob(cfg, locker)
+	job.store = state.NewMockStore()
+	job.Run()
+
+	if _, err := os.Stat(failureMarker); os.IsNotExist(err) {
+		t.Error("on_failure hook was not executed when a shard failed")
+	}
+	if _, err := os.Stat(successMarker); !os.IsNotExist(err) {
+		t.Error("on_success hook should not be called when a shard failed")
+	}
+}
+
+func TestJob_ShardDeadline(t *testing.T) {
+	tick := time.Date(2026, 10, 18, 14, 0, 0, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		timeout  time.Duration
+		want     time.Time
+	}{
+		{"*/5 * * * *", 0, tick.Add(5*time.Minute + shardDeadlineSlack)},
+		{"*/5 * * * *", time.Hour, tick.Add(time.Hour + shardDeadlineSlack)},
+		{"@daily", time.Hour, tick.Add(10*time.Hour + shardDeadlineSlack)},
+	}
+	for _, tt := range tests {
+		job := newTestJob(config.JobConfig{Name: "reindex", Schedule: tt.schedule, Timeout: tt.timeout, Shards: 2}, lock.NewMockLocker())
+		if got := job.shardDeadline(tick); !got.Equal(tt.want) {
+			t.Errorf("shardDeadline() with %q, timeout %v = %v, want %v", tt.schedule, tt.timeout, got, tt.want)
+		}
+	}
+}
+
+func TestJob_FailStalledRun(t *testing.T) {
+	store := state.NewMockStore()
+	failureMarker := t.TempDir() + "/hook-failure"
+
+	// Only one node with one shard per node: two shards never run
+	cfg := config.JobConfig{
+		Name:             "reindex",
+		Schedule:         "* * * * *",
+		Command:          config.ShellCommand("true"),
+		Shards:           3,
+		MaxShardsPerNode: 1,
+		OnFailure:        "touch " + failureMarker,
+	}
+	job := newTestJob(cfg, lock.NewMockLocker())
+	job.store = store
+	job.Run()
+
+	ctx := context.Background()
+	if runs, _ := store.ClaimStalledShardRuns(ctx, "reindex", time.Now()); len(runs) != 0 {
+		t.Fatalf("ClaimStalledShardRuns() before the deadline = %+v, want none", runs)
+	}
+	runs, _ := store.ClaimStalledShardRuns(ctx, "reindex", time.Now().Add(3*time.Minute))
+	if len(runs) != 1 || runs[0].Reported != 1 {
+		t.Fatalf("ClaimStalledShardRuns() = %+v, want the run with 1 shard reported", runs)
+	}
+
+	job.failStalledRun(ctx, runs[0])
+
+	history, _ := store.History(ctx, "reindex", 10)
+	if len(history) != 1 || history[0].Outcome != state.OutcomeFailure || history[0].Error != "2 of 3 shards did not report" {
+		t.Errorf("History() = %+v, want the run recorded as failed", history)
+	}
+	if _, err := os.Stat(failureMarker); err != nil {
+		t.Error("on_failure hook was not executed for the stalled run")
+	}
+}
+
+func TestAddJob_ShardedRequiresStore(t *testing.T) {
+	s := New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger())
+
+	err := s.AddJob(config.JobConfig{
+		Name:     "reindex",
+		Schedule: "* * * * *",
+		Command:  config.ShellCommand("true"),
+		Shards:   2,
+	})
+	if err == nil {
+		t.Fatal("AddJob() error = nil, want error for sharded job without store")
+	}
+
+	s = New(lock.NewMockLocker(), config.NodeConfig{}, newTestLogger(), WithStore(state.NewMockStore()))
+	if err := s.AddJob(config.JobConfig{
+		Name:     "reindex",
+		Schedule: "* * * * *",
+		Command:  config.ShellCommand("true"),
+		Shards:   2,
+	}); err != nil {
+		t.Errorf("AddJob() error = %v", err)
+	}
+}
@@ -0,0 +1,69 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"maps"
+	"sync"
+	"time"
+
+	"cronlock/internal/config"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runCounter counts runs per job on this node.
+type runCounter struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func newRunCounter() *runCounter {
+	return &runCounter{counts: make(map[string]int64)}
+}
+
+// add counts one run of the job.
+func (c *runCounter) add(job string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.counts[job]++
+}
+
+// snapshot returns the counts so far.
+func (c *runCounter) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return maps.Clone(c.counts)
+}
+
+// SlowRuns returns how many runs of each job this node has flagged as slow
+// since it started. Jobs without slow runs are left out.
+func (s *Scheduler) SlowRuns() map[string]int64 {
+	return s.slowRuns.snapshot()
+}
+
+// watchSlow calls slow in its own goroutine once the command has run for
+// warn_after, unless the returned stop function is called first. stop
+// reports whether slow was called.
+func (j *Job) watchSlow(slow func()) (stop func() bool) {
+	if j.config.WarnAfter <= 0 || slow == nil {
+		return func() bool { return false }
+	}
+	timer := time.AfterFunc(j.config.WarnAfter, slow)
+	return func() bool { return !timer.Stop() }
+}
+
+// warnSlow reports a run whose command is still going after warn_after: it
+// logs a warning, counts the run, sends the slow event and runs on_slow.
+// The command is left running.
+func (j *Job) warnSlow(ctx context.Context, logger *slog.Logger, run hookRun) {
+	logger.Warn("job is still running after warn_after", "warn_after", j.config.WarnAfter.String())
+	trace.SpanFromContext(ctx).AddEvent("warn_after exceeded")
+	if j.slowRuns != nil {
+		j.slowRuns.add(j.config.Name)
+	}
+
+	run.duration = j.config.WarnAfter
+	j.notify(config.EventSlow, run, 0)
+	j.runHook(ctx, hookSlow, run)
+}
@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+	"cronlock/internal/state"
+)
+
+func TestJob_Run_WarnAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		command  string
+		wantSlow bool
+	}{
+		{"slow", "sleep 0.5", true},
+		{"fast", "true", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			envFile := filepath.Join(t.TempDir(), "env")
+			cfg := config.JobConfig{
+				Name:      "slow-job",
+				Command:   config.ShellCommand(tt.command),
+				WarnAfter: 100 * time.Millisecond,
+				OnSlow:    "env > " + envFile,
+				Notify:    []config.NotifyConfig{{Notifier: "rec", Events: []string{config.EventSlow}}},
+			}
+			store := state.NewMockStore()
+			job := newTestJob(cfg, lock.NewMockLocker())
+			job.nodeID = "node-1"
+			job.store = store
+			job.slowRuns = newRunCounter()
+			events := runNotified(t, job)
+
+			history, _ := store.History(context.Background(), "slow-job", 10)
+			if len(history) != 1 || history[0].Slow != tt.wantSlow {
+				t.Fatalf("history = %+v, want one run with Slow = %v", history, tt.wantSlow)
+			}
+			if history[0].Outcome != state.OutcomeSuccess {
+				t.Errorf("Outcome = %q, want %q; a slow run is not stopped", history[0].Outcome, state.OutcomeSuccess)
+			}
+			var wantCount int64
+			if tt.wantSlow {
+				wantCount = 1
+			}
+			if got := job.slowRuns.snapshot()["slow-job"]; got != wantCount {
+				t.Errorf("slow run count = %d, want %d", got, wantCount)
+			}
+
+			if !tt.wantSlow {
+				if len(events) != 0 {
+					t.Errorf("events = %+v, want none", events)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Event != config.EventSlow || events[0].RunID != history[0].RunID {
+				t.Fatalf("events = %+v, want one slow event for the run", events)
+			}
+			if events[0].Duration != 0.1 {
+				t.Errorf("Duration = %v, want 0.1", events[0].Duration)
+			}
+
+			env := readEnvFile(t, envFile)
+			if env[envHook] != hookSlow || env[envRunID] != history[0].RunID {
+				t.Errorf("hook env %s = %q, %s = %q, want %q, %q", envHook, env[envHook], envRunID, env[envRunID], hookSlow, history[0].RunID)
+			}
+			if _, ok := env[envOutcome]; ok {
+				t.Errorf("hook env %s = %q, want it unset while the run is going", envOutcome, env[envOutcome])
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"context"
+	"maps"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// tracerName is the instrumentation scope of cronlock's spans.
+const tracerName = "cronlock/internal/scheduler"
+
+// Span attributes.
+const (
+	attrJob         = attribute.Key("cronlock.job")
+	attrNodeID      = attribute.Key("cronlock.node_id")
+	attrRunID       = attribute.Key("cronlock.run_id")
+	attrScheduledAt = attribute.Key("cronlock.scheduled_at")
+	attrOutcome     = attribute.Key("cronlock.outcome")
+	attrSkipReason  = attribute.Key("cronlock.skip_reason")
+	attrExitCode    = attribute.Key("cronlock.exit_code")
+	attrOOMKilled   = attribute.Key("cronlock.oom_killed")
+	attrSlow        = attribute.Key("cronlock.slow")
+	attrShard       = attribute.Key("cronlock.shard")
+	attrShards      = attribute.Key("cronlock.shards")
+	attrLock        = attribute.Key("cronlock.lock")
+	attrAcquired    = attribute.Key("cronlock.lock.acquired")
+	attrExtended    = attribute.Key("cronlock.lock.extended")
+	attrHook        = attribute.Key("cronlock.hook")
+)
+
+// outcomeSkipped is the outcome of a run span that didn't run the command,
+// with the reason in attrSkipReason.
+const outcomeSkipped = "skipped"
+
+// Environment variables carrying the trace context to commands and hooks,
+// per the OpenTelemetry environment variable carrier convention.
+const (
+	envTraceParent = "TRACEPARENT"
+	envTraceState  = "TRACESTATE"
+)
+
+// WithTracerProvider makes jobs trace their runs with tp.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(s *Scheduler) {
+		s.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// noopTracer is the tracer of jobs when tracing is off.
+var noopTracer = noop.NewTracerProvider().Tracer(tracerName)
+
+// startSpan starts a span for a step of a run, as a child of the span in
+// ctx.
+func (j *Job) startSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return j.tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// spanError marks a span as failed with err.
+func spanError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// endSpan ends a span, marking it as failed if err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		spanError(span, err)
+	}
+	span.End()
+}
+
+// skipSpan marks a run span as skipped for reason.
+func skipSpan(span trace.Span, reason string) {
+	span.SetAttributes(attrOutcome.String(outcomeSkipped), attrSkipReason.String(reason))
+}
+
+// withTraceContext returns env plus the trace context of the span in ctx,
+// so that spans the process creates join the run's trace. env is returned
+// as is if there is no span to propagate.
+func withTraceContext(ctx context.Context, env map[string]string) map[string]string {
+	carrier := propagation.MapCarrier{}
+	propagation.TraceContext{}.Inject(ctx, carrier)
+	traceParent := carrier.Get("traceparent")
+	if traceParent == "" {
+		return env
+	}
+
+	out := make(map[string]string, len(env)+2)
+	maps.Copy(out, env)
+	out[envTraceParent] = traceParent
+	if traceState := carrier.Get("tracestate"); traceState != "" {
+		out[envTraceState] = traceState
+	}
+	return out
+}
@@ -0,0 +1,162 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cronlock/internal/config"
+	"cronlock/internal/lock"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// newTracedJob returns a job whose spans go to the returned exporter.
+func newTracedJob(t *testing.T, cfg config.JobConfig, locker lock.Locker) (*Job, *tracetest.InMemoryExporter) {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	t.Cleanup(func() { _ = tp.Shutdown(context.Background()) })
+
+	job := newTestJob(cfg, locker)
+	job.nodeID = "node-1"
+	job.tracer = tp.Tracer(tracerName)
+	return job, exporter
+}
+
+// spanAttr returns a span's attribute, or an empty value if it has none.
+func spanAttr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestJob_Run_Tracing(t *testing.T) {
+	tmpDir := t.TempDir()
+	cmdEnv := filepath.Join(tmpDir, "cmd-env")
+	hookEnv := filepath.Join(tmpDir, "hook-env")
+
+	cfg := config.JobConfig{
+		Name:      "traced",
+		Command:   config.ShellCommand("env > " + cmdEnv + "; sleep 1.2"),
+		LockTTL:   3 * time.Second, // renewed every second
+		OnStart:   "true",
+		OnSuccess: "env > " + hookEnv,
+	}
+	job, exporter := newTracedJob(t, cfg, lock.NewMockLocker())
+	job.gracePeriod = 10 * time.Millisecond
+	job.Run()
+
+	spans := exporter.GetSpans()
+	byName := make(map[string]tracetest.SpanStub)
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	root, ok := byName["job.run"]
+	if !ok {
+		t.Fatalf("spans = %v, want a job.run span", spanNames(spans))
+	}
+	if root.Parent.IsValid() {
+		t.Errorf("job.run has parent %v, want a root span", root.Parent.SpanID())
+	}
+	for key, want := range map[attribute.Key]string{
+		attrJob:     "traced",
+		attrNodeID:  "node-1",
+		attrOutcome: outcomeSuccess,
+	} {
+		if got := spanAttr(root, key).AsString(); got != want {
+			t.Errorf("job.run %s = %q, want %q", key, got, want)
+		}
+	}
+	if got := spanAttr(root, attrExitCode).AsInt64(); got != 0 {
+		t.Errorf("job.run %s = %d, want 0", attrExitCode, got)
+	}
+
+	for _, name := range []string{"lock.acquire", "hook on_start", "command", "lock.renew", "hook on_success", "grace_wait", "lock.release"} {
+		span, ok := byName[name]
+		if !ok {
+			t.Errorf("spans = %v, want a %s span", spanNames(spans), name)
+			continue
+		}
+		if span.Parent.SpanID() != root.SpanContext.SpanID() {
+			t.Errorf("%s parent = %v, want job.run", name, span.Parent.SpanID())
+		}
+	}
+	if got := spanAttr(byName["lock.renew"], attrExtended); !got.AsBool() {
+		t.Errorf("lock.renew %s = %v, want true", attrExtended, got.AsBool())
+	}
+
+	// The command and hooks get the trace context of their own span
+	command := byName["command"]
+	want := "00-" + command.SpanContext.TraceID().String() + "-" + command.SpanContext.SpanID().String() + "-01"
+	if got := readEnvFile(t, cmdEnv)[envTraceParent]; got != want {
+		t.Errorf("command %s = %q, want %q", envTraceParent, got, want)
+	}
+	hook := byName["hook on_success"]
+	if got := readEnvFile(t, hookEnv)[envTraceParent]; !strings.Contains(got, hook.SpanContext.SpanID().String()) {
+		t.Errorf("hook %s = %q, want the hook span %v", envTraceParent, got, hook.SpanContext.SpanID())
+	}
+}
+
+func TestJob_Run_Tracing_Outcomes(t *testing.T) {
+	tests := []struct {
+		name       string
+		command    string
+		lockHeld   bool
+		wantStatus codes.Code
+		outcome    string
+		skipReason string
+	}{
+		{"failure", "exit 3", false, codes.Error, outcomeFailure, ""},
+		{"lock held elsewhere", "true", true, codes.Unset, outcomeSkipped, skipReasonLockHeld},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			locker := lock.NewMockLocker()
+			locker.SetLockHeld("traced", tt.lockHeld)
+			job, exporter := newTracedJob(t, config.JobConfig{Name: "traced", Command: config.ShellCommand(tt.command)}, locker)
+			job.Run()
+
+			var root tracetest.SpanStub
+			for _, span := range exporter.GetSpans() {
+				if span.Name == "job.run" {
+					root = span
+				}
+			}
+			if root.Status.Code != tt.wantStatus {
+				t.Errorf("job.run status = %v, want %v", root.Status.Code, tt.wantStatus)
+			}
+			if got := spanAttr(root, attrOutcome).AsString(); got != tt.outcome {
+				t.Errorf("job.run %s = %q, want %q", attrOutcome, got, tt.outcome)
+			}
+			if got := spanAttr(root, attrSkipReason).AsString(); got != tt.skipReason {
+				t.Errorf("job.run %s = %q, want %q", attrSkipReason, got, tt.skipReason)
+			}
+		})
+	}
+}
+
+func TestWithTraceContext_NoSpan(t *testing.T) {
+	env := map[string]string{"A": "1"}
+	if got := withTraceContext(context.Background(), env); len(got) != 1 {
+		t.Errorf("withTraceContext() = %v, want env unchanged without a span", got)
+	}
+}
+
+func spanNames(spans tracetest.SpanStubs) []string {
+	names := make([]string, len(spans))
+	for i, span := range spans {
+		names[i] = span.Name
+	}
+	return names
+}
@@ -0,0 +1,360 @@
+package state
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MockStore is a test implementation of the Store interface.
+type MockStore struct {
+	mu sync.Mutex
+
+	// Configurable return values
+	RecordShardError error
+	RegisterError    error
+
+	// Call tracking
+	ShardCalls []ShardCall
+
+	// Simulated shard results keyed by job name and scheduled time, and
+	// incomplete runs' deadlines
+	shards     map[string]map[int]bool
+	shardsDone map[string]bool
+	shardRuns  map[string]map[time.Time]time.Time
+
+	// Simulated node registry, and registrations that have expired
+	nodes   map[string]NodeInfo
+	expired map[string]NodeInfo
+
+	// Simulated drain requests: node ID -> exit
+	drains map[string]bool
+
+	// Simulated pauses and history
+	pauses  map[string]Pause
+	history map[string][]RunRecord
+
+	// Simulated audit log, newest first
+	audit []AuditEntry
+
+	// Simulated alert state
+	alerts map[string]mockAlert
+
+	// Simulated last successes, first seen times and overdue alert claims
+	lastSuccess map[string]time.Time
+	firstSeen   map[string]time.Time
+	overdue     map[string]time.Time // claim expiry
+}
+
+// mockAlert is a job's simulated alert state.
+type mockAlert struct {
+	failures  int
+	alertedAt time.Time // zero if no alert has fired
+}
+
+// ShardCall records a RecordShardResult call.
+type ShardCall struct {
+	JobName     string
+	ScheduledAt time.Time
+	Index       int
+	Count       int
+	Success     bool
+}
+
+// NewMockStore creates a new MockStore.
+func NewMockStore() *MockStore {
+	return &MockStore{
+		shards:  make(map[string]map[int]bool),
+		nodes:   make(map[string]NodeInfo),
+		expired: make(map[string]NodeInfo),
+		drains:  make(map[string]bool),
+		pauses:  make(map[string]Pause),
+		history: make(map[string][]RunRecord),
+		alerts:  make(map[string]mockAlert),
+
+		shardsDone:  make(map[string]bool),
+		shardRuns:   make(map[string]map[time.Time]time.Time),
+		lastSuccess: make(map[string]time.Time),
+		firstSeen:   make(map[string]time.Time),
+		overdue:     make(map[string]time.Time),
+	}
+}
+
+// RecordShardResult implements Store.RecordShardResult.
+func (m *MockStore) RecordShardResult(ctx context.Context, jobName string, scheduledAt time.Time, index, count int, success bool, deadline time.Time) (ShardOutcome, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ShardCalls = append(m.ShardCalls, ShardCall{
+		JobName:     jobName,
+		ScheduledAt: scheduledAt,
+		Index:       index,
+		Count:       count,
+		Success:     success,
+	})
+
+	if m.RecordShardError != nil {
+		return ShardsPending, m.RecordShardError
+	}
+
+	key := shardRunKey(jobName, scheduledAt)
+	if m.shards[key] == nil {
+		m.shards[key] = make(map[int]bool)
+	}
+	if _, reported := m.shards[key][index]; reported || m.shardsDone[key] {
+		return ShardsPending, nil
+	}
+	m.shards[key][index] = success
+
+	if len(m.shards[key]) < count {
+		if m.shardRuns[jobName] == nil {
+			m.shardRuns[jobName] = make(map[time.Time]time.Time)
+		}
+		if _, ok := m.shardRuns[jobName][scheduledAt]; !ok {
+			m.shardRuns[jobName][scheduledAt] = deadline
+		}
+		return ShardsPending, nil
+	}
+	m.shardsDone[key] = true
+	delete(m.shardRuns[jobName], scheduledAt)
+	for _, ok := range m.shards[key] {
+		if !ok {
+			return ShardsFailed, nil
+		}
+	}
+	return ShardsSucceeded, nil
+}
+
+// ClaimStalledShardRuns implements Store.ClaimStalledShardRuns.
+func (m *MockStore) ClaimStalledShardRuns(ctx context.Context, jobName string, now time.Time) ([]StalledShardRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var runs []StalledShardRun
+	for scheduledAt, deadline := range m.shardRuns[jobName] {
+		if deadline.After(now) {
+			continue
+		}
+		delete(m.shardRuns[jobName], scheduledAt)
+		key := shardRunKey(jobName, scheduledAt)
+		m.shardsDone[key] = true
+		runs = append(runs, StalledShardRun{ScheduledAt: scheduledAt, Reported: len(m.shards[key])})
+	}
+	return runs, nil
+}
+
+// shardRunKey identifies a sharded run in the simulated shard results.
+func shardRunKey(jobName string, scheduledAt time.Time) string {
+	return jobName + "@" + scheduledAt.String()
+}
+
+// RegisterNode implements Store.RegisterNode.
+func (m *MockStore) RegisterNode(ctx context.Context, info NodeInfo, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.RegisterError != nil {
+		return m.RegisterError
+	}
+	m.nodes[info.ID] = info
+	delete(m.expired, info.ID)
+	return nil
+}
+
+// Nodes implements Store.Nodes.
+func (m *MockStore) Nodes(ctx context.Context) ([]NodeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nodes := make([]NodeInfo, 0, len(m.nodes))
+	for _, info := range m.nodes {
+		nodes = append(nodes, info)
+	}
+	return nodes, nil
+}
+
+// RecentNodes implements Store.RecentNodes. Every registered node is live;
+// use ExpireNode to simulate one that stopped heartbeating.
+func (m *MockStore) RecentNodes(ctx context.Context) ([]NodeRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	nodes := make([]NodeRecord, 0, len(m.nodes)+len(m.expired))
+	for _, info := range m.nodes {
+		nodes = append(nodes, NodeRecord{NodeInfo: info, Live: true})
+	}
+	for _, info := range m.expired {
+		nodes = append(nodes, NodeRecord{NodeInfo: info})
+	}
+	return nodes, nil
+}
+
+// ExpireNode simulates a node's registration expiring: it drops out of
+// Nodes but stays in RecentNodes as not live.
+func (m *MockStore) ExpireNode(nodeID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if info, ok := m.nodes[nodeID]; ok {
+		m.expired[nodeID] = info
+		delete(m.nodes, nodeID)
+	}
+}
+
+// SetDrain implements Store.SetDrain.
+func (m *MockStore) SetDrain(ctx context.Context, nodeID string, exit bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.drains[nodeID] = exit
+	return nil
+}
+
+// ClearDrain implements Store.ClearDrain.
+func (m *MockStore) ClearDrain(ctx context.Context, nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.drains, nodeID)
+	return nil
+}
+
+// DrainRequest implements Store.DrainRequest.
+func (m *MockStore) DrainRequest(ctx context.Context, nodeID string) (bool, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	exit, ok := m.drains[nodeID]
+	return ok, exit, nil
+}
+
+// SetPause implements Store.SetPause.
+func (m *MockStore) SetPause(ctx context.Context, jobName string, pause Pause) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.pauses[jobName] = pause
+	return nil
+}
+
+// ClearPause implements Store.ClearPause.
+func (m *MockStore) ClearPause(ctx context.Context, jobName string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.pauses, jobName)
+	return nil
+}
+
+// GetPause implements Store.GetPause.
+func (m *MockStore) GetPause(ctx context.Context, jobName string) (*Pause, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pause, ok := m.pauses[jobName]
+	if !ok || (!pause.Until.IsZero() && time.Now().After(pause.Until)) {
+		return nil, nil
+	}
+	return &pause, nil
+}
+
+// RecordRun implements Store.RecordRun.
+func (m *MockStore) RecordRun(ctx context.Context, rec RunRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.history[rec.Job] = append([]RunRecord{rec}, m.history[rec.Job]...)
+	if rec.Outcome == OutcomeSuccess {
+		m.lastSuccess[rec.Job] = rec.FinishedAt()
+		delete(m.overdue, rec.Job)
+	}
+	return nil
+}
+
+// LastSuccess implements Store.LastSuccess.
+func (m *MockStore) LastSuccess(ctx context.Context, jobName string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lastSuccess[jobName], nil
+}
+
+// FirstSeen implements Store.FirstSeen.
+func (m *MockStore) FirstSeen(ctx context.Context, jobName string, now time.Time) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if seen, ok := m.firstSeen[jobName]; ok {
+		return seen, nil
+	}
+	m.firstSeen[jobName] = now
+	return now, nil
+}
+
+// FirstSeenAt implements Store.FirstSeenAt.
+func (m *MockStore) FirstSeenAt(ctx context.Context, jobName string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.firstSeen[jobName], nil
+}
+
+// ClaimOverdueAlert implements Store.ClaimOverdueAlert.
+func (m *MockStore) ClaimOverdueAlert(ctx context.Context, jobName string, every time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if expiry, ok := m.overdue[jobName]; ok && time.Now().Before(expiry) {
+		return false, nil
+	}
+	m.overdue[jobName] = time.Now().Add(every)
+	return true, nil
+}
+
+// History implements Store.History.
+func (m *MockStore) History(ctx context.Context, jobName string, limit int) ([]RunRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	records := m.history[jobName]
+	if len(records) > limit {
+		records = records[:limit]
+	}
+	return append([]RunRecord(nil), records...), nil
+}
+
+// RecordAudit implements Store.RecordAudit.
+func (m *MockStore) RecordAudit(ctx context.Context, entry AuditEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.audit = append([]AuditEntry{entry}, m.audit...)
+	return nil
+}
+
+// AuditLog implements Store.AuditLog.
+func (m *MockStore) AuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entries := m.audit
+	if len(entries) > limit {
+		entries = entries[:limit]
+	}
+	return append([]AuditEntry(nil), entries...), nil
+}
+
+// RecordAlertOutcome implements Store.RecordAlertOutcome.
+func (m *MockStore) RecordAlertOutcome(ctx context.Context, jobName string, success bool, threshold int, renotify time.Duration, now time.Time) (AlertState, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a := m.alerts[jobName]
+	if success {
+		delete(m.alerts, jobName)
+		if !a.alertedAt.IsZero() {
+			return AlertState{Transition: AlertRecovered, Failures: a.failures}, nil
+		}
+		return AlertState{}, nil
+	}
+
+	a.failures++
+	state := AlertState{Failures: a.failures}
+	switch {
+	case a.failures < threshold:
+	case a.alertedAt.IsZero():
+		a.alertedAt = now
+		state.Transition = AlertFiring
+	case renotify > 0 && now.Sub(a.alertedAt) >= renotify:
+		a.alertedAt = now
+		state.Transition = AlertRenotify
+	}
+	m.alerts[jobName] = a
+	return state, nil
+}
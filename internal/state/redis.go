@@ -0,0 +1,572 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// nodeRetention is how long a node stays in the registry index after its
+// last heartbeat.
+const nodeRetention = 24 * time.Hour
+
+// historyLength is how many runs are kept per job.
+const historyLength = 100
+
+// auditLength is how many audit entries are kept.
+const auditLength = 1000
+
+// historyDedupeTTL is how long a run's dedupe marker is kept.
+const historyDedupeTTL = time.Hour
+
+// shardResultTTL bounds how long shard results are kept. Runs whose shards
+// never all report (e.g. no node had capacity for one) simply expire.
+const shardResultTTL = 24 * time.Hour
+
+// shardCompleteField marks a sharded run's results as complete, either
+// because every shard reported or because the run stalled.
+const shardCompleteField = "complete"
+
+// Lua script for atomic shard reporting: record this shard's status unless
+// it or the whole run was already recorded and, if this report completes
+// the run, mark it complete and return the aggregate outcome. Incomplete
+// runs are tracked in a sorted set scored by their deadline.
+// Returns 0 while pending, 1 when all succeeded, 2 when any failed.
+var shardResultScript = redis.NewScript(`
+if redis.call("hexists", KEYS[1], ARGV[5]) == 1 then
+	return 0
+end
+if redis.call("hsetnx", KEYS[1], ARGV[1], ARGV[2]) == 0 then
+	return 0
+end
+redis.call("pexpire", KEYS[1], ARGV[4])
+if redis.call("hlen", KEYS[1]) < tonumber(ARGV[3]) then
+	redis.call("zadd", KEYS[2], "NX", ARGV[7], ARGV[6])
+	redis.call("pexpire", KEYS[2], ARGV[4])
+	return 0
+end
+redis.call("hset", KEYS[1], ARGV[5], "1")
+redis.call("zrem", KEYS[2], ARGV[6])
+for _, v in ipairs(redis.call("hvals", KEYS[1])) do
+	if v == "failed" then
+		return 2
+	end
+end
+return 1
+`)
+
+// Lua script for deduplicated history: push the record only if this is the
+// first record for its dedupe key, then trim the list to its maximum length.
+var recordRunScript = redis.NewScript(`
+if redis.call("set", KEYS[2], "1", "NX", "PX", ARGV[3]) then
+	redis.call("lpush", KEYS[1], ARGV[1])
+	redis.call("ltrim", KEYS[1], 0, tonumber(ARGV[2]) - 1)
+	return 1
+end
+return 0
+`)
+
+// Lua script for alert state: count consecutive failures and return the
+// transition and failure count. A success clears the state, returning 3
+// (recovered) if an alert had fired. A failure returns 1 (firing) when the
+// count reaches the threshold and 2 (renotify) once the renotify interval
+// has passed since the last alert, or 0 otherwise.
+var alertOutcomeScript = redis.NewScript(`
+local alerted = redis.call("hget", KEYS[1], "alerted_at")
+if ARGV[1] == "1" then
+	local failures = tonumber(redis.call("hget", KEYS[1], "failures") or "0")
+	redis.call("del", KEYS[1])
+	if alerted then
+		return {3, failures}
+	end
+	return {0, 0}
+end
+local failures = redis.call("hincrby", KEYS[1], "failures", 1)
+if failures < tonumber(ARGV[2]) then
+	return {0, failures}
+end
+if not alerted then
+	redis.call("hset", KEYS[1], "alerted_at", ARGV[4])
+	return {1, failures}
+end
+local renotify = tonumber(ARGV[3])
+if renotify > 0 and tonumber(ARGV[4]) - tonumber(alerted) >= renotify then
+	redis.call("hset", KEYS[1], "alerted_at", ARGV[4])
+	return {2, failures}
+end
+return {0, failures}
+`)
+
+// RedisStore implements Store using Redis.
+type RedisStore struct {
+	client    *redis.Client
+	keyPrefix string
+}
+
+// NewRedisStore creates a new Redis-based state store.
+func NewRedisStore(client *redis.Client, keyPrefix string) *RedisStore {
+	return &RedisStore{
+		client:    client,
+		keyPrefix: keyPrefix,
+	}
+}
+
+// shardsKey returns the Redis key holding shard results for one run.
+func (r *RedisStore) shardsKey(jobName string, scheduledAt time.Time) string {
+	return fmt.Sprintf("%sshards:%s:%d", r.keyPrefix, jobName, scheduledAt.Unix())
+}
+
+// shardRunsKey returns the Redis key of a job's incomplete sharded runs, a
+// sorted set of scheduled times (Unix seconds) scored by deadline (Unix
+// milliseconds).
+func (r *RedisStore) shardRunsKey(jobName string) string {
+	return fmt.Sprintf("%sshardruns:%s", r.keyPrefix, jobName)
+}
+
+// RecordShardResult records a shard's status using a Lua script for atomicity.
+func (r *RedisStore) RecordShardResult(ctx context.Context, jobName string, scheduledAt time.Time, index, count int, success bool, deadline time.Time) (ShardOutcome, error) {
+	status := "ok"
+	if !success {
+		status = "failed"
+	}
+
+	// Keep the results until well after the deadline, so a stalled run's
+	// results are still there when it is claimed
+	ttl := max(shardResultTTL, time.Until(deadline)+shardResultTTL)
+
+	keys := []string{r.shardsKey(jobName, scheduledAt), r.shardRunsKey(jobName)}
+	result, err := shardResultScript.Run(ctx, r.client, keys,
+		strconv.Itoa(index), status, count, ttl.Milliseconds(),
+		shardCompleteField, scheduledAt.Unix(), deadline.UnixMilli(),
+	).Int64()
+	if err != nil {
+		return ShardsPending, fmt.Errorf("failed to record shard result: %w", err)
+	}
+
+	return ShardOutcome(result), nil
+}
+
+// ClaimStalledShardRuns claims each overdue run by removing it from the
+// job's incomplete runs, then marks it complete unless a shard report
+// completed it in the meantime.
+func (r *RedisStore) ClaimStalledShardRuns(ctx context.Context, jobName string, now time.Time) ([]StalledShardRun, error) {
+	key := r.shardRunsKey(jobName)
+	members, err := r.client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min: "-inf",
+		Max: strconv.FormatInt(now.UnixMilli(), 10),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stalled shard runs: %w", err)
+	}
+
+	var runs []StalledShardRun
+	for _, member := range members {
+		unix, err := strconv.ParseInt(member, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shard run %q", member)
+		}
+		claimed, err := r.client.ZRem(ctx, key, member).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim stalled shard run: %w", err)
+		}
+		if claimed == 0 {
+			continue // another node claimed it
+		}
+
+		scheduledAt := time.Unix(unix, 0)
+		results := r.shardsKey(jobName, scheduledAt)
+		marked, err := r.client.HSetNX(ctx, results, shardCompleteField, "1").Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim stalled shard run: %w", err)
+		}
+		if !marked {
+			continue // completed since it was read
+		}
+		// The results may have expired, leaving only the marker
+		pipe := r.client.TxPipeline()
+		reported := pipe.HLen(ctx, results)
+		pipe.Expire(ctx, results, shardResultTTL)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read shard results: %w", err)
+		}
+		runs = append(runs, StalledShardRun{ScheduledAt: scheduledAt, Reported: int(reported.Val()) - 1})
+	}
+	return runs, nil
+}
+
+// nodeKey returns the Redis key holding a node's registration.
+func (r *RedisStore) nodeKey(nodeID string) string {
+	return fmt.Sprintf("%snode:%s", r.keyPrefix, nodeID)
+}
+
+// nodeLastKey returns the Redis key holding a node's last registration,
+// kept for the registry's retention after the registration expires.
+func (r *RedisStore) nodeLastKey(nodeID string) string {
+	return fmt.Sprintf("%snode:%s:last", r.keyPrefix, nodeID)
+}
+
+// nodesKey returns the Redis key of the registry index, a sorted set of
+// node IDs scored by last heartbeat time.
+func (r *RedisStore) nodesKey() string {
+	return r.keyPrefix + "nodes"
+}
+
+// RegisterNode stores the node's registration with a TTL and updates the index.
+func (r *RedisStore) RegisterNode(ctx context.Context, info NodeInfo, ttl time.Duration) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode node info: %w", err)
+	}
+
+	now := time.Now()
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, r.nodeKey(info.ID), data, ttl)
+	pipe.Set(ctx, r.nodeLastKey(info.ID), data, nodeRetention)
+	pipe.ZAdd(ctx, r.nodesKey(), redis.Z{Score: float64(now.Unix()), Member: info.ID})
+	pipe.ZRemRangeByScore(ctx, r.nodesKey(), "-inf", strconv.FormatInt(now.Add(-nodeRetention).Unix(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to register node: %w", err)
+	}
+	return nil
+}
+
+// Nodes returns every node whose registration has not expired.
+func (r *RedisStore) Nodes(ctx context.Context) ([]NodeInfo, error) {
+	ids, err := r.client.ZRange(ctx, r.nodesKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	var nodes []NodeInfo
+	for _, id := range ids {
+		data, err := r.client.Get(ctx, r.nodeKey(id)).Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node %s: %w", id, err)
+		}
+		var info NodeInfo
+		if err := json.Unmarshal(data, &info); err != nil {
+			return nil, fmt.Errorf("failed to decode node %s: %w", id, err)
+		}
+		nodes = append(nodes, info)
+	}
+	return nodes, nil
+}
+
+// RecentNodes returns the last registration of every node in the registry
+// index, newest heartbeat first.
+func (r *RedisStore) RecentNodes(ctx context.Context) ([]NodeRecord, error) {
+	ids, err := r.client.ZRevRange(ctx, r.nodesKey(), 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	live := make([]*redis.IntCmd, len(ids))
+	last := make([]*redis.StringCmd, len(ids))
+	for i, id := range ids {
+		live[i] = pipe.Exists(ctx, r.nodeKey(id))
+		last[i] = pipe.Get(ctx, r.nodeLastKey(id))
+	}
+	if _, err := pipe.Exec(ctx); err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to read nodes: %w", err)
+	}
+
+	var nodes []NodeRecord
+	for i, id := range ids {
+		data, err := last[i].Bytes()
+		if errors.Is(err, redis.Nil) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read node %s: %w", id, err)
+		}
+		var rec NodeRecord
+		if err := json.Unmarshal(data, &rec.NodeInfo); err != nil {
+			return nil, fmt.Errorf("failed to decode node %s: %w", id, err)
+		}
+		rec.Live = live[i].Val() == 1
+		nodes = append(nodes, rec)
+	}
+	return nodes, nil
+}
+
+// drainKey returns the Redis key holding a node's drain request.
+func (r *RedisStore) drainKey(nodeID string) string {
+	return fmt.Sprintf("%sdrain:%s", r.keyPrefix, nodeID)
+}
+
+// SetDrain stores a drain request for the node.
+func (r *RedisStore) SetDrain(ctx context.Context, nodeID string, exit bool) error {
+	value := "drain"
+	if exit {
+		value = "exit"
+	}
+	if err := r.client.Set(ctx, r.drainKey(nodeID), value, 0).Err(); err != nil {
+		return fmt.Errorf("failed to set drain request: %w", err)
+	}
+	return nil
+}
+
+// ClearDrain deletes the node's drain request.
+func (r *RedisStore) ClearDrain(ctx context.Context, nodeID string) error {
+	if err := r.client.Del(ctx, r.drainKey(nodeID)).Err(); err != nil {
+		return fmt.Errorf("failed to clear drain request: %w", err)
+	}
+	return nil
+}
+
+// DrainRequest reads the node's drain request.
+func (r *RedisStore) DrainRequest(ctx context.Context, nodeID string) (bool, bool, error) {
+	value, err := r.client.Get(ctx, r.drainKey(nodeID)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to read drain request: %w", err)
+	}
+	return true, value == "exit", nil
+}
+
+// pauseKey returns the Redis key holding a job's pause record.
+func (r *RedisStore) pauseKey(jobName string) string {
+	return fmt.Sprintf("%spause:%s", r.keyPrefix, jobName)
+}
+
+// SetPause stores the pause record, expiring it at pause.Until if set.
+func (r *RedisStore) SetPause(ctx context.Context, jobName string, pause Pause) error {
+	data, err := json.Marshal(pause)
+	if err != nil {
+		return fmt.Errorf("failed to encode pause: %w", err)
+	}
+
+	var ttl time.Duration
+	if !pause.Until.IsZero() {
+		ttl = time.Until(pause.Until)
+		if ttl <= 0 {
+			return fmt.Errorf("pause end %s is in the past", pause.Until.Format(time.RFC3339))
+		}
+	}
+
+	if err := r.client.Set(ctx, r.pauseKey(jobName), data, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to set pause: %w", err)
+	}
+	return nil
+}
+
+// ClearPause deletes the pause record.
+func (r *RedisStore) ClearPause(ctx context.Context, jobName string) error {
+	if err := r.client.Del(ctx, r.pauseKey(jobName)).Err(); err != nil {
+		return fmt.Errorf("failed to clear pause: %w", err)
+	}
+	return nil
+}
+
+// GetPause reads the pause record.
+func (r *RedisStore) GetPause(ctx context.Context, jobName string) (*Pause, error) {
+	data, err := r.client.Get(ctx, r.pauseKey(jobName)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pause: %w", err)
+	}
+
+	var pause Pause
+	if err := json.Unmarshal(data, &pause); err != nil {
+		return nil, fmt.Errorf("failed to decode pause: %w", err)
+	}
+	return &pause, nil
+}
+
+// historyKey returns the Redis key holding a job's run history, newest first.
+func (r *RedisStore) historyKey(jobName string) string {
+	return fmt.Sprintf("%shistory:%s", r.keyPrefix, jobName)
+}
+
+// RecordRun pushes the record onto the job's history list.
+func (r *RedisStore) RecordRun(ctx context.Context, rec RunRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("failed to encode run record: %w", err)
+	}
+
+	dedupeKey := fmt.Sprintf("%s:%d:%s", r.historyKey(rec.Job), rec.ScheduledAt.Unix(), rec.Outcome)
+	err = recordRunScript.Run(ctx, r.client, []string{r.historyKey(rec.Job), dedupeKey},
+		data, historyLength, historyDedupeTTL.Milliseconds(),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to record run: %w", err)
+	}
+
+	if rec.Outcome == OutcomeSuccess {
+		pipe := r.client.TxPipeline()
+		pipe.Set(ctx, r.lastSuccessKey(rec.Job), rec.FinishedAt().UnixMilli(), 0)
+		pipe.Del(ctx, r.overdueKey(rec.Job))
+		if _, err := pipe.Exec(ctx); err != nil {
+			return fmt.Errorf("failed to record last success: %w", err)
+		}
+	}
+	return nil
+}
+
+// lastSuccessKey returns the Redis key holding when a job last succeeded,
+// in Unix milliseconds.
+func (r *RedisStore) lastSuccessKey(jobName string) string {
+	return fmt.Sprintf("%slast_success:%s", r.keyPrefix, jobName)
+}
+
+// firstSeenKey returns the Redis key holding when a job was first
+// monitored, in Unix milliseconds.
+func (r *RedisStore) firstSeenKey(jobName string) string {
+	return fmt.Sprintf("%sfirst_seen:%s", r.keyPrefix, jobName)
+}
+
+// overdueKey returns the Redis key claimed by the node alerting that a job
+// is overdue.
+func (r *RedisStore) overdueKey(jobName string) string {
+	return fmt.Sprintf("%soverdue:%s", r.keyPrefix, jobName)
+}
+
+// LastSuccess reads the job's last success time.
+func (r *RedisStore) LastSuccess(ctx context.Context, jobName string) (time.Time, error) {
+	ms, err := r.client.Get(ctx, r.lastSuccessKey(jobName)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read last success: %w", err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// FirstSeen records now with SETNX, so the earliest node to monitor the
+// job sets the time, and reads back the recorded time.
+func (r *RedisStore) FirstSeen(ctx context.Context, jobName string, now time.Time) (time.Time, error) {
+	key := r.firstSeenKey(jobName)
+	set, err := r.client.SetNX(ctx, key, now.UnixMilli(), 0).Result()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to record first seen: %w", err)
+	}
+	if set {
+		return time.UnixMilli(now.UnixMilli()), nil
+	}
+	ms, err := r.client.Get(ctx, key).Int64()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read first seen: %w", err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// FirstSeenAt reads when the job was first monitored.
+func (r *RedisStore) FirstSeenAt(ctx context.Context, jobName string) (time.Time, error) {
+	ms, err := r.client.Get(ctx, r.firstSeenKey(jobName)).Int64()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to read first seen: %w", err)
+	}
+	return time.UnixMilli(ms), nil
+}
+
+// ClaimOverdueAlert claims the job's overdue alert with SET NX PX, so the
+// claim lapses after every.
+func (r *RedisStore) ClaimOverdueAlert(ctx context.Context, jobName string, every time.Duration) (bool, error) {
+	ok, err := r.client.SetNX(ctx, r.overdueKey(jobName), time.Now().UnixMilli(), every).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to claim overdue alert: %w", err)
+	}
+	return ok, nil
+}
+
+// History reads the most recent runs from the job's history list.
+func (r *RedisStore) History(ctx context.Context, jobName string, limit int) ([]RunRecord, error) {
+	items, err := r.client.LRange(ctx, r.historyKey(jobName), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history: %w", err)
+	}
+
+	records := make([]RunRecord, 0, len(items))
+	for _, item := range items {
+		var rec RunRecord
+		if err := json.Unmarshal([]byte(item), &rec); err != nil {
+			return nil, fmt.Errorf("failed to decode run record: %w", err)
+		}
+		records = append(records, rec)
+	}
+	return records, nil
+}
+
+// alertKey returns the Redis key holding a job's alert state.
+func (r *RedisStore) alertKey(jobName string) string {
+	return fmt.Sprintf("%salert:%s", r.keyPrefix, jobName)
+}
+
+// RecordAlertOutcome updates the job's alert state using a Lua script for
+// atomicity, since runs on different nodes report into the same state.
+func (r *RedisStore) RecordAlertOutcome(ctx context.Context, jobName string, success bool, threshold int, renotify time.Duration, now time.Time) (AlertState, error) {
+	ok := "0"
+	if success {
+		ok = "1"
+	}
+	result, err := alertOutcomeScript.Run(ctx, r.client, []string{r.alertKey(jobName)},
+		ok, threshold, renotify.Milliseconds(), now.UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return AlertState{}, fmt.Errorf("failed to record alert outcome: %w", err)
+	}
+	if len(result) != 2 {
+		return AlertState{}, fmt.Errorf("failed to record alert outcome: unexpected result %v", result)
+	}
+	return AlertState{Transition: AlertTransition(result[0]), Failures: int(result[1])}, nil
+}
+
+// auditKey returns the Redis key holding the audit log, newest first.
+func (r *RedisStore) auditKey() string {
+	return r.keyPrefix + "audit"
+}
+
+// RecordAudit pushes the entry onto the audit log, trimming old entries.
+func (r *RedisStore) RecordAudit(ctx context.Context, entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+
+	pipe := r.client.TxPipeline()
+	pipe.LPush(ctx, r.auditKey(), data)
+	pipe.LTrim(ctx, r.auditKey(), 0, auditLength-1)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// AuditLog reads the most recent entries from the audit log.
+func (r *RedisStore) AuditLog(ctx context.Context, limit int) ([]AuditEntry, error) {
+	items, err := r.client.LRange(ctx, r.auditKey(), 0, int64(limit)-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	entries := make([]AuditEntry, 0, len(items))
+	for _, item := range items {
+		var entry AuditEntry
+		if err := json.Unmarshal([]byte(item), &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,551 @@
+package state
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func setupMiniredis(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start miniredis: %v", err)
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr: s.Addr(),
+	})
+
+	t.Cleanup(func() {
+		client.Close()
+		s.Close()
+	})
+
+	return s, client
+}
+
+func TestRedisStore_RecordShardResult_AllSucceed(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	tick := time.Unix(1700000000, 0)
+
+	for i := 0; i < 2; i++ {
+		outcome, err := store.RecordShardResult(ctx, "reindex", tick, i, 3, true, tick.Add(time.Hour))
+		if err != nil {
+			t.Fatalf("RecordShardResult() error = %v", err)
+		}
+		if outcome != ShardsPending {
+			t.Errorf("RecordShardResult() shard %d = %v, want %v", i, outcome, ShardsPending)
+		}
+	}
+
+	outcome, err := store.RecordShardResult(ctx, "reindex", tick, 2, 3, true, tick.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	if outcome != ShardsSucceeded {
+		t.Errorf("RecordShardResult() last shard = %v, want %v", outcome, ShardsSucceeded)
+	}
+}
+
+func TestRedisStore_RecordShardResult_AnyFailure(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	tick := time.Unix(1700000000, 0)
+
+	if _, err := store.RecordShardResult(ctx, "reindex", tick, 1, 2, false, tick.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	outcome, err := store.RecordShardResult(ctx, "reindex", tick, 0, 2, true, tick.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	if outcome != ShardsFailed {
+		t.Errorf("RecordShardResult() = %v, want %v", outcome, ShardsFailed)
+	}
+}
+
+func TestRedisStore_RecordShardResult_SeparateRuns(t *testing.T) {
+	s, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	first := time.Unix(1700000000, 0)
+	second := first.Add(time.Minute)
+
+	if _, err := store.RecordShardResult(ctx, "reindex", first, 0, 2, true, first.Add(time.Hour)); err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	outcome, err := store.RecordShardResult(ctx, "reindex", second, 1, 2, true, second.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	if outcome != ShardsPending {
+		t.Errorf("RecordShardResult() = %v, want %v (different runs must not mix)", outcome, ShardsPending)
+	}
+
+	// Results expire so abandoned runs don't accumulate
+	key := store.shardsKey("reindex", first)
+	if ttl := s.TTL(key); ttl <= 0 {
+		t.Errorf("TTL(%s) = %v, want > 0", key, ttl)
+	}
+}
+
+func TestRedisStore_RecordShardResult_CompletesOnce(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	tick := time.Unix(1700000000, 0)
+	deadline := tick.Add(time.Hour)
+
+	if _, err := store.RecordShardResult(ctx, "reindex", tick, 0, 2, true, deadline); err != nil {
+		t.Fatalf("RecordShardResult() error = %v", err)
+	}
+	// A repeated report of a pending shard doesn't count twice
+	if outcome, _ := store.RecordShardResult(ctx, "reindex", tick, 0, 2, false, deadline); outcome != ShardsPending {
+		t.Errorf("RecordShardResult() repeated shard = %v, want %v", outcome, ShardsPending)
+	}
+	if outcome, _ := store.RecordShardResult(ctx, "reindex", tick, 1, 2, true, deadline); outcome != ShardsSucceeded {
+		t.Errorf("RecordShardResult() last shard = %v, want %v", outcome, ShardsSucceeded)
+	}
+
+	// A late node running a shard again must not complete the run again
+	for i := range 2 {
+		if outcome, _ := store.RecordShardResult(ctx, "reindex", tick, i, 2, true, deadline); outcome != ShardsPending {
+			t.Errorf("RecordShardResult() after completion = %v, want %v", outcome, ShardsPending)
+		}
+	}
+
+	runs, err := store.ClaimStalledShardRuns(ctx, "reindex", deadline.Add(time.Minute))
+	if err != nil || len(runs) != 0 {
+		t.Errorf("ClaimStalledShardRuns() = %v, %v, want no runs", runs, err)
+	}
+}
+
+func TestRedisStore_ClaimStalledShardRuns(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	tick := time.Unix(1700000000, 0)
+	deadline := tick.Add(time.Hour)
+
+	for i := range 2 {
+		if _, err := store.RecordShardResult(ctx, "reindex", tick, i, 3, true, deadline); err != nil {
+			t.Fatalf("RecordShardResult() error = %v", err)
+		}
+	}
+
+	runs, err := store.ClaimStalledShardRuns(ctx, "reindex", deadline.Add(-time.Second))
+	if err != nil || len(runs) != 0 {
+		t.Fatalf("ClaimStalledShardRuns() before the deadline = %v, %v, want no runs", runs, err)
+	}
+
+	runs, err = store.ClaimStalledShardRuns(ctx, "reindex", deadline)
+	if err != nil {
+		t.Fatalf("ClaimStalledShardRuns() error = %v", err)
+	}
+	if len(runs) != 1 || !runs[0].ScheduledAt.Equal(tick) || runs[0].Reported != 2 {
+		t.Fatalf("ClaimStalledShardRuns() = %+v, want the run with 2 shards reported", runs)
+	}
+
+	// Claimed once, and the missing shard's late report doesn't complete it
+	if runs, _ := store.ClaimStalledShardRuns(ctx, "reindex", deadline); len(runs) != 0 {
+		t.Errorf("ClaimStalledShardRuns() again = %+v, want no runs", runs)
+	}
+	if outcome, _ := store.RecordShardResult(ctx, "reindex", tick, 2, 3, true, deadline); outcome != ShardsPending {
+		t.Errorf("RecordShardResult() after claim = %v, want %v", outcome, ShardsPending)
+	}
+}
+
+func TestRedisStore_RegisterNode(t *testing.T) {
+	s, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	ctx := context.Background()
+	info := NodeInfo{ID: "node-1", Labels: map[string]string{"zone": "a"}}
+
+	if err := store.RegisterNode(ctx, info, 30*time.Second); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+	if err := store.RegisterNode(ctx, NodeInfo{ID: "node-2"}, 5*time.Second); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+
+	nodes, err := store.Nodes(ctx)
+	if err != nil {
+		t.Fatalf("Nodes() error = %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("Nodes() returned %d nodes, want 2", len(nodes))
+	}
+
+	// node-2 stops heartbeating and its registration expires
+	s.FastForward(10 * time.Second)
+
+	nodes, err = store.Nodes(ctx)
+	if err != nil {
+		t.Fatalf("Nodes() error = %v", err)
+	}
+	if len(nodes) != 1 || nodes[0].ID != "node-1" {
+		t.Fatalf("Nodes() = %v, want only node-1", nodes)
+	}
+	if nodes[0].Labels["zone"] != "a" {
+		t.Errorf("Labels = %v, want zone=a", nodes[0].Labels)
+	}
+}
+
+func TestRedisStore_RecentNodes(t *testing.T) {
+	s, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	started := time.Unix(1700000000, 0).UTC()
+	info := NodeInfo{ID: "node-1", Hostname: "host-1", Version: "v1.2.3", ConfigHash: "abc", StartedAt: started, Jobs: []string{"backup"}}
+	if err := store.RegisterNode(ctx, info, 30*time.Second); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+	if err := store.RegisterNode(ctx, NodeInfo{ID: "node-2"}, 5*time.Second); err != nil {
+		t.Fatalf("RegisterNode() error = %v", err)
+	}
+
+	// node-2 stops heartbeating; its last registration is kept
+	s.FastForward(10 * time.Second)
+
+	nodes, err := store.RecentNodes(ctx)
+	if err != nil {
+		t.Fatalf("RecentNodes() error = %v", err)
+	}
+	if len(nodes) != 2 {
+		t.Fatalf("RecentNodes() returned %d nodes, want 2", len(nodes))
+	}
+	live := make(map[string]NodeRecord)
+	for _, n := range nodes {
+		live[n.ID] = n
+	}
+	if !live["node-1"].Live || live["node-2"].Live {
+		t.Errorf("Live = %v, %v, want node-1 live and node-2 not", live["node-1"].Live, live["node-2"].Live)
+	}
+	n := live["node-1"]
+	if n.Hostname != "host-1" || n.Version != "v1.2.3" || n.ConfigHash != "abc" || !n.StartedAt.Equal(started) || len(n.Jobs) != 1 {
+		t.Errorf("node-1 = %+v, want its registration", n)
+	}
+
+	// Nodes drop out entirely after the registry's retention
+	s.FastForward(nodeRetention)
+	nodes, err = store.RecentNodes(ctx)
+	if err != nil {
+		t.Fatalf("RecentNodes() error = %v", err)
+	}
+	if len(nodes) != 0 {
+		t.Errorf("RecentNodes() = %+v, want none after the retention", nodes)
+	}
+}
+
+func TestRedisStore_Pause(t *testing.T) {
+	s, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	pause, err := store.GetPause(ctx, "backup")
+	if err != nil {
+		t.Fatalf("GetPause() error = %v", err)
+	}
+	if pause != nil {
+		t.Fatalf("GetPause() = %+v, want nil before pausing", pause)
+	}
+
+	err = store.SetPause(ctx, "backup", Pause{
+		Reason: "storage migration",
+		Until:  time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("SetPause() error = %v", err)
+	}
+
+	pause, err = store.GetPause(ctx, "backup")
+	if err != nil {
+		t.Fatalf("GetPause() error = %v", err)
+	}
+	if pause == nil || pause.Reason != "storage migration" {
+		t.Fatalf("GetPause() = %+v, want reason %q", pause, "storage migration")
+	}
+
+	// The pause expires on its own at Until
+	s.FastForward(time.Hour + time.Second)
+	if pause, _ := store.GetPause(ctx, "backup"); pause != nil {
+		t.Errorf("GetPause() = %+v after Until, want nil", pause)
+	}
+
+	// Indefinite pauses last until cleared
+	if err := store.SetPause(ctx, "backup", Pause{}); err != nil {
+		t.Fatalf("SetPause() error = %v", err)
+	}
+	s.FastForward(24 * time.Hour)
+	if pause, _ := store.GetPause(ctx, "backup"); pause == nil {
+		t.Error("GetPause() = nil for indefinite pause, want pause")
+	}
+	if err := store.ClearPause(ctx, "backup"); err != nil {
+		t.Fatalf("ClearPause() error = %v", err)
+	}
+	if pause, _ := store.GetPause(ctx, "backup"); pause != nil {
+		t.Errorf("GetPause() = %+v after ClearPause(), want nil", pause)
+	}
+}
+
+func TestRedisStore_SetPause_PastUntil(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+
+	err := store.SetPause(context.Background(), "backup", Pause{Until: time.Now().Add(-time.Minute)})
+	if err == nil {
+		t.Error("SetPause() error = nil for Until in the past, want error")
+	}
+}
+
+func TestRedisStore_History(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	base := time.Unix(1700000000, 0)
+	for i := 0; i < historyLength+5; i++ {
+		rec := RunRecord{
+			Job:         "backup",
+			NodeID:      "node-1",
+			Outcome:     OutcomeSuccess,
+			ScheduledAt: base.Add(time.Duration(i) * time.Minute),
+			Duration:    time.Duration(i) * time.Second,
+		}
+		if err := store.RecordRun(ctx, rec); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	records, err := store.History(ctx, "backup", 20)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(records) != 20 {
+		t.Fatalf("History() returned %d records, want 20", len(records))
+	}
+	if records[0].Duration != time.Duration(historyLength+4)*time.Second {
+		t.Errorf("History()[0].Duration = %v, want newest record first", records[0].Duration)
+	}
+
+	all, _ := store.History(ctx, "backup", 1000)
+	if len(all) != historyLength {
+		t.Errorf("History() kept %d records, want %d", len(all), historyLength)
+	}
+}
+
+func TestRedisStore_RecordRun_Dedupes(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	tick := time.Unix(1700000000, 0)
+
+	// Every node records the same paused tick
+	for _, node := range []string{"node-1", "node-2", "node-3"} {
+		rec := RunRecord{Job: "backup", NodeID: node, Outcome: OutcomePaused, ScheduledAt: tick}
+		if err := store.RecordRun(ctx, rec); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	records, err := store.History(ctx, "backup", 10)
+	if err != nil {
+		t.Fatalf("History() error = %v", err)
+	}
+	if len(records) != 1 {
+		t.Errorf("History() returned %d records, want 1", len(records))
+	}
+}
+
+func TestRedisStore_AuditLog(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	for _, target := range []string{"backup", "report"} {
+		entry := AuditEntry{
+			Time:     time.Unix(1700000000, 0),
+			Operator: "ops@admin-1",
+			Action:   "lock.force_release",
+			Target:   target,
+		}
+		if err := store.RecordAudit(ctx, entry); err != nil {
+			t.Fatalf("RecordAudit() error = %v", err)
+		}
+	}
+
+	entries, err := store.AuditLog(ctx, 10)
+	if err != nil {
+		t.Fatalf("AuditLog() error = %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("AuditLog() returned %d entries, want 2", len(entries))
+	}
+	if entries[0].Target != "report" || entries[0].Operator != "ops@admin-1" {
+		t.Errorf("AuditLog()[0] = %+v, want newest entry (report) first", entries[0])
+	}
+}
+
+func TestRedisStore_RecordAlertOutcome(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+	start := time.Unix(1700000000, 0)
+
+	steps := []struct {
+		success  bool
+		after    time.Duration
+		want     AlertTransition
+		failures int
+	}{
+		{false, 0, AlertNone, 1},
+		{false, 5 * time.Minute, AlertNone, 2},
+		{false, 10 * time.Minute, AlertFiring, 3},
+		{false, 15 * time.Minute, AlertNone, 4},
+		{false, 70 * time.Minute, AlertRenotify, 5},
+		{false, 75 * time.Minute, AlertNone, 6},
+		{true, 80 * time.Minute, AlertRecovered, 6},
+		{true, 85 * time.Minute, AlertNone, 0},
+		// The streak starts over after a success
+		{false, 90 * time.Minute, AlertNone, 1},
+		{true, 95 * time.Minute, AlertNone, 0},
+	}
+
+	for i, step := range steps {
+		got, err := store.RecordAlertOutcome(ctx, "health", step.success, 3, time.Hour, start.Add(step.after))
+		if err != nil {
+			t.Fatalf("step %d: RecordAlertOutcome() error = %v", i, err)
+		}
+		if got.Transition != step.want || got.Failures != step.failures {
+			t.Errorf("step %d: RecordAlertOutcome() = %v with %d failures, want %v with %d", i, got.Transition, got.Failures, step.want, step.failures)
+		}
+	}
+}
+
+func TestRedisStore_RecordAlertOutcome_NoRenotify(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+
+	if got, _ := store.RecordAlertOutcome(ctx, "health", false, 1, 0, now); got.Transition != AlertFiring {
+		t.Fatalf("RecordAlertOutcome() = %v, want %v", got.Transition, AlertFiring)
+	}
+	if got, _ := store.RecordAlertOutcome(ctx, "health", false, 1, 0, now.Add(24*time.Hour)); got.Transition != AlertNone {
+		t.Errorf("RecordAlertOutcome() = %v, want %v without renotify", got.Transition, AlertNone)
+	}
+	// Other jobs have their own state
+	if got, _ := store.RecordAlertOutcome(ctx, "other", true, 1, 0, now); got.Transition != AlertNone {
+		t.Errorf("RecordAlertOutcome() for another job = %v, want %v", got.Transition, AlertNone)
+	}
+}
+
+func TestRedisStore_LastSuccess(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	last, err := store.LastSuccess(ctx, "backup")
+	if err != nil {
+		t.Fatalf("LastSuccess() error = %v", err)
+	}
+	if !last.IsZero() {
+		t.Errorf("LastSuccess() = %v, want zero before any success", last)
+	}
+
+	started := time.Unix(1700000000, 0)
+	records := []RunRecord{
+		{Job: "backup", Outcome: OutcomeSuccess, ScheduledAt: started, StartedAt: started, Duration: time.Minute},
+		// Failures and pauses don't move the last success
+		{Job: "backup", Outcome: OutcomeFailure, ScheduledAt: started.Add(time.Hour), StartedAt: started.Add(time.Hour)},
+		{Job: "backup", Outcome: OutcomePaused, ScheduledAt: started.Add(2 * time.Hour)},
+	}
+	for _, rec := range records {
+		if err := store.RecordRun(ctx, rec); err != nil {
+			t.Fatalf("RecordRun() error = %v", err)
+		}
+	}
+
+	last, err = store.LastSuccess(ctx, "backup")
+	if err != nil {
+		t.Fatalf("LastSuccess() error = %v", err)
+	}
+	if want := started.Add(time.Minute); !last.Equal(want) {
+		t.Errorf("LastSuccess() = %v, want the first run's end %v", last, want)
+	}
+}
+
+func TestRedisStore_FirstSeen(t *testing.T) {
+	_, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	if seen, err := store.FirstSeenAt(ctx, "backup"); err != nil || !seen.IsZero() {
+		t.Errorf("FirstSeenAt() = %v, %v, want zero before the job is seen", seen, err)
+	}
+
+	first := time.UnixMilli(1700000000000)
+	for _, now := range []time.Time{first, first.Add(time.Hour)} {
+		seen, err := store.FirstSeen(ctx, "backup", now)
+		if err != nil {
+			t.Fatalf("FirstSeen() error = %v", err)
+		}
+		if !seen.Equal(first) {
+			t.Errorf("FirstSeen(%v) = %v, want the first recorded %v", now, seen, first)
+		}
+	}
+	if seen, err := store.FirstSeenAt(ctx, "backup"); err != nil || !seen.Equal(first) {
+		t.Errorf("FirstSeenAt() = %v, %v, want %v", seen, err, first)
+	}
+}
+
+func TestRedisStore_ClaimOverdueAlert(t *testing.T) {
+	s, client := setupMiniredis(t)
+	store := NewRedisStore(client, "test:")
+	ctx := context.Background()
+
+	claim := func() bool {
+		t.Helper()
+		ok, err := store.ClaimOverdueAlert(ctx, "backup", time.Hour)
+		if err != nil {
+			t.Fatalf("ClaimOverdueAlert() error = %v", err)
+		}
+		return ok
+	}
+
+	if !claim() {
+		t.Fatal("ClaimOverdueAlert() = false, want the first claim to succeed")
+	}
+	if claim() {
+		t.Error("ClaimOverdueAlert() = true again within the interval, want false")
+	}
+
+	s.FastForward(time.Hour)
+	if !claim() {
+		t.Error("ClaimOverdueAlert() = false after the interval, want true")
+	}
+
+	// A success clears the claim
+	rec := RunRecord{Job: "backup", Outcome: OutcomeSuccess, ScheduledAt: time.Now()}
+	if err := store.RecordRun(ctx, rec); err != nil {
+		t.Fatalf("RecordRun() error = %v", err)
+	}
+	if !claim() {
+		t.Error("ClaimOverdueAlert() = false after a success, want true")
+	}
+}
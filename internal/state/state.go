@@ -0,0 +1,257 @@
+package state
+
+import (
+	"context"
+	"time"
+)
+
+// Store defines the interface for cluster-wide job state shared between nodes.
+type Store interface {
+	// RecordShardResult records the outcome of one shard of a sharded run.
+	// The run is identified by the job name and its scheduled time, so every
+	// node reports into the same record. Returns the aggregate outcome to
+	// the report that completes the run, and ShardsPending to every other
+	// report, including repeated reports of a shard and reports after the
+	// run completed or was claimed as stalled. A run still incomplete at
+	// deadline is returned by ClaimStalledShardRuns.
+	RecordShardResult(ctx context.Context, jobName string, scheduledAt time.Time, index, count int, success bool, deadline time.Time) (ShardOutcome, error)
+
+	// ClaimStalledShardRuns returns the runs of a sharded job still
+	// incomplete at their deadline, as of now. Each run is returned to one
+	// caller only and counts as complete from then on.
+	ClaimStalledShardRuns(ctx context.Context, jobName string, now time.Time) ([]StalledShardRun, error)
+
+	// RegisterNode records a node in the cluster registry. The registration
+	// expires after ttl unless refreshed by another call.
+	RegisterNode(ctx context.Context, info NodeInfo, ttl time.Duration) error
+
+	// Nodes returns every node with a live registration.
+	Nodes(ctx context.Context) ([]NodeInfo, error)
+
+	// RecentNodes returns the last registration of every node seen within
+	// the registry's retention, live or not.
+	RecentNodes(ctx context.Context) ([]NodeRecord, error)
+
+	// SetDrain asks a node to stop taking new jobs. With exit set, the node
+	// also shuts down once its running jobs finish.
+	SetDrain(ctx context.Context, nodeID string, exit bool) error
+
+	// ClearDrain withdraws a drain request for a node.
+	ClearDrain(ctx context.Context, nodeID string) error
+
+	// DrainRequest returns whether a drain has been requested for a node.
+	DrainRequest(ctx context.Context, nodeID string) (requested, exit bool, err error)
+
+	// SetPause pauses a job on every node until the pause is cleared or,
+	// if set, pause.Until passes.
+	SetPause(ctx context.Context, jobName string, pause Pause) error
+
+	// ClearPause resumes a paused job.
+	ClearPause(ctx context.Context, jobName string) error
+
+	// GetPause returns the job's active pause, or nil if it is not paused.
+	GetPause(ctx context.Context, jobName string) (*Pause, error)
+
+	// RecordRun appends a run to the job's history. Records with the same
+	// job, scheduled time and outcome are stored once, so every node can
+	// report an outcome such as "paused" without duplicating it. A success
+	// also becomes the job's last success and clears its overdue alert.
+	RecordRun(ctx context.Context, rec RunRecord) error
+
+	// LastSuccess returns when the job last finished a successful run, or
+	// the zero time if it never has.
+	LastSuccess(ctx context.Context, jobName string) (time.Time, error)
+
+	// FirstSeen returns when the job was first monitored, recording now if
+	// no node has yet. It stands in for the last success of a job that
+	// never succeeded.
+	FirstSeen(ctx context.Context, jobName string, now time.Time) (time.Time, error)
+
+	// FirstSeenAt returns when the job was first monitored without recording
+	// anything, or the zero time if no node has monitored it yet.
+	FirstSeenAt(ctx context.Context, jobName string) (time.Time, error)
+
+	// ClaimOverdueAlert reports whether the caller should alert that the job
+	// is overdue. Only one claim succeeds per job every interval, until the
+	// job next succeeds.
+	ClaimOverdueAlert(ctx context.Context, jobName string, every time.Duration) (bool, error)
+
+	// History returns up to limit of the job's most recent runs, newest first.
+	History(ctx context.Context, jobName string, limit int) ([]RunRecord, error)
+
+	// RecordAlertOutcome counts a run's outcome in the job's cluster-wide
+	// alert state and returns the alert transition it causes. Alerts fire
+	// once threshold consecutive runs have failed, fire again every renotify
+	// (if non-zero) while runs keep failing, and recover on the next success.
+	RecordAlertOutcome(ctx context.Context, jobName string, success bool, threshold int, renotify time.Duration, now time.Time) (AlertState, error)
+
+	// RecordAudit appends an entry to the cluster's audit log of admin actions.
+	RecordAudit(ctx context.Context, entry AuditEntry) error
+
+	// AuditLog returns up to limit of the most recent audit entries, newest first.
+	AuditLog(ctx context.Context, limit int) ([]AuditEntry, error)
+}
+
+// Pause describes a cluster-wide pause of a job.
+type Pause struct {
+	Reason   string    `json:"reason,omitempty"`
+	Until    time.Time `json:"until,omitempty"` // zero means until resumed
+	PausedAt time.Time `json:"paused_at"`
+	PausedBy string    `json:"paused_by,omitempty"`
+}
+
+// Run outcomes recorded in history.
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+	OutcomePaused  = "paused"
+)
+
+// RunRecord is one entry in a job's run history.
+type RunRecord struct {
+	Job         string        `json:"job"`
+	RunID       string        `json:"run_id,omitempty"`
+	NodeID      string        `json:"node_id"`
+	Outcome     string        `json:"outcome"`
+	ScheduledAt time.Time     `json:"scheduled_at"`
+	StartedAt   time.Time     `json:"started_at,omitempty"`
+	Duration    time.Duration `json:"duration,omitempty"`
+	ExitCode    int           `json:"exit_code"`
+	Error       string        `json:"error,omitempty"`
+	Reason      string        `json:"reason,omitempty"`
+	// Slow is set if the run was still going after the job's warn_after.
+	Slow bool `json:"slow,omitempty"`
+}
+
+// FinishedAt returns when the run finished, or its scheduled time if it
+// never started.
+func (r RunRecord) FinishedAt() time.Time {
+	if r.StartedAt.IsZero() {
+		return r.ScheduledAt
+	}
+	return r.StartedAt.Add(r.Duration)
+}
+
+// AuditEntry records an admin action taken against the cluster.
+type AuditEntry struct {
+	Time     time.Time `json:"time"`
+	Operator string    `json:"operator"` // user@host
+	Action   string    `json:"action"`   // e.g. "lock.force_release"
+	Target   string    `json:"target"`   // e.g. the lock or job name
+	Detail   string    `json:"detail,omitempty"`
+}
+
+// NodeInfo describes a node in the cluster registry.
+type NodeInfo struct {
+	ID          string            `json:"id"`
+	Hostname    string            `json:"hostname,omitempty"`
+	Version     string            `json:"version,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	RunningJobs int               `json:"running_jobs"`
+	// Jobs are the names of the jobs the node is running.
+	Jobs     []string `json:"jobs,omitempty"`
+	LoadAvg  float64  `json:"load_avg"`
+	CPUs     int      `json:"cpus"`
+	Draining bool     `json:"draining,omitempty"`
+	// ConfigHash identifies the node's job configuration; nodes with the
+	// same jobs configured have the same hash.
+	ConfigHash string `json:"config_hash,omitempty"`
+	// ConfigVersion is the version of the job configuration pushed to
+	// Redis the node's jobs were loaded from; 0 if they were loaded from
+	// its configuration file.
+	ConfigVersion int `json:"config_version,omitempty"`
+	// JobConfigs identifies the configuration of each job the node
+	// schedules, by job name.
+	JobConfigs map[string]JobVersion `json:"job_configs,omitempty"`
+	// StartedAt is when the node's scheduler started.
+	StartedAt time.Time `json:"started_at,omitzero"`
+	// LastHeartbeat is when the node sent this registration.
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+// JobVersion identifies the configuration of a job on a node.
+type JobVersion struct {
+	Hash string `json:"hash"`
+	// ConfigVersion is the version of the configuration pushed to Redis the
+	// job was loaded from, or 0 if it came from the node's config file.
+	ConfigVersion int `json:"config_version,omitempty"`
+	// ModTime is when that configuration last changed: the config file's
+	// modification time, or when the version was pushed.
+	ModTime time.Time `json:"mod_time"`
+}
+
+// NodeRecord is a node's last registration.
+type NodeRecord struct {
+	NodeInfo
+	// Live is false once the registration has expired, i.e. the node has
+	// stopped heartbeating.
+	Live bool `json:"live"`
+}
+
+// AlertTransition is the change in a job's alert state caused by a run.
+type AlertTransition int
+
+const (
+	// AlertNone means nothing needs to be sent.
+	AlertNone AlertTransition = iota
+	// AlertFiring means the failures just reached the threshold.
+	AlertFiring
+	// AlertRenotify means the job is still failing and the renotify
+	// interval has passed since the last alert.
+	AlertRenotify
+	// AlertRecovered means the job succeeded after an alert fired.
+	AlertRecovered
+)
+
+// String returns the transition name.
+func (t AlertTransition) String() string {
+	switch t {
+	case AlertFiring:
+		return "firing"
+	case AlertRenotify:
+		return "renotify"
+	case AlertRecovered:
+		return "recovered"
+	default:
+		return "none"
+	}
+}
+
+// AlertState is the result of recording a run's outcome for alerting.
+type AlertState struct {
+	Transition AlertTransition
+	// Failures is the number of consecutive failed runs, including this
+	// one. For AlertRecovered it is the length of the streak just ended.
+	Failures int
+}
+
+// ShardOutcome is the aggregate result of a sharded run.
+type ShardOutcome int
+
+const (
+	// ShardsPending means not every shard has reported yet.
+	ShardsPending ShardOutcome = iota
+	// ShardsSucceeded means every shard reported success.
+	ShardsSucceeded
+	// ShardsFailed means every shard reported and at least one failed.
+	ShardsFailed
+)
+
+// StalledShardRun is a sharded run that didn't complete by its deadline.
+type StalledShardRun struct {
+	ScheduledAt time.Time
+	// Reported is the number of shards that reported.
+	Reported int
+}
+
+// String returns the outcome name.
+func (o ShardOutcome) String() string {
+	switch o {
+	case ShardsSucceeded:
+		return "succeeded"
+	case ShardsFailed:
+		return "failed"
+	default:
+		return "pending"
+	}
+}
@@ -0,0 +1,67 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"cronlock/internal/config"
+)
+
+func TestNewProvider_HTTP(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		paths   []string
+		headers http.Header
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		paths = append(paths, r.URL.Path)
+		headers = r.Header
+	}))
+	defer srv.Close()
+
+	cfg := config.Defaults().OTel
+	cfg.Enabled = true
+	cfg.Protocol = config.OTelProtocolHTTP
+	cfg.Endpoint = srv.URL
+	cfg.Headers = map[string]string{"X-Api-Key": "s3cret"}
+
+	tp, err := NewProvider(context.Background(), cfg, "node-1", "test")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	_, span := tp.Tracer("test").Start(context.Background(), "job.run")
+	span.End()
+	if err := tp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 1 || paths[0] != "/v1/traces" {
+		t.Fatalf("requests = %v, want one to /v1/traces", paths)
+	}
+	if headers.Get("X-Api-Key") != "s3cret" {
+		t.Errorf("X-Api-Key = %q, want %q", headers.Get("X-Api-Key"), "s3cret")
+	}
+}
+
+func TestNewProvider_GRPC(t *testing.T) {
+	cfg := config.Defaults().OTel
+	cfg.Enabled = true
+	cfg.Endpoint = "localhost:4317"
+	cfg.Insecure = true
+
+	// The gRPC exporter connects lazily, so no collector is needed
+	tp, err := NewProvider(context.Background(), cfg, "node-1", "test")
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_ = tp.Shutdown(ctx)
+}